@@ -1,16 +1,37 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/Advik-B/Axon/parser"
 	"github.com/Advik-B/Axon/transpiler"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 )
 
+func init() {
+	buildCmd.Flags().Bool("no-fold", false, "Keep one variable per node instead of inlining values that are used once")
+	buildCmd.Flags().String("format", "text", "Output format for the result and any problems found: text or json")
+	buildCmd.Flags().String("package", "main", "Name of the generated Go package")
+	buildCmd.Flags().Bool("no-main", false, "Leave out the main function and generate a library, even if the graph has a START node")
+	buildCmd.Flags().String("func-names", "label", "How FUNC_DEF labels become function names: label, exported or unexported")
+	buildCmd.Flags().StringP("output", "o", "", "File to write the Go code to (default out/main.go, or out/<package>.go for a library)")
+	buildCmd.Flags().Bool("line-directives", false, "Add //line directives so compiler errors and stack traces point into the graph file (.ax and .axd only)")
+	buildCmd.Flags().String("trace", "", "Make the program record each node it runs, with its output values, to this JSONL file (default trace.jsonl)")
+	buildCmd.Flags().Lookup("trace").NoOptDefVal = "trace.jsonl"
+}
+
+// funcNamings maps the values of --func-names to the transpiler option.
+var funcNamings = map[string]transpiler.FuncNaming{
+	"label":      transpiler.FuncNamesAsLabeled,
+	"exported":   transpiler.FuncNamesExported,
+	"unexported": transpiler.FuncNamesUnexported,
+}
+
 // transpileCmd represents the transpile command
 var buildCmd = &cobra.Command{
 	Use:   "build [path/to/graph.ax]",
@@ -18,8 +39,22 @@ var buildCmd = &cobra.Command{
 	Long: `Transpile reads an Axon graph file, validates its structure, and generates
 a runnable Go program located in the 'out' directory.
 
-It checks for valid execution paths, explicit error handling, and type consistency
-before generating the final Go code. It can process .ax, .axb, and .axd formats.`,
+It checks for valid execution paths, explicit error handling (every error result must
+reach an ERROR_CHECK or IGNORE node), and type consistency before generating the final Go code. It can process .ax, .axb, and .axd formats.
+
+Every problem found is reported, not just the first. With --format json the result is
+written as a JSON object whose "diagnostics" give each problem's severity, code, node and port.
+
+To generate a reusable package instead of a program, name it with --package and pass
+--no-main; --func-names exported makes its functions callable from other packages.
+
+Next to each Go file, a source map (main.go.axmap) records which node every line came from;
+'axon trace-line out/main.go:17' looks a line up in it.
+
+With --trace the program records every node it runs, with the values of its outputs, as
+one JSON event per line in trace.jsonl (or the file given, or the one the AXON_TRACE
+environment variable names when it runs). 'axon preview --trace trace.jsonl' replays the
+run on the graph.`,
 	Args: cobra.ExactArgs(1), // Requires exactly one argument: the file path.
 	Run:  runBuild,
 }
@@ -29,50 +64,138 @@ func runBuild(cmd *cobra.Command, args []string) {
 	filePath := args[0]
 	startTime := time.Now()
 
-	fmt.Println("🚀 Starting Axon build process...")
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		fmt.Printf("❌ Error: unknown format '%s' (expected text or json)\n", format)
+		os.Exit(1)
+	}
+	opts := transpiler.Options{}
+	opts.NoFold, _ = cmd.Flags().GetBool("no-fold")
+	opts.PackageName, _ = cmd.Flags().GetString("package")
+	opts.NoMain, _ = cmd.Flags().GetBool("no-main")
+	opts.Trace, _ = cmd.Flags().GetString("trace")
+	naming, _ := cmd.Flags().GetString("func-names")
+	var ok bool
+	if opts.FuncNaming, ok = funcNamings[naming]; !ok {
+		fmt.Printf("❌ Error: unknown function naming '%s' (expected label, exported or unexported)\n", naming)
+		os.Exit(1)
+	}
+	outputFile, _ := cmd.Flags().GetString("output")
+	if outputFile == "" {
+		outputFile = filepath.Join("out", "main.go")
+		if opts.PackageName != "main" {
+			outputFile = filepath.Join("out", opts.PackageName+".go")
+		}
+	}
+	// In JSON mode stdout carries only the report, so progress messages are dropped.
+	logf := func(format string, args ...any) {}
+	if format == "text" {
+		logf = func(format string, args ...any) { fmt.Printf(format, args...) }
+	}
+	report := &fileReport{File: filePath, Diagnostics: transpiler.Diagnostics{}}
+	fail := func(err error, code string) {
+		report.Diagnostics = diagnosticsOf(err, code)
+		if format == "json" {
+			writeJSON(os.Stdout, report)
+		} else {
+			printDiagnostics(os.Stdout, report.Diagnostics)
+		}
+		os.Exit(1)
+	}
+
+	logf("🚀 Starting Axon build process...\n")
 
 	// 1. Validate file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		fmt.Printf("❌ Error: Input file not found at '%s'\n", filePath)
-		os.Exit(1)
+		logf("❌ Error: Input file not found at '%s'\n", filePath)
+		fail(err, transpiler.CodeInvalidFile)
 	}
-	fmt.Printf("   - Found graph file: %s\n", filePath)
+	logf("   - Found graph file: %s\n", filePath)
 
 	// 2. Parse the graph file
-	fmt.Println("   - Parsing graph...")
+	logf("   - Parsing graph...\n")
 	graph, err := parser.LoadGraphFromFile(filePath)
 	if err != nil {
-		fmt.Printf("❌ Error parsing graph file %s: %v\n", filePath, err)
-		os.Exit(1)
+		logf("❌ Error parsing graph file %s:\n", filePath)
+		fail(err, transpiler.CodeInvalidFile)
 	}
-	fmt.Printf("   - Successfully parsed graph: %s\n", graph.Name)
+	logf("   - Successfully parsed graph: %s\n", graph.Name)
 
 	// 3. Transpile the graph to Go code
-	fmt.Println("   - Transpiling to Go...")
-	goCode, err := transpiler.Transpile(graph)
+	logf("   - Transpiling to Go...\n")
+	opts.FileName = filepath.Base(outputFile)
+	opts.SourceFile = filePath
+	if lineDirectives, _ := cmd.Flags().GetBool("line-directives"); lineDirectives {
+		if opts.SourceLines, err = sourceLines(graph, filePath); err != nil {
+			fail(err, transpiler.CodeInvalidFile)
+		}
+	}
+	result, err := transpiler.Compile(context.Background(), graph, opts)
 	if err != nil {
-		fmt.Printf("❌ Error transpiling graph: %v\n", err)
-		os.Exit(1)
+		logf("❌ Error transpiling graph:\n")
+		fail(err, transpiler.CodeInvalidNode)
+	}
+	if result.Diagnostics.HasErrors() {
+		logf("❌ Error transpiling graph (%d problems found):\n", len(result.Diagnostics))
+		fail(result.Diagnostics, transpiler.CodeInvalidNode)
+	}
+	logf("   - Transpilation successful.\n")
+	report.Diagnostics = result.Diagnostics
+	if len(result.Diagnostics) > 0 && format == "text" {
+		fmt.Printf("⚠️  Warnings:\n")
+		printDiagnostics(os.Stdout, result.Diagnostics)
 	}
-	fmt.Println("   - Transpilation successful.")
 
 	// 4. Write the output to a file
-	fmt.Println("   - Writing output file...")
-	outputDir := "out"
+	logf("   - Writing output file...\n")
+	outputDir := filepath.Dir(outputFile)
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		fmt.Printf("❌ Error creating output directory %s: %v\n", outputDir, err)
+		fmt.Fprintf(os.Stderr, "❌ Error creating output directory %s: %v\n", outputDir, err)
 		os.Exit(1)
 	}
 
-	outputFile := filepath.Join(outputDir, "main.go")
-	err = os.WriteFile(outputFile, []byte(goCode), 0644)
-	if err != nil {
-		fmt.Printf("❌ Error writing to output file %s: %v\n", outputFile, err)
+	var written []string
+	for _, file := range result.Files {
+		path := filepath.Join(outputDir, file.Name)
+		written = append(written, path)
+		if err := os.WriteFile(path, file.Content, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error writing to output file %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		logf("   - Go code written to %s\n", path)
+	}
+	mapFile := filepath.Join(outputDir, result.SourceMap.File+".axmap")
+	if err := writeSourceMap(mapFile, result.SourceMap); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error writing source map %s: %v\n", mapFile, err)
 		os.Exit(1)
 	}
-	fmt.Printf("   - Go code written to %s\n", outputFile)
+	logf("   - Source map written to %s\n", mapFile)
 
+	if format == "json" {
+		report.Success, report.Output = true, outputFile
+		writeJSON(os.Stdout, report)
+		return
+	}
 	duration := time.Since(startTime)
 	fmt.Printf("\n✅ Transpilation Succeeded in %.2fs!\n", duration.Seconds())
-	fmt.Printf("   Run the output with: go run %s\n", outputFile)
+	if opts.PackageName == "main" && !opts.NoMain {
+		if opts.Trace != "" {
+			// The recording code is a second file of the package, which go run must be given too.
+			fmt.Printf("   Run the output with: go run %s\n", strings.Join(written, " "))
+			fmt.Printf("   Then replay the run with: axon preview --trace %s %s\n", opts.Trace, filePath)
+		} else {
+			fmt.Printf("   Run the output with: go run %s\n", outputFile)
+		}
+		fmt.Printf("   Or build and run the graph in one step with: axon run %s\n", filePath)
+	}
+}
+
+// writeSourceMap saves a source map to a .axmap file.
+func writeSourceMap(path string, sourceMap *transpiler.SourceMap) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return transpiler.WriteSourceMap(f, sourceMap)
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/Advik-B/Axon/parser"
+	"github.com/Advik-B/Axon/transpiler"
+
+	"github.com/spf13/cobra"
+)
+
+// graphExtensions are the file formats a directory is searched for.
+var graphExtensions = map[string]bool{".ax": true, ".axb": true, ".axd": true, ".axc": true}
+
+func init() {
+	checkCmd.Flags().String("format", "text", "Report format: text, json, junit or sarif")
+	checkCmd.Flags().StringP("output", "o", "", "Write the report to this file instead of stdout")
+}
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check <files or dirs...>",
+	Short: "Validates Axon graphs without generating any code.",
+	Long: `Check loads each graph and runs every validation the build does: execution
+flow structure, error handling, and type and signature checks. Nothing is written to 'out'.
+
+Directories are searched recursively for .ax, .axb, .axd and .axc files. The command
+exits with status 1 if any graph has an error, so it can gate CI, and with status 2 if it
+cannot run at all. The report can be written as text, JSON, JUnit XML or SARIF for code
+review tools.`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runCheck,
+}
+
+func runCheck(cmd *cobra.Command, args []string) {
+	reportFiles(cmd, args, checkFile)
+}
+
+// reportFiles runs process on every graph file the arguments name, writes the report in the format
+// the command's flags ask for, and exits with status 1 if any file did not pass.
+func reportFiles(cmd *cobra.Command, args []string, process func(path string) *fileReport) {
+	format, _ := cmd.Flags().GetString("format")
+	writeReport, ok := reportWriters[format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "❌ Error: unknown format '%s' (expected text, json, junit or sarif)\n", format)
+		os.Exit(2)
+	}
+
+	files, err := findGraphFiles(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(2)
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "❌ Error: no graph files found")
+		os.Exit(2)
+	}
+
+	var reports []*fileReport
+	failed := false
+	for _, file := range files {
+		report := process(file)
+		failed = failed || !report.Success
+		reports = append(reports, report)
+	}
+
+	out := os.Stdout
+	if path, _ := cmd.Flags().GetString("output"); path != "" {
+		out, err = os.Create(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error creating report file %s: %v\n", path, err)
+			os.Exit(2)
+		}
+		defer out.Close()
+	}
+	if err := writeReport(out, reports); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error writing report: %v\n", err)
+		os.Exit(2)
+	}
+	if failed {
+		out.Close()
+		os.Exit(1)
+	}
+}
+
+// checkFile loads and validates one graph file.
+func checkFile(path string) *fileReport {
+	report := &fileReport{File: path}
+	graph, err := parser.LoadGraphFromFile(path)
+	if err != nil {
+		report.Diagnostics = diagnosticsOf(err, transpiler.CodeInvalidFile)
+		return report
+	}
+	// Compile rather than Transpile, which drops the warnings of a graph that has no errors.
+	result, err := transpiler.Compile(context.Background(), graph, transpiler.Options{})
+	if err != nil {
+		report.Diagnostics = diagnosticsOf(err, transpiler.CodeInvalidNode)
+	} else {
+		report.Diagnostics = result.Diagnostics
+	}
+	report.Success = !report.Diagnostics.HasErrors()
+	return report
+}
+
+// findGraphFiles expands the arguments into graph files. Files are taken as given, and
+// directories are walked recursively for files with a graph extension.
+func findGraphFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && graphExtensions[filepath.Ext(path)] {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
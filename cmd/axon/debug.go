@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Advik-B/Axon/interp"
+	axonparser "github.com/Advik-B/Axon/parser"
+	"github.com/Advik-B/Axon/pkg/axon"
+	"github.com/Advik-B/Axon/transpiler"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	debugCmd.Flags().Bool("allow-writes", false, "Let the graph write files")
+	debugCmd.Flags().StringSliceP("break", "b", nil, "Set a breakpoint on a node, by ID or label, and run until it is reached (repeatable)")
+}
+
+// debugCmd represents the debug command
+var debugCmd = &cobra.Command{
+	Use:   "debug [path/to/graph.ax] [-- args...]",
+	Short: "Steps through an Axon graph node by node, showing the values on its edges.",
+	Long: `Debug runs a graph with the built-in interpreter (see 'axon run --interpret') and
+pauses before nodes so you can see the actual values flowing along its data edges.
+
+The run pauses at the START node, or with --break runs until a breakpoint is reached.
+At the (axon) prompt:
+
+` + debugHelp,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runDebug,
+}
+
+// errQuit stops a debugged run when the user quits.
+var errQuit = errors.New("quit")
+
+// stepMode is what the debugger does between pauses.
+type stepMode int
+
+const (
+	stepInto     stepMode = iota // Pause at the next node.
+	stepOver                     // Pause at the next node no deeper than the current one.
+	stepOut                      // Pause at the next node shallower than the current one.
+	stepContinue                 // Pause only at breakpoints.
+)
+
+// debugger is the REPL that runs as the interpreter's hook.
+type debugger struct {
+	graph       *axon.Graph
+	input       *bufio.Scanner
+	out         io.Writer
+	breakpoints map[string]bool
+	mode        stepMode
+	// depth is the call depth at the pause the last step command was given at.
+	depth int
+}
+
+func runDebug(cmd *cobra.Command, args []string) {
+	filePath := args[0]
+	if dash := cmd.ArgsLenAtDash(); dash > 1 || dash < 0 && len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "❌ Error: program arguments must follow '--', as in 'axon debug graph.ax -- arg1 arg2'")
+		os.Exit(1)
+	}
+	graph, err := axonparser.LoadGraphFromFile(filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error parsing graph file %s: %v\n", filePath, err)
+		os.Exit(1)
+	}
+
+	d := &debugger{
+		graph:       graph,
+		input:       bufio.NewScanner(os.Stdin),
+		out:         os.Stdout,
+		breakpoints: make(map[string]bool),
+	}
+	breaks, _ := cmd.Flags().GetStringSlice("break")
+	for _, name := range breaks {
+		node, err := d.findNode(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+			os.Exit(1)
+		}
+		d.breakpoints[node.Id] = true
+	}
+	if len(breaks) > 0 {
+		d.mode = stepContinue
+	}
+
+	allowWrites, _ := cmd.Flags().GetBool("allow-writes")
+	in, err := interp.New(graph, interp.Options{Stdout: os.Stdout, Args: args, AllowFileWrites: allowWrites, Hook: d.pause})
+	var diags transpiler.Diagnostics
+	if errors.As(err, &diags) {
+		fmt.Fprintf(os.Stderr, "❌ Error: the graph cannot be run (%d problems found):\n", len(diags))
+		printDiagnostics(os.Stderr, diags)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(d.out, "🐞 Debugging %s. Type 'help' for commands.\n", graph.Name)
+	err = in.Run(context.Background())
+	switch {
+	case errors.Is(err, errQuit):
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	default:
+		fmt.Fprintln(d.out, "✅ The graph finished.")
+	}
+}
+
+// pause is the interpreter hook: it decides whether to stop before a node, and if so reads
+// commands until one resumes the run.
+func (d *debugger) pause(s *interp.Step) error {
+	stop := d.breakpoints[s.Node.Id]
+	switch d.mode {
+	case stepInto:
+		stop = true
+	case stepOver:
+		stop = stop || s.Depth <= d.depth
+	case stepOut:
+		stop = stop || s.Depth < d.depth
+	}
+	if !stop {
+		return nil
+	}
+
+	if d.breakpoints[s.Node.Id] {
+		fmt.Fprintf(d.out, "🔴 Breakpoint at %s\n", describeNode(s.Node))
+	} else {
+		fmt.Fprintf(d.out, "⏸  At %s\n", describeNode(s.Node))
+	}
+	d.printEdges(s.Inputs(), func(v interp.EdgeValue) string { return "   " + v.Edge.ToPort })
+
+	for {
+		fmt.Fprint(d.out, "(axon) ")
+		if !d.input.Scan() {
+			fmt.Fprintln(d.out)
+			return errQuit
+		}
+		fields := strings.Fields(d.input.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		command, arg := fields[0], strings.Join(fields[1:], " ")
+		switch command {
+		case "s", "step":
+			d.mode, d.depth = stepInto, s.Depth
+			return nil
+		case "n", "next":
+			d.mode, d.depth = stepOver, s.Depth
+			return nil
+		case "o", "out":
+			d.mode, d.depth = stepOut, s.Depth
+			return nil
+		case "c", "continue":
+			d.mode = stepContinue
+			return nil
+		case "b", "break":
+			d.setBreakpoint(arg)
+		case "d", "delete":
+			d.deleteBreakpoint(arg)
+		case "p", "print":
+			d.printNode(s, arg)
+		case "v", "values":
+			d.printEdges(s.Values(), func(v interp.EdgeValue) string {
+				return fmt.Sprintf("   %s.%s → %s.%s", v.Edge.FromNodeId, v.Edge.FromPort, v.Edge.ToNodeId, v.Edge.ToPort)
+			})
+		case "w", "where":
+			stack := s.Stack()
+			for i := len(stack) - 1; i >= 0; i-- {
+				fmt.Fprintf(d.out, "   #%d %s\n", len(stack)-1-i, describeNode(stack[i]))
+			}
+		case "h", "help":
+			fmt.Fprintln(d.out, debugHelp)
+		case "q", "quit":
+			return errQuit
+		default:
+			fmt.Fprintf(d.out, "Unknown command '%s'. Type 'help' for commands.\n", command)
+		}
+	}
+}
+
+// debugHelp lists the commands the debugger takes.
+const debugHelp = `   s, step          run the next node, stepping into functions the graph defines
+   n, next          run the next node of this flow, stepping over function calls
+   o, out           run until the current function returns
+   c, continue      run until a breakpoint is reached
+   b, break [node]  set a breakpoint on a node by ID or label, or list breakpoints
+   d, delete <node> remove a breakpoint
+   p, print <node>  show the values on a node's input and output edges
+   v, values        show the value on every data edge that carries one
+   w, where         show the stack of flows being run
+   h, help          show this help
+   q, quit          stop the program`
+
+func (d *debugger) setBreakpoint(name string) {
+	if name == "" {
+		if len(d.breakpoints) == 0 {
+			fmt.Fprintln(d.out, "No breakpoints.")
+		}
+		var ids []string
+		for id := range d.breakpoints {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			node, _ := d.findNode(id)
+			fmt.Fprintf(d.out, "   %s\n", describeNode(node))
+		}
+		return
+	}
+	node, err := d.findNode(name)
+	if err != nil {
+		fmt.Fprintln(d.out, err)
+		return
+	}
+	d.breakpoints[node.Id] = true
+	fmt.Fprintf(d.out, "Breakpoint set at %s\n", describeNode(node))
+}
+
+func (d *debugger) deleteBreakpoint(name string) {
+	node, err := d.findNode(name)
+	if err != nil {
+		fmt.Fprintln(d.out, err)
+		return
+	}
+	if !d.breakpoints[node.Id] {
+		fmt.Fprintf(d.out, "There is no breakpoint at %s\n", describeNode(node))
+		return
+	}
+	delete(d.breakpoints, node.Id)
+	fmt.Fprintf(d.out, "Breakpoint removed from %s\n", describeNode(node))
+}
+
+// printNode shows the values on a node's input and output edges, or the paused node's if none is
+// named.
+func (d *debugger) printNode(s *interp.Step, name string) {
+	node := s.Node
+	if name != "" {
+		var err error
+		if node, err = d.findNode(name); err != nil {
+			fmt.Fprintln(d.out, err)
+			return
+		}
+	}
+	var inputs, outputs []interp.EdgeValue
+	seen := make(map[string]bool)
+	for _, v := range s.Values() {
+		if v.Edge.ToNodeId == node.Id {
+			inputs = append(inputs, v)
+		}
+		// An output may feed several edges, but has one value.
+		if v.Edge.FromNodeId == node.Id && !seen[v.Edge.FromPort] {
+			seen[v.Edge.FromPort] = true
+			outputs = append(outputs, v)
+		}
+	}
+	fmt.Fprintln(d.out, describeNode(node))
+	if len(inputs)+len(outputs) == 0 {
+		fmt.Fprintln(d.out, "   (no values yet)")
+	}
+	d.printEdges(inputs, func(v interp.EdgeValue) string { return "   in  " + v.Edge.ToPort })
+	d.printEdges(outputs, func(v interp.EdgeValue) string { return "   out " + v.Edge.FromPort })
+}
+
+// printEdges writes one line per edge value, naming each edge with name.
+func (d *debugger) printEdges(values []interp.EdgeValue, name func(interp.EdgeValue) string) {
+	for _, v := range values {
+		fmt.Fprintf(d.out, "%s = %s\n", name(v), formatValue(v.Value))
+	}
+}
+
+// findNode finds a node by ID, or else by label, which must then be unique.
+func (d *debugger) findNode(name string) (*axon.Node, error) {
+	var matches []*axon.Node
+	for _, node := range d.graph.Nodes {
+		if node.Id == name {
+			return node, nil
+		}
+		if node.Label == name {
+			matches = append(matches, node)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no node has the ID or label '%s'", name)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("%d nodes are labelled '%s'; use a node ID instead", len(matches), name)
+}
+
+// describeNode names a node the way the debugger shows it, like "'Add' (add, OPERATOR)".
+func describeNode(node *axon.Node) string {
+	if node.Label == "" {
+		return fmt.Sprintf("%s (%s)", node.Id, node.Type)
+	}
+	return fmt.Sprintf("'%s' (%s, %s)", node.Label, node.Id, node.Type)
+}
+
+// formatValue shows a value with its type, quoting strings so empty and blank ones are visible.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return fmt.Sprintf("%q (string)", v)
+	case error:
+		return fmt.Sprintf("%q (error)", v.Error())
+	}
+	return fmt.Sprintf("%v (%T)", v, v)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Advik-B/Axon/transpiler"
+)
+
+// fileReport is the machine-readable result of processing one graph file, as written by --format json.
+type fileReport struct {
+	File        string                 `json:"file"`
+	Success     bool                   `json:"success"`
+	Output      string                 `json:"output,omitempty"`
+	Diagnostics transpiler.Diagnostics `json:"diagnostics"`
+}
+
+// diagnosticsOf returns the diagnostics an error from loading or transpiling a graph carries.
+// Errors that are not diagnostics are reported as one with the given code.
+func diagnosticsOf(err error, code string) transpiler.Diagnostics {
+	if err == nil {
+		return transpiler.Diagnostics{}
+	}
+	var diags transpiler.Diagnostics
+	if errors.As(err, &diags) {
+		return diags
+	}
+	return transpiler.Diagnostics{{Severity: transpiler.SeverityError, Code: code, Message: err.Error()}}
+}
+
+// printDiagnostics writes one line per diagnostic, located by node and port like "printer.a".
+func printDiagnostics(w io.Writer, diags transpiler.Diagnostics) {
+	for _, d := range diags {
+		location := d.NodeID
+		if d.Port != "" {
+			location += "." + d.Port
+		}
+		if location != "" {
+			location = " " + location + ":"
+		}
+		fmt.Fprintf(w, "   %s[%s]%s %s\n", d.Severity, d.Code, location, d.Message)
+	}
+}
+
+// writeJSON writes a value as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
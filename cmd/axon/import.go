@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Advik-B/Axon/importer"
+	"github.com/Advik-B/Axon/parser"
+	"github.com/Advik-B/Axon/pkg/axon"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	importCmd.Flags().StringP("output", "o", "", "Output graph file (.ax, .axb, .axd, .axc); defaults to the input with .ax")
+}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <file.go>",
+	Short: "Converts a Go source file into an Axon graph.",
+	Long: `Import parses a Go file and converts it into a graph: functions become FUNC_DEF
+flows (main becomes the START flow), struct types STRUCT_DEF nodes, calls FUNCTION nodes,
+operators OPERATOR nodes, literals CONSTANT nodes and returns RETURN nodes. if statements,
+loops, switches, go and defer statements, channel makes, sends and receives, variables and
+'if err != nil' checks become the matching nodes too.
+
+Code with no node equivalent, like closures or struct literals, becomes PLACEHOLDER
+nodes labelled "TODO" that keep the original source. The graph is written either way, but
+it cannot be built until they are replaced.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runImport,
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ".ax"
+	}
+
+	src, err := os.ReadFile(inputPath)
+	if err != nil {
+		fmt.Printf("❌ Error reading Go file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("📥 Importing Go source: %s\n", inputPath)
+	graph, err := importer.Import(inputPath, src)
+	if err != nil {
+		fmt.Printf("❌ Error parsing Go file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		fmt.Printf("❌ Error creating output directory: %v\n", err)
+		os.Exit(1)
+	}
+	if err := parser.SaveGraphToFile(graph, outputPath); err != nil {
+		fmt.Printf("❌ Error writing graph file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("   -> Wrote %d nodes to %s\n", len(graph.Nodes), outputPath)
+
+	var placeholders []string
+	for _, node := range graph.Nodes {
+		if node.Type == axon.NodeType_PLACEHOLDER {
+			placeholders = append(placeholders, node.Label)
+		}
+	}
+	if len(placeholders) > 0 {
+		fmt.Printf("\n⚠️  %d placeholder(s) stand in for code that could not be converted; replace them before building:\n", len(placeholders))
+		for _, label := range placeholders {
+			fmt.Printf("   - %s\n", label)
+		}
+	}
+
+	fmt.Printf("\n✅ Successfully imported %s\n", outputPath)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Advik-B/Axon/lint"
+	"github.com/Advik-B/Axon/parser"
+	"github.com/Advik-B/Axon/transpiler"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	lintCmd.Flags().String("format", "text", "Report format: text, json, junit or sarif")
+	lintCmd.Flags().StringP("output", "o", "", "Write the report to this file instead of stdout")
+	lintCmd.Flags().StringSlice("disable", nil, "Rule IDs to skip, separated by commas")
+	lintCmd.Flags().Bool("list-rules", false, "List the available rules and exit")
+}
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint <files or dirs...>",
+	Short: "Reports style problems and likely mistakes in Axon graphs.",
+	Long: `Lint checks graphs for problems that do not stop them from transpiling, like unused
+imports, orphaned comments, duplicate labels and calls to deprecated APIs. Each finding names
+the rule that produced it; run with --list-rules to see them all.
+
+A rule can be silenced for one node by setting its "lint:ignore" config entry to a
+comma-separated list of rule IDs (or "all"). On the START node it silences them for the
+whole graph. Directories are searched recursively, and the command exits with status 1
+if anything is found.`,
+	Run: runLint,
+}
+
+func runLint(cmd *cobra.Command, args []string) {
+	if list, _ := cmd.Flags().GetBool("list-rules"); list {
+		for _, rule := range lint.Rules() {
+			fmt.Printf("%-20s %s\n", rule.ID, rule.Description)
+		}
+		return
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "❌ Error: no files or directories to lint")
+		os.Exit(2)
+	}
+
+	disabled, _ := cmd.Flags().GetStringSlice("disable")
+	for _, id := range disabled {
+		if lint.Lookup(id) == nil {
+			fmt.Fprintf(os.Stderr, "❌ Error: unknown rule '%s'\n", id)
+			os.Exit(2)
+		}
+	}
+	reportFiles(cmd, args, func(path string) *fileReport {
+		report := &fileReport{File: path}
+		graph, err := parser.LoadGraphFromFile(path)
+		if err != nil {
+			report.Diagnostics = diagnosticsOf(err, transpiler.CodeInvalidFile)
+			return report
+		}
+		report.Diagnostics = lint.Run(graph, lint.Options{Disabled: disabled})
+		if report.Diagnostics == nil {
+			report.Diagnostics = transpiler.Diagnostics{}
+		}
+		report.Success = len(report.Diagnostics) == 0
+		return report
+	})
+}
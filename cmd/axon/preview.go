@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"github.com/Advik-B/Axon/parser"
+	"github.com/Advik-B/Axon/pkg/axon"
 	"github.com/Advik-B/Axon/previewer"
+	"github.com/Advik-B/Axon/transpiler"
 	"log"
 	"os"
 
@@ -11,6 +13,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
+func init() {
+	previewCmd.Flags().String("trace", "", "Replay a run recorded by a program built with 'axon build --trace' (a .jsonl file)")
+}
+
 // previewCmd represents the preview command
 var previewCmd = &cobra.Command{
 	Use:   "preview [path/to/graph.ax | .axb | .axd | .axc]",
@@ -23,7 +29,16 @@ feel. Nodes are connected by springs and will react to being moved.
 Controls:
   - Drag Node:  Click and drag a node to move it.
   - Pan View:   Click and drag the background.
-  - Zoom View:  Use the mouse wheel.`,
+  - Zoom View:  Use the mouse wheel.
+
+With --trace, a run recorded by a program built with 'axon build --trace' is replayed
+on the graph: the node running pulses, data edges are labelled with the latest values
+they carried, and a timeline along the bottom shows where the replay is.
+
+Replay controls:
+  - Play/Pause:  P
+  - Step:        Left and Right arrow keys (Home and End jump to either end).
+  - Scrub:       Click and drag along the timeline.`,
 	Args: cobra.ExactArgs(1),
 	Run:  runPreview,
 }
@@ -38,6 +53,14 @@ func runPreview(cmd *cobra.Command, args []string) {
 		fmt.Printf("❌ Error parsing graph file: %v\n", err)
 		os.Exit(1)
 	}
+	var events []transpiler.TraceEvent
+	if tracePath, _ := cmd.Flags().GetString("trace"); tracePath != "" {
+		if events, err = loadTrace(tracePath, graph.Nodes); err != nil {
+			fmt.Printf("❌ Error reading trace file %s: %v\n", tracePath, err)
+			os.Exit(1)
+		}
+		fmt.Printf("   - Loaded %d trace events from %s.\n", len(events), tracePath)
+	}
 	fmt.Println("   - Graph loaded. Launching interactive physics preview...")
 
 	// 2. Initialize the Ebitengine previewer with the graph data.
@@ -45,6 +68,7 @@ func runPreview(cmd *cobra.Command, args []string) {
 	if err != nil {
 		log.Fatalf("❌ Failed to initialize previewer: %v", err)
 	}
+	previewApp.SetTrace(events)
 
 	// 3. Configure and run the Ebitengine window.
 	ebiten.SetWindowSize(1600, 900)
@@ -57,3 +81,35 @@ func runPreview(cmd *cobra.Command, args []string) {
 
 	fmt.Println("👋 Preview window closed.")
 }
+
+// loadTrace reads a trace file, warning if some of its events are for nodes the graph does not
+// have, as when it was recorded from another version of the graph.
+func loadTrace(path string, nodes []*axon.Node) ([]transpiler.TraceEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	events, err := transpiler.ReadTrace(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("the trace has no events")
+	}
+
+	known := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		known[node.Id] = true
+	}
+	unknown := 0
+	for _, event := range events {
+		if !known[event.Node] {
+			unknown++
+		}
+	}
+	if unknown > 0 {
+		fmt.Printf("⚠️  %d of the %d trace events are for nodes not in this graph; was it recorded from another version?\n", unknown, len(events))
+	}
+	return events, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+	"github.com/Advik-B/Axon/transpiler"
+)
+
+// reportWriters write the results of `axon check` in each supported format.
+var reportWriters = map[string]func(io.Writer, []*fileReport) error{
+	"text":  writeTextReport,
+	"json":  func(w io.Writer, reports []*fileReport) error { return writeJSON(w, reports) },
+	"junit": writeJUnitReport,
+	"sarif": writeSARIFReport,
+}
+
+func writeTextReport(w io.Writer, reports []*fileReport) error {
+	failed := 0
+	for _, report := range reports {
+		switch {
+		case report.Success:
+			fmt.Fprintf(w, "✅ %s\n", report.File)
+		case report.Diagnostics.HasErrors():
+			failed++
+			fmt.Fprintf(w, "❌ %s\n", report.File)
+		default:
+			failed++
+			fmt.Fprintf(w, "⚠️ %s\n", report.File)
+		}
+		printDiagnostics(w, report.Diagnostics)
+	}
+	_, err := fmt.Fprintf(w, "\n%d graphs checked, %d with problems\n", len(reports), failed)
+	return err
+}
+
+// JUnit XML, as read by most CI systems: one test case per graph file, failing with its diagnostics.
+type junitTestSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	// Warnings of a graph that passed.
+	SystemOut string `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnitReport(w io.Writer, reports []*fileReport) error {
+	suite := junitSuite{Name: "axon check", Tests: len(reports)}
+	for _, report := range reports {
+		testCase := junitTestCase{Name: report.File, ClassName: "axon.check"}
+		if !report.Success {
+			suite.Failures++
+			var details strings.Builder
+			printDiagnostics(&details, report.Diagnostics)
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d problems found", len(report.Diagnostics)),
+				Type:    report.Diagnostics[0].Code,
+				Text:    details.String(),
+			}
+		} else if len(report.Diagnostics) > 0 {
+			var details strings.Builder
+			printDiagnostics(&details, report.Diagnostics)
+			testCase.SystemOut = details.String()
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// SARIF 2.1.0, as read by code scanning tools. Each diagnostic is a result located at its graph file,
+// and at the line that defines its node when the file is text.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+func writeSARIFReport(w io.Writer, reports []*fileReport) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "axon",
+			InformationURI: "https://github.com/Advik-B/Axon",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	seenRules := make(map[string]bool)
+	for _, report := range reports {
+		source, _ := os.ReadFile(report.File)
+		for _, d := range report.Diagnostics {
+			if !seenRules[d.Code] {
+				seenRules[d.Code] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: d.Code})
+			}
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(report.File)},
+			}}
+			if d.NodeID != "" {
+				if line := nodeLine(source, filepath.Ext(report.File), d.NodeID); line > 0 {
+					location.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+				}
+				name := d.NodeID
+				if d.Port != "" {
+					name += "." + d.Port
+				}
+				location.LogicalLocations = []sarifLogicalLocation{{Name: d.NodeID, FullyQualifiedName: name, Kind: "object"}}
+			}
+			level := "error"
+			if d.Severity == transpiler.SeverityWarning {
+				level = "warning"
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    d.Code,
+				Level:     level,
+				Message:   sarifMessage{Text: d.Message},
+				Locations: []sarifLocation{location},
+			})
+		}
+	}
+	return writeJSON(w, sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sourceLines maps the IDs of a graph's nodes to the lines of its file where they are defined, for
+// //line directives. Nodes whose line cannot be found, as in the binary formats, are left out.
+func sourceLines(graph *axon.Graph, filePath string) (map[string]int, error) {
+	source, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	lines := make(map[string]int)
+	for _, node := range graph.Nodes {
+		if line := nodeLine(source, filepath.Ext(filePath), node.Id); line > 0 {
+			lines[node.Id] = line
+		}
+	}
+	return lines, nil
+}
+
+// nodeLine finds the 1-based line where a node's ID is defined in a JSON (.ax) or YAML (.axd)
+// graph, or returns 0 if it cannot be found, as in the binary formats.
+func nodeLine(source []byte, ext string, nodeID string) int {
+	var pattern string
+	switch ext {
+	case ".ax":
+		pattern = `"id"\s*:\s*"` + regexp.QuoteMeta(nodeID) + `"`
+	case ".axd":
+		pattern = `(?m)\bid:\s*["']?` + regexp.QuoteMeta(nodeID) + `["']?\s*$`
+	default:
+		return 0
+	}
+	loc := regexp.MustCompile(pattern).FindIndex(source)
+	if loc == nil {
+		return 0
+	}
+	return strings.Count(string(source[:loc[0]]), "\n") + 1
+}
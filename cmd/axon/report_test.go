@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Advik-B/Axon/transpiler"
+)
+
+// testReports returns the reports of two graph files written to a temporary directory: one that
+// failed with an error on a port of its second node, and one that passed with a warning.
+func testReports(t *testing.T) []*fileReport {
+	t.Helper()
+	dir := t.TempDir()
+	failing := filepath.Join(dir, "failing.ax")
+	graph := "{\n  \"nodes\": [\n    {\"id\": \"start\", \"type\": \"START\"},\n    {\"id\": \"print\", \"type\": \"FUNCTION\"}\n  ]\n}\n"
+	if err := os.WriteFile(failing, []byte(graph), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return []*fileReport{
+		{File: failing, Diagnostics: transpiler.Diagnostics{
+			{Severity: transpiler.SeverityError, Code: transpiler.CodeTypeMismatch, NodeID: "print", Port: "a", Message: "wrong type"},
+		}},
+		{File: filepath.Join(dir, "passing.ax"), Success: true, Diagnostics: transpiler.Diagnostics{
+			{Severity: transpiler.SeverityWarning, Code: transpiler.CodePackageNotFound, Message: "package not found"},
+		}},
+	}
+}
+
+func TestJUnitReport(t *testing.T) {
+	reports := testReports(t)
+	var out bytes.Buffer
+	if err := writeJUnitReport(&out, reports); err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, out.String())
+	}
+	if len(suites.Suites) != 1 {
+		t.Fatalf("got %d test suites, want 1", len(suites.Suites))
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 2 || suite.Failures != 1 || len(suite.Cases) != 2 {
+		t.Fatalf("got %d tests, %d failures and %d cases, want 2, 1 and 2", suite.Tests, suite.Failures, len(suite.Cases))
+	}
+	failed, passed := suite.Cases[0], suite.Cases[1]
+	if failed.Name != reports[0].File || failed.Failure == nil || failed.Failure.Type != transpiler.CodeTypeMismatch || !strings.Contains(failed.Failure.Text, "wrong type") {
+		t.Errorf("failing graph reported as %+v", failed)
+	}
+	if passed.Failure != nil || !strings.Contains(passed.SystemOut, "package not found") {
+		t.Errorf("passing graph reported as %+v", passed)
+	}
+}
+
+func TestSARIFReport(t *testing.T) {
+	reports := testReports(t)
+	var out bytes.Buffer
+	if err := writeSARIFReport(&out, reports); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("got version %s with %d runs, want 2.1.0 with 1", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 2 {
+		t.Fatalf("got %d rules and %d results, want 2 of each", len(run.Tool.Driver.Rules), len(run.Results))
+	}
+
+	failed := run.Results[0]
+	if failed.RuleID != transpiler.CodeTypeMismatch || failed.Level != "error" {
+		t.Errorf("error reported as rule %s at level %s", failed.RuleID, failed.Level)
+	}
+	location := failed.Locations[0]
+	if location.PhysicalLocation.ArtifactLocation.URI != filepath.ToSlash(reports[0].File) {
+		t.Errorf("error located in %s", location.PhysicalLocation.ArtifactLocation.URI)
+	}
+	if region := location.PhysicalLocation.Region; region == nil || region.StartLine != 4 {
+		t.Errorf("error located at %+v, want line 4, where its node is", region)
+	}
+	if len(location.LogicalLocations) != 1 || location.LogicalLocations[0].FullyQualifiedName != "print.a" {
+		t.Errorf("error has logical locations %+v, want print.a", location.LogicalLocations)
+	}
+
+	warned := run.Results[1]
+	if warned.Level != "warning" || warned.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("warning about the whole graph reported as %+v", warned)
+	}
+}
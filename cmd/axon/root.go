@@ -26,8 +26,14 @@ func Execute() {
 func init() {
 	// Add the subcommands to the root command.
 	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(debugCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(traceLineCmd)
 	rootCmd.AddCommand(packCmd)
 	rootCmd.AddCommand(unpackCmd)
 	rootCmd.AddCommand(convertCmd)
+	rootCmd.AddCommand(importCmd)
 	rootCmd.AddCommand(previewCmd)
 }
\ No newline at end of file
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Advik-B/Axon/interp"
+	axonparser "github.com/Advik-B/Axon/parser"
+	"github.com/Advik-B/Axon/pkg/axon"
+	"github.com/Advik-B/Axon/transpiler"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+)
+
+// runCacheVersion is part of every cache key, so binaries built by an older layout of the
+// temporary module are not reused.
+const runCacheVersion = "1"
+
+func init() {
+	runCmd.Flags().Bool("interpret", false, "Run the graph with the built-in interpreter instead of the Go toolchain")
+	runCmd.Flags().Bool("allow-writes", false, "With --interpret, let the graph write files")
+	runCmd.Flags().Bool("no-cache", false, "Compile the graph even if a binary built from the same graph is cached")
+}
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run [path/to/graph.ax] [-- args...]",
+	Short: "Transpiles, compiles and runs an Axon graph file (.ax, .axb, .axd, .axc).",
+	Long: `Run executes a graph's START flow in one step. Arguments after '--' are passed to the
+program, and its output, input and exit code are its own.
+
+The graph is transpiled into a temporary module and compiled with the local 'go'
+toolchain. Compiler errors point into the graph file (.ax and .axd). The binary is cached
+by the graph's content, so running an unchanged graph again skips compilation; --no-cache
+compiles it anyway.
+
+With --interpret the graph is run by a built-in interpreter, with no Go toolchain
+needed: it walks the execution flows and evaluates each node as it is reached. FUNCTION
+nodes can call the functions the graph defines and a registry of common standard
+library functions (fmt, strings, strconv, math, errors, os, time and more). It can read
+files but not write them unless --allow-writes is given, so untrusted graphs can be tried
+with it. Concurrency
+nodes (GO, WAIT, channels, SELECT, DEFER, RECOVER) and struct types need the compiled
+run. The program sees the graph's path as os.Args[0].`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runRun,
+}
+
+func runRun(cmd *cobra.Command, args []string) {
+	filePath := args[0]
+	if dash := cmd.ArgsLenAtDash(); dash > 1 || dash < 0 && len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "❌ Error: program arguments must follow '--', as in 'axon run graph.ax -- arg1 arg2'")
+		os.Exit(1)
+	}
+
+	graph, err := axonparser.LoadGraphFromFile(filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error parsing graph file %s: %v\n", filePath, err)
+		os.Exit(1)
+	}
+	if interpret, _ := cmd.Flags().GetBool("interpret"); interpret {
+		allowWrites, _ := cmd.Flags().GetBool("allow-writes")
+		runInterpreted(graph, args, allowWrites)
+		return
+	}
+
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	binary, err := cachedBinary(graph, filePath, noCache)
+	var diags transpiler.Diagnostics
+	if errors.As(err, &diags) {
+		fmt.Fprintf(os.Stderr, "❌ Error transpiling graph (%d problems found):\n", len(diags))
+		printDiagnostics(os.Stderr, diags)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(runBinary(binary, args[1:]))
+}
+
+// runInterpreted runs a graph with the interpreter, passing it args as os.Args.
+func runInterpreted(graph *axon.Graph, args []string, allowWrites bool) {
+	in, err := interp.New(graph, interp.Options{Stdout: os.Stdout, Args: args, AllowFileWrites: allowWrites})
+	var diags transpiler.Diagnostics
+	if errors.As(err, &diags) {
+		fmt.Fprintf(os.Stderr, "❌ Error: the graph cannot be run (%d problems found):\n", len(diags))
+		printDiagnostics(os.Stderr, diags)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := in.Run(ctx); err != nil {
+		stop()
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, "❌ Interrupted")
+			os.Exit(130)
+		}
+		fmt.Fprintf(os.Stderr, "❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// cachedBinary returns the path of the program compiled from a graph, compiling it first unless
+// it is already in the cache.
+func cachedBinary(graph *axon.Graph, filePath string, noCache bool) (string, error) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		return "", fmt.Errorf("the Go toolchain was not found in PATH; install Go, or run the graph with --interpret")
+	}
+	toolchain, err := toolchainVersion(goTool)
+	if err != nil {
+		return "", err
+	}
+	opts := transpiler.Options{FileName: "main.go", SourceFile: filePath}
+	if abs, err := filepath.Abs(filePath); err == nil {
+		opts.SourceFile = abs // The build runs in another directory.
+	}
+	if opts.SourceLines, err = sourceLines(graph, filePath); err != nil {
+		return "", err
+	}
+	key, err := graphHash(graph, opts, toolchain)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding the cache directory: %w", err)
+	}
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	binary := filepath.Join(cacheDir, "axon", "run", key, name)
+	if _, err := os.Stat(binary); err == nil && !noCache {
+		return binary, nil
+	}
+	if err := compileGraph(graph, opts, filePath, binary, goTool, toolchain); err != nil {
+		return "", err
+	}
+	return binary, nil
+}
+
+// graphHash identifies a graph by its content, whatever file format it was loaded from, and what
+// it is compiled with and for: the build of axon that generates its code, the Go toolchain, and the
+// platform. The graph file's path and the lines of its nodes count too, as the binary reports
+// panics by them.
+func graphHash(graph *axon.Graph, opts transpiler.Options, toolchain string) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(graph)
+	if err != nil {
+		return "", fmt.Errorf("hashing the graph: %w", err)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00", runCacheVersion, axonBuild(), toolchain, runtime.GOOS, runtime.GOARCH, opts.SourceFile)
+	for _, id := range slices.Sorted(maps.Keys(opts.SourceLines)) {
+		fmt.Fprintf(h, "%s:%d\x00", id, opts.SourceLines[id])
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+// compileGraph transpiles a graph with opts into a temporary module and builds it into binary.
+// Problems in the graph are returned as transpiler.Diagnostics; its warnings and compiler errors are
+// printed to stderr.
+func compileGraph(graph *axon.Graph, opts transpiler.Options, filePath, binary, goTool, toolchain string) error {
+	if !hasStart(graph) {
+		return fmt.Errorf("the graph has no START node to run")
+	}
+
+	result, err := transpiler.Compile(context.Background(), graph, opts)
+	if err != nil {
+		return err
+	}
+	if result.Diagnostics.HasErrors() {
+		return result.Diagnostics
+	}
+	if len(result.Diagnostics) > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Warnings:\n")
+		printDiagnostics(os.Stderr, result.Diagnostics)
+	}
+
+	dir, err := os.MkdirTemp("", "axon-run-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	thirdParty := false
+	for _, file := range result.Files {
+		if err := os.WriteFile(filepath.Join(dir, file.Name), file.Content, 0644); err != nil {
+			return err
+		}
+		thirdParty = thirdParty || importsThirdParty(file.Content)
+	}
+	version := goVersion(toolchain)
+	goMod := fmt.Sprintf("module axon.run/%s\n", moduleName(graph))
+	if version != "" {
+		goMod += fmt.Sprintf("\ngo %s\n", version)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		return err
+	}
+
+	// The //line directives make the compiler name the graph file, by a path relative to the
+	// temporary module; errors are shown with the path the graph was run by instead.
+	paths := strings.NewReplacer()
+	if rel, err := filepath.Rel(dir, opts.SourceFile); err == nil {
+		paths = strings.NewReplacer(rel+":", filePath+":", opts.SourceFile+":", filePath+":")
+	}
+	goCmd := func(args ...string) error {
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOWORK=off")
+		out, err := cmd.CombinedOutput()
+		paths.WriteString(os.Stderr, string(out))
+		return err
+	}
+	if thirdParty {
+		// Find and pin the modules of the packages the graph imports beyond the standard library.
+		if err := goCmd("mod", "tidy"); err != nil {
+			return fmt.Errorf("resolving the graph's module dependencies failed: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(binary), 0755); err != nil {
+		return err
+	}
+	// Build next to the cached path and rename, so an interrupted build never leaves a broken binary.
+	// Each build gets a file of its own, as the same graph may be run twice at once.
+	partial, err := os.CreateTemp(filepath.Dir(binary), filepath.Base(binary)+".*.partial")
+	if err != nil {
+		return err
+	}
+	partial.Close()
+	defer os.Remove(partial.Name())
+	if err := goCmd("build", "-o", partial.Name(), "."); err != nil {
+		return fmt.Errorf("compiling the generated code failed: %w", err)
+	}
+	return os.Rename(partial.Name(), binary)
+}
+
+// runBinary runs a compiled graph with the terminal's input and output, and returns its exit code.
+// An interrupt reaches the program, which decides whether to stop.
+func runBinary(binary string, args []string) int {
+	cmd := exec.Command(binary, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		if code := exitErr.ExitCode(); code >= 0 {
+			return code
+		}
+		return 1 // Killed by a signal.
+	default:
+		fmt.Fprintf(os.Stderr, "❌ Error running %s: %v\n", binary, err)
+		return 1
+	}
+}
+
+// hasStart reports whether a graph has a START node, without which it compiles to a library.
+func hasStart(graph *axon.Graph) bool {
+	for _, node := range graph.Nodes {
+		if node.Type == axon.NodeType_START {
+			return true
+		}
+	}
+	return false
+}
+
+// importsThirdParty reports whether a generated file imports a package outside the standard library,
+// whose module must be resolved before it can be built.
+func importsThirdParty(src []byte) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return false
+	}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if first, _, _ := strings.Cut(path, "/"); strings.Contains(first, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// goVersion returns the version of a Go toolchain without its "go" prefix, like "1.24.4", for the
+// go directive of the temporary module, or "" for a development toolchain, whose version go.mod
+// cannot name.
+func goVersion(toolchain string) string {
+	version, ok := strings.CutPrefix(toolchain, "go")
+	if !ok || strings.ContainsAny(version, " -") {
+		return ""
+	}
+	return version
+}
+
+// toolchainVersion returns the version of a Go toolchain, like "go1.24.2".
+func toolchainVersion(goTool string) (string, error) {
+	out, err := exec.Command(goTool, "env", "GOVERSION").Output()
+	if err != nil {
+		return "", fmt.Errorf("running '%s env GOVERSION': %w", goTool, err)
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
+
+// axonBuild identifies the build of axon that is running, so binaries whose code an older build
+// generated are not reused. Builds from a checkout with uncommitted changes also add when the
+// executable was built, as their VCS revision does not change with the code.
+func axonBuild() string {
+	id := runtime.Version()
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return id
+	}
+	id += " " + info.Main.Version
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	id += " " + revision
+	if info.Main.Version == "(devel)" && (revision == "" || modified == "true") {
+		if exe, err := os.Executable(); err == nil {
+			if stat, err := os.Stat(exe); err == nil {
+				id += " " + stat.ModTime().UTC().Format(time.RFC3339Nano)
+			}
+		}
+	}
+	return id
+}
+
+// moduleName derives the last element of the temporary module's path from the graph's ID.
+func moduleName(graph *axon.Graph) string {
+	name := strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || '0' <= r && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, graph.Id)
+	if strings.Trim(name, "-_") == "" {
+		return "graph"
+	}
+	return name
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	axonparser "github.com/Advik-B/Axon/parser"
+	"github.com/Advik-B/Axon/transpiler"
+)
+
+func TestGraphHash(t *testing.T) {
+	graph, err := axonparser.LoadGraphFromFile(filepath.Join("..", "..", "examples", "branch.ax"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := transpiler.Options{SourceFile: "/graphs/branch.ax", SourceLines: map[string]int{"start": 3, "end": 9}}
+	key := func(opts transpiler.Options, toolchain string) string {
+		t.Helper()
+		hash, err := graphHash(graph, opts, toolchain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	base := key(opts, "go1.24.0")
+	if again := key(transpiler.Options{SourceFile: opts.SourceFile, SourceLines: map[string]int{"end": 9, "start": 3}}, "go1.24.0"); again != base {
+		t.Errorf("the same graph hashed to %s and %s", base, again)
+	}
+	for name, changed := range map[string]string{
+		"toolchain":   key(opts, "go1.25.0"),
+		"source file": key(transpiler.Options{SourceFile: "/elsewhere/branch.ax", SourceLines: opts.SourceLines}, "go1.24.0"),
+		"node lines":  key(transpiler.Options{SourceFile: opts.SourceFile, SourceLines: map[string]int{"start": 4, "end": 10}}, "go1.24.0"),
+	} {
+		if changed == base {
+			t.Errorf("a different %s gave the same cache key", name)
+		}
+	}
+}
+
+func TestCachedBinary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a graph with the Go toolchain")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the Go toolchain is not in PATH")
+	}
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("LocalAppData", cache)
+	t.Setenv("HOME", cache)
+
+	src, err := os.ReadFile(filepath.Join("..", "..", "examples", "branch.ax"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "branch.ax")
+	if err := os.WriteFile(file, src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := axonparser.LoadGraphFromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary, err := cachedBinary(graph, file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(binary).CombinedOutput()
+	if err != nil || string(out) != "the number is odd\n" {
+		t.Errorf("compiled graph printed %q with %v", out, err)
+	}
+	built, err := os.Stat(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cached, err := cachedBinary(graph, file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(cached); cached != binary || err != nil || !info.ModTime().Equal(built.ModTime()) {
+		t.Errorf("rerun of an unchanged graph compiled %s again", cached)
+	}
+	if partial, _ := filepath.Glob(filepath.Join(filepath.Dir(binary), "*.partial")); len(partial) != 0 {
+		t.Errorf("partial builds left behind: %v", partial)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Advik-B/Axon/parser"
+	"github.com/Advik-B/Axon/pkg/axon"
+	"github.com/Advik-B/Axon/transpiler"
+
+	"github.com/spf13/cobra"
+)
+
+// traceLineCmd represents the trace-line command
+var traceLineCmd = &cobra.Command{
+	Use:   "trace-line <file:line>",
+	Short: "Finds the graph node a line of generated Go code came from.",
+	Long: `Trace-line resolves a position reported by the Go compiler or a stack trace, such as
+out/main.go:17, to the node that generated it, using the source map 'axon build' writes
+next to the code (out/main.go.axmap). A column, as in out/main.go:17:5, is ignored.
+
+Positions in the graph file itself, as reported for code built with --line-directives
+(for example graph.ax:42), are resolved to the node defined at that line.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runTraceLine,
+}
+
+func runTraceLine(cmd *cobra.Command, args []string) {
+	file, line, err := parseLocation(args[0])
+	if err != nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if graphExtensions[filepath.Ext(file)] {
+		graph, source := loadGraphSource(file)
+		if graph == nil {
+			fmt.Printf("❌ Error: cannot load graph file %s\n", file)
+			os.Exit(1)
+		}
+		node := nodeDefinedAt(graph, source, filepath.Ext(file), line)
+		if node == nil {
+			fmt.Printf("❌ %s:%d is not inside any node's definition\n", file, line)
+			os.Exit(1)
+		}
+		printNode(node.Id, node, "", file, nodeLine(source, filepath.Ext(file), node.Id))
+		return
+	}
+
+	f, err := os.Open(file + ".axmap")
+	if err != nil {
+		fmt.Printf("❌ Error: no source map for %s: %v\n", file, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	sourceMap, err := transpiler.ReadSourceMap(f)
+	if err != nil {
+		fmt.Printf("❌ Error reading %s.axmap: %v\n", file, err)
+		os.Exit(1)
+	}
+	mapping := sourceMap.MappingAt(line)
+	if mapping == nil {
+		fmt.Printf("❌ %s:%d was not generated for any node\n", file, line)
+		os.Exit(1)
+	}
+
+	// The graph adds the node's label and type, if it can still be found.
+	if sourceMap.Source != "" {
+		if graph, source := loadGraphSource(sourceMap.Source); graph != nil {
+			for _, node := range graph.Nodes {
+				if node.Id == mapping.NodeID {
+					printNode(node.Id, node, mapping.Port, sourceMap.Source, nodeLine(source, filepath.Ext(sourceMap.Source), node.Id))
+					return
+				}
+			}
+		}
+	}
+	printNode(mapping.NodeID, nil, mapping.Port, sourceMap.Source, 0)
+}
+
+// parseLocation splits a position like out/main.go:17 or out/main.go:17:5 into its file and line.
+func parseLocation(location string) (string, int, error) {
+	parts := strings.Split(location, ":")
+	// Drop a column, if there is one.
+	if len(parts) > 2 {
+		if _, err := strconv.Atoi(parts[len(parts)-1]); err == nil {
+			if _, err := strconv.Atoi(parts[len(parts)-2]); err == nil {
+				parts = parts[:len(parts)-1]
+			}
+		}
+	}
+	if len(parts) < 2 {
+		return "", 0, fmt.Errorf("'%s' is not a position like out/main.go:17", location)
+	}
+	line, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || line < 1 {
+		return "", 0, fmt.Errorf("'%s' is not a position like out/main.go:17", location)
+	}
+	return strings.Join(parts[:len(parts)-1], ":"), line, nil
+}
+
+// loadGraphSource loads a graph file along with its raw contents, or returns nil if it cannot.
+func loadGraphSource(path string) (*axon.Graph, []byte) {
+	graph, err := parser.LoadGraphFromFile(path)
+	if err != nil {
+		return nil, nil
+	}
+	source, _ := os.ReadFile(path)
+	return graph, source
+}
+
+// nodeDefinedAt returns the node whose definition in a graph file is the last to start at or
+// before the given line.
+func nodeDefinedAt(graph *axon.Graph, source []byte, ext string, line int) *axon.Node {
+	var found *axon.Node
+	foundLine := 0
+	for _, node := range graph.Nodes {
+		if start := nodeLine(source, ext, node.Id); start > foundLine && start <= line {
+			found, foundLine = node, start
+		}
+	}
+	return found
+}
+
+// printNode describes the node a position resolved to. node is nil if the graph could not be
+// loaded, and line is 0 if the node's definition could not be found in it.
+func printNode(nodeID string, node *axon.Node, port string, source string, line int) {
+	fmt.Printf("Node: %s\n", nodeID)
+	if node != nil {
+		fmt.Printf("   Type: %s\n", node.Type)
+		fmt.Printf("   Label: %s\n", node.Label)
+	}
+	if port != "" {
+		fmt.Printf("   Port: %s\n", port)
+	}
+	if source != "" && line > 0 {
+		fmt.Printf("   Defined at: %s:%d\n", source, line)
+	} else if source != "" {
+		fmt.Printf("   Graph: %s\n", source)
+	}
+}
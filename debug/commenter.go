@@ -26,9 +26,30 @@ func GenerateDebugGraph(graph *axon.Graph) *DebugGraph {
 		HeadComment: fmt.Sprintf(" Axon Debug Graph | Name: %s | ID: %s ", graph.Name, graph.Id),
 		ID:          graph.Id,
 		Name:        graph.Name,
+		Imports:     graph.Imports,
 		Nodes:       debugNodes,
 		DataEdges:   graph.DataEdges,
 		ExecEdges:   graph.ExecEdges,
+		Comments:    graph.Comments,
+	}
+}
+
+// ToGraph converts a DebugGraph read back from YAML into a standard axon.Graph.
+func (dg *DebugGraph) ToGraph() *axon.Graph {
+	nodes := make([]*axon.Node, 0, len(dg.Nodes))
+	for _, debugNode := range dg.Nodes {
+		if debugNode.Node != nil {
+			nodes = append(nodes, debugNode.Node)
+		}
+	}
+	return &axon.Graph{
+		Id:        dg.ID,
+		Name:      dg.Name,
+		Imports:   dg.Imports,
+		Nodes:     nodes,
+		DataEdges: dg.DataEdges,
+		ExecEdges: dg.ExecEdges,
+		Comments:  dg.Comments,
 	}
 }
 
@@ -45,6 +66,44 @@ func generateNodeComment(node *axon.Node, graph *axon.Graph, nodeLabels map[stri
 		details = fmt.Sprintf("calls: %s", node.ImplReference)
 	case axon.NodeType_OPERATOR:
 		details = fmt.Sprintf("op: '%s'", node.Config["op"])
+	case axon.NodeType_LOOP:
+		details = fmt.Sprintf("loop: %s", node.Config["mode"])
+	case axon.NodeType_SWITCH, axon.NodeType_SELECT:
+		var cases []string
+		for _, name := range node.SwitchCases() {
+			cases = append(cases, fmt.Sprintf("%s => %s", node.Config[axon.SwitchCasePrefix+name], name))
+		}
+		details = fmt.Sprintf("cases: %s", strings.Join(cases, "; "))
+	case axon.NodeType_VAR_SET, axon.NodeType_VAR_GET:
+		details = fmt.Sprintf("variable: %s", nodeLabels[node.Config["variable"]])
+	case axon.NodeType_ERROR_CHECK:
+		details = "checks error"
+		if msg := node.Config["wrap"]; msg != "" {
+			details = fmt.Sprintf("wraps error: '%s'", msg)
+		}
+	case axon.NodeType_GO:
+		details = "runs body in a goroutine"
+		if node.ImplReference != "" {
+			details = fmt.Sprintf("runs %s in a goroutine", node.ImplReference)
+		}
+		if group := node.Config["group"]; group != "" {
+			details += fmt.Sprintf(", joins '%s'", nodeLabels[group])
+		}
+	case axon.NodeType_DEFER:
+		details = "defers body"
+		if node.ImplReference != "" {
+			details = fmt.Sprintf("defers: %s", node.ImplReference)
+		}
+	case axon.NodeType_RECOVER:
+		details = "recovers from panics"
+	case axon.NodeType_PLACEHOLDER:
+		details = fmt.Sprintf("placeholder for: %s", strings.ReplaceAll(node.Config["source"], "\n", " "))
+	case axon.NodeType_WAIT:
+		kind := node.Config["kind"]
+		if kind == "" {
+			kind = "waitgroup"
+		}
+		details = fmt.Sprintf("waits on: %s", kind)
 	default:
 		details = node.Type.String() // Fallback to the type name
 	}
@@ -62,13 +121,20 @@ func generateNodeComment(node *axon.Node, graph *axon.Graph, nodeLabels map[stri
 		}
 	}
 
-	// Describe execution flow
+	// Describe execution flow, naming the pins when a node has more than the default ones
 	for _, edge := range graph.ExecEdges {
 		if edge.FromNodeId == node.Id {
 			targetLabel := nodeLabels[edge.ToNodeId]
-			sb.WriteString(fmt.Sprintf("\n - After this, execution flows to: '%s'", targetLabel))
+			if edge.SourcePin() != axon.DefaultExecOutput {
+				sb.WriteString(fmt.Sprintf("\n - From '%s', execution flows to: '%s'", edge.SourcePin(), targetLabel))
+			} else {
+				sb.WriteString(fmt.Sprintf("\n - After this, execution flows to: '%s'", targetLabel))
+			}
+			if edge.TargetPin() != axon.DefaultExecInput {
+				sb.WriteString(fmt.Sprintf(" (entering '%s')", edge.TargetPin()))
+			}
 		}
 	}
 
 	return sb.String()
-}
\ No newline at end of file
+}
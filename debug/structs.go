@@ -14,11 +14,14 @@ type DebugGraph struct {
 	HeadComment string       `yaml:"-"`
 	ID          string       `yaml:"id"`
 	Name        string       `yaml:"name"`
+	Imports     []string     `yaml:"imports"`
 	Nodes       []*DebugNode `yaml:"nodes"`
 	DataEdges   []*DataEdge  `yaml:"data_edges"`
 	ExecEdges   []*ExecEdge  `yaml:"exec_edges"`
+	Comments    []*Comment   `yaml:"comments"`
 }
 
 // DataEdge and ExecEdge are included for completeness if we ever want to comment them.
 type DataEdge = axon.DataEdge
-type ExecEdge = axon.ExecEdge
\ No newline at end of file
+type ExecEdge = axon.ExecEdge
+type Comment = axon.Comment
@@ -0,0 +1,548 @@
+package importer
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// operatorLabels name the results of binary operators that have no variable to be named after.
+var operatorLabels = map[token.Token]string{
+	token.ADD: "sum", token.SUB: "difference", token.MUL: "product", token.QUO: "quotient",
+	token.REM: "remainder", token.EQL: "equal", token.NEQ: "notEqual", token.LSS: "less",
+	token.LEQ: "atMost", token.GTR: "greater", token.GEQ: "atLeast", token.LAND: "both",
+	token.LOR: "either",
+}
+
+// builtins are the builtin functions a FUNCTION node can call: those that take only values.
+var builtins = map[string]bool{
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true, "copy": true,
+	"delete": true, "imag": true, "len": true, "max": true, "min": true, "panic": true,
+	"print": true, "println": true, "real": true,
+}
+
+// expr converts an expression into nodes, adding those that compute something to the flow in the
+// order Go evaluates them, and returns where its value comes from. name, if not empty, is the
+// variable the value is assigned to, which the node computing it is labelled with.
+func (f *flow) expr(e ast.Expr, name string) source {
+	e = ast.Unparen(e)
+	if ident, ok := e.(*ast.Ident); ok {
+		if value, ok := f.lookup(f.info.Uses[ident]); ok {
+			return value
+		}
+	}
+	tv := f.info.Types[e]
+	if tv.Value != nil {
+		return f.constant(e, tv, name)
+	}
+
+	out := []*axon.Port{{Name: "out", TypeName: f.typeName(tv.Type)}}
+	switch e := e.(type) {
+	case *ast.CallExpr:
+		if node := f.chanMake(e, name); node != nil {
+			return source{node: node, port: "out"}
+		}
+		if node := f.call(e, []string{""}, name); node != nil {
+			return source{node: node, port: node.Outputs[0].Name}
+		}
+	case *ast.BinaryExpr:
+		if e.Op == token.LAND || e.Op == token.LOR {
+			if !f.pure(e.Y) {
+				break // Computing the right operand up front would skip the short circuit.
+			}
+		}
+		a, b := f.expr(e.X, ""), f.expr(e.Y, "")
+		return f.operator(e.Op.String(), cmp.Or(name, operatorLabels[e.Op], "result"), out, a, b)
+	case *ast.UnaryExpr:
+		switch e.Op {
+		case token.SUB, token.ADD, token.NOT, token.XOR:
+			return f.operator(e.Op.String(), cmp.Or(name, "result"), out, f.expr(e.X, ""))
+		case token.ARROW:
+			if node := f.receive(e, []string{""}, name); node != nil {
+				return source{node: node, port: node.Outputs[0].Name}
+			}
+		case token.AND:
+			// The address of a variable; VAR_GET gives the variable itself.
+			if ident, ok := ast.Unparen(e.X).(*ast.Ident); ok && f.variable(f.info.Uses[ident]) != nil {
+				return f.operator("&", cmp.Or(name, "ptr"), out, f.expr(ident, ""))
+			}
+		}
+	case *ast.StarExpr:
+		return f.operator("*", cmp.Or(name, "value"), out, f.expr(e.X, ""))
+	case *ast.SelectorExpr:
+		if pkgVar, ok := f.info.Uses[e.Sel].(*types.Var); ok && pkgVar.Parent() == pkgVar.Pkg().Scope() {
+			return f.packageVar(e, pkgVar, cmp.Or(name, lowerFirst(e.Sel.Name)))
+		}
+	case *ast.CompositeLit:
+		if value, ok := f.compositeValue(e, tv.Type); ok {
+			return f.newConstant(value, f.typeName(tv.Type), cmp.Or(name, "values"))
+		}
+	}
+	return f.placeholder(e, describeExpr(f.info, e), out, name)
+}
+
+// packageVar reads a variable of another package, like os.Args, by declaring a variable with it
+// as its initial value.
+func (f *flow) packageVar(e *ast.SelectorExpr, obj *types.Var, name string) source {
+	decl := f.step(&axon.Node{
+		Type:   axon.NodeType_VAR_DECLARE,
+		Label:  name,
+		Config: map[string]string{"type": f.typeName(obj.Type()), "value": f.sourceText(e)},
+	})
+	f.fixed[decl.Id] = true
+	node := f.add(&axon.Node{
+		Type:    axon.NodeType_VAR_GET,
+		Label:   name,
+		Outputs: []*axon.Port{{Name: "value", TypeName: f.typeName(obj.Type())}},
+		Config:  map[string]string{"variable": decl.Id},
+	})
+	return source{node: node, port: "value"}
+}
+
+// operator adds an OPERATOR node applying op to one or two operands.
+func (f *flow) operator(op, label string, out []*axon.Port, operands ...source) source {
+	node := &axon.Node{Type: axon.NodeType_OPERATOR, Label: label, Outputs: out, Config: map[string]string{"op": op}}
+	ports := []string{"in"}
+	if len(operands) == 2 {
+		ports = []string{"a", "b"}
+	}
+	for i, operand := range operands {
+		node.Inputs = append(node.Inputs, &axon.Port{Name: ports[i], TypeName: portType(operand)})
+	}
+	f.step(node)
+	for i, operand := range operands {
+		f.connect(operand, node, ports[i])
+	}
+	return source{node: node, port: "out"}
+}
+
+// pure reports whether an expression can be computed before it is known to be needed, without
+// calls, or operations like division that can panic.
+func (f *flow) pure(e ast.Expr) bool {
+	if f.info.Types[e].Value != nil {
+		return true
+	}
+	switch e := e.(type) {
+	case *ast.Ident:
+		return true
+	case *ast.ParenExpr:
+		return f.pure(e.X)
+	case *ast.UnaryExpr:
+		return e.Op != token.ARROW && f.pure(e.X)
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.QUO, token.REM, token.SHL, token.SHR:
+			return false
+		}
+		return f.pure(e.X) && f.pure(e.Y)
+	}
+	return false
+}
+
+// call converts a call into a FUNCTION node, or an OPERATOR for a conversion, with one output per
+// name given (none for a call made as a statement). Names that are empty or _ are replaced by
+// the names of the function's results. It returns nil, having added nothing, if the call cannot
+// be a node: calls of function values or with a spread ... argument, or calls that produce a
+// different number of results.
+func (f *flow) call(call *ast.CallExpr, names []string, label string) *axon.Node {
+	fun := ast.Unparen(call.Fun)
+	if tv := f.info.Types[fun]; tv.IsType() {
+		// A conversion, like string(data)
+		if len(call.Args) != 1 || len(names) != 1 {
+			return nil
+		}
+		typeName := f.typeName(tv.Type)
+		op := typeName
+		if strings.HasPrefix(op, "*") || strings.HasPrefix(op, "<-") || strings.HasPrefix(op, "func") {
+			op = "(" + op + ")"
+		}
+		value := f.operator(op, cmp.Or(label, identifier("as "+typeName)), []*axon.Port{{Name: "out", TypeName: typeName}}, f.expr(call.Args[0], ""))
+		return value.node
+	}
+	impl := f.callee(call)
+	if impl == "" || call.Ellipsis.IsValid() {
+		return nil
+	}
+	sig, _ := f.info.TypeOf(fun).(*types.Signature)
+	if sig == nil {
+		return nil
+	}
+	var results []types.Type
+	switch t := f.info.TypeOf(call).(type) {
+	case *types.Tuple:
+		for i := range t.Len() {
+			results = append(results, t.At(i).Type())
+		}
+	case nil:
+	default:
+		results = append(results, t)
+	}
+	if len(names) > 0 && len(names) != len(results) {
+		return nil
+	}
+
+	node := &axon.Node{Type: axon.NodeType_FUNCTION, ImplReference: impl}
+	var args []source
+	if sel, ok := fun.(*ast.SelectorExpr); ok && f.info.Selections[sel] != nil {
+		node.Inputs = append(node.Inputs, &axon.Port{Name: "receiver"})
+		args = append(args, f.expr(sel.X, ""))
+	}
+	taken := map[string]bool{"receiver": true}
+	for i, arg := range call.Args {
+		args = append(args, f.expr(arg, ""))
+		node.Inputs = append(node.Inputs, &axon.Port{Name: argName(sig, i, taken)})
+	}
+	for i, port := range node.Inputs {
+		port.TypeName = portType(args[i])
+	}
+
+	resultNames := make([]string, len(names))
+	for i := range names {
+		if i < sig.Results().Len() {
+			resultNames[i] = sig.Results().At(i).Name()
+		}
+	}
+	taken = make(map[string]bool)
+	for i, name := range names {
+		port := uniqueName(taken, name, resultNames[i], "out")
+		node.Outputs = append(node.Outputs, &axon.Port{Name: port, TypeName: f.typeName(results[i])})
+	}
+
+	name := impl[strings.LastIndex(impl, ".")+1:]
+	switch {
+	case len(names) == 1 && label != "":
+		node.Label = label
+	case len(names) == 1 && !strings.Contains(impl, "."):
+		// Named after the function, the value would clash with it.
+		node.Label = lowerFirst(name) + "Result"
+	case len(names) == 1:
+		node.Label = lowerFirst(name)
+	default:
+		node.Label = name
+	}
+	f.step(node)
+	for i, arg := range args {
+		f.connect(arg, node, node.Inputs[i].Name)
+	}
+	return node
+}
+
+// chanMake converts make(chan T), or make(chan T, size) with a constant size, into a CHAN_MAKE
+// node. It returns nil, having added nothing, for any other call.
+func (f *flow) chanMake(call *ast.CallExpr, label string) *axon.Node {
+	ident, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	if builtin, ok := f.info.Uses[ident].(*types.Builtin); !ok || builtin.Name() != "make" {
+		return nil
+	}
+	// A channel type of the file, like type pipe chan int, is made by its name, which CHAN_MAKE
+	// cannot tell is a channel.
+	ch, ok := f.info.TypeOf(call).(*types.Chan)
+	if !ok {
+		return nil
+	}
+	node := &axon.Node{
+		Type:    axon.NodeType_CHAN_MAKE,
+		Label:   cmp.Or(label, "ch"),
+		Outputs: []*axon.Port{{Name: "out", TypeName: f.typeName(ch)}},
+	}
+	if len(call.Args) == 2 {
+		tv := f.info.Types[call.Args[1]]
+		if tv.Value == nil {
+			return nil
+		}
+		node.Config = map[string]string{"size": f.constantValue(call.Args[1], tv)}
+	}
+	return f.step(node)
+}
+
+// receive converts a channel receive into a CHAN_RECEIVE node, with an output for the value when
+// one name is given, and one for whether the channel is open too when two are. It returns nil,
+// having added nothing, if the channel's type is not a channel type but a type parameter.
+func (f *flow) receive(e *ast.UnaryExpr, names []string, label string) *axon.Node {
+	ch, ok := f.info.TypeOf(e.X).Underlying().(*types.Chan)
+	if !ok {
+		return nil
+	}
+	channel := f.expr(e.X, "")
+	node := &axon.Node{
+		Type:   axon.NodeType_CHAN_RECEIVE,
+		Label:  cmp.Or(label, "received"),
+		Inputs: []*axon.Port{{Name: "channel", TypeName: portType(channel)}},
+	}
+	taken := make(map[string]bool)
+	for i, name := range names {
+		port, typeName := "value", f.typeName(ch.Elem())
+		if i == 1 {
+			port, typeName = "ok", "bool"
+		}
+		node.Outputs = append(node.Outputs, &axon.Port{Name: uniqueName(taken, name, port), TypeName: typeName})
+	}
+	f.step(node)
+	f.connect(channel, node, "channel")
+	return node
+}
+
+// callee returns what a FUNCTION node calls for a call: a package function as it is written, a
+// method, builtin or function of the file by its name, or "" for anything else.
+func (im *importer) callee(call *ast.CallExpr) string {
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		switch im.info.Uses[fun].(type) {
+		case *types.Builtin:
+			if builtins[fun.Name] {
+				return fun.Name
+			}
+		case *types.Func:
+			return fun.Name
+		}
+	case *ast.SelectorExpr:
+		if ident, ok := fun.X.(*ast.Ident); ok {
+			if _, ok := im.info.Uses[ident].(*types.PkgName); ok {
+				if _, ok := im.info.Uses[fun.Sel].(*types.Func); ok {
+					return ident.Name + "." + fun.Sel.Name
+				}
+				return ""
+			}
+		}
+		if sel := im.info.Selections[fun]; sel != nil && sel.Kind() == types.MethodVal {
+			return fun.Sel.Name
+		}
+	}
+	return ""
+}
+
+// argName names the input of a call's i'th argument after the parameter it is passed to. Extra
+// arguments to a variadic parameter are numbered, as in a, a2, a3.
+func argName(sig *types.Signature, i int, taken map[string]bool) string {
+	params := sig.Params()
+	var name string
+	switch {
+	case i < params.Len()-1 || (i < params.Len() && !sig.Variadic()):
+		name = params.At(i).Name()
+	case sig.Variadic():
+		name = params.At(params.Len() - 1).Name()
+		if n := i - params.Len() + 1; n > 0 && name != "" && name != "_" {
+			name = fmt.Sprintf("%s%d", name, n+1)
+		}
+	}
+	return uniqueName(taken, name, "", fmt.Sprintf("arg%d", i+1))
+}
+
+// uniqueName returns the first of the given names that is a usable port name not yet taken,
+// numbering the last if need be, and marks it taken.
+func uniqueName(taken map[string]bool, names ...string) string {
+	for _, name := range names {
+		if name != "" && name != "_" && !taken[name] {
+			taken[name] = true
+			return name
+		}
+	}
+	base := names[len(names)-1]
+	name := base
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	taken[name] = true
+	return name
+}
+
+// multiValue converts an expression that gives several values, like a call with several results or
+// a comma-ok form like v, ok := m[k], into a node with one output per name.
+func (f *flow) multiValue(e ast.Expr, names []string) *axon.Node {
+	e = ast.Unparen(e)
+	switch e := e.(type) {
+	case *ast.CallExpr:
+		if node := f.call(e, names, ""); node != nil {
+			return node
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.ARROW && len(names) == 2 {
+			if node := f.receive(e, names, ""); node != nil {
+				return node
+			}
+		}
+	}
+	var outputs []*axon.Port
+	tuple, _ := f.info.TypeOf(e).(*types.Tuple)
+	taken := make(map[string]bool)
+	for i, name := range names {
+		var typ types.Type
+		if tuple != nil && i < tuple.Len() {
+			typ = tuple.At(i).Type()
+		}
+		outputs = append(outputs, &axon.Port{Name: uniqueName(taken, name, fmt.Sprintf("out%d", i+1)), TypeName: f.typeName(typ)})
+	}
+	return f.placeholder(e, describeExpr(f.info, e), outputs, "").node
+}
+
+// constant adds a CONSTANT node for a constant expression, named after its text unless a name is
+// given. Its value is the expression as written unless that uses constants of the file, which are
+// not kept by name inside functions.
+func (f *flow) constant(e ast.Expr, tv types.TypeAndValue, name string) source {
+	return f.newConstant(f.constantValue(e, tv), f.typeName(tv.Type), cmp.Or(name, identifier(f.sourceText(e))))
+}
+
+func (f *flow) newConstant(value, typeName, label string) source {
+	node := f.add(&axon.Node{
+		Type:    axon.NodeType_CONSTANT,
+		Label:   label,
+		Outputs: []*axon.Port{{Name: "out", TypeName: typeName}},
+		Config:  map[string]string{"value": value},
+	})
+	return source{node: node, port: "out"}
+}
+
+// constantValue returns the Go expression a CONSTANT node is given for a constant expression.
+func (im *importer) constantValue(e ast.Expr, tv types.TypeAndValue) string {
+	if im.refersToFile(e) {
+		return constantText(tv.Value)
+	}
+	return im.sourceText(e)
+}
+
+// compositeValue returns the Go expression a CONSTANT node is given for a slice or map literal
+// whose keys and elements are all constants, and whether the literal is one.
+func (im *importer) compositeValue(lit *ast.CompositeLit, t types.Type) (string, bool) {
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Map:
+	default:
+		return "", false
+	}
+	var elts []string
+	for _, elt := range lit.Elts {
+		exprs := []ast.Expr{elt}
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			exprs = []ast.Expr{kv.Key, kv.Value}
+		}
+		var parts []string
+		for _, e := range exprs {
+			tv := im.info.Types[e]
+			if tv.Value == nil {
+				return "", false
+			}
+			parts = append(parts, im.constantValue(e, tv))
+		}
+		elts = append(elts, strings.Join(parts, ": "))
+	}
+	return fmt.Sprintf("%s{%s}", im.typeName(t), strings.Join(elts, ", ")), true
+}
+
+// refersToFile reports whether an expression uses anything declared in the file, or iota.
+func (im *importer) refersToFile(e ast.Expr) bool {
+	found := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			obj := im.info.Uses[ident]
+			found = found || (obj != nil && (obj.Pkg() == im.pkg || obj == types.Universe.Lookup("iota")))
+		}
+		return !found
+	})
+	return found
+}
+
+// constantText writes a constant's value as a Go literal.
+func constantText(v constant.Value) string {
+	switch v.Kind() {
+	case constant.String:
+		return strconv.Quote(constant.StringVal(v))
+	case constant.Float:
+		if text := v.ExactString(); !strings.Contains(text, "/") {
+			return text
+		}
+		x, _ := constant.Float64Val(v)
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	return v.String()
+}
+
+// placeholder adds a PLACEHOLDER node to the flow for code that cannot be converted, with the
+// given outputs for the values the code produces, and an input for each local variable it uses,
+// so what it depends on stays connected.
+func (f *flow) placeholder(n ast.Node, what string, outputs []*axon.Port, name string) source {
+	code := f.sourceText(n)
+	node := newPlaceholder(what, code, outputs)
+	if name != "" {
+		node.Label += " for " + name
+	}
+
+	var inputs []source
+	seen := make(map[types.Object]bool)
+	ast.Inspect(n, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		obj, ok := f.info.Uses[ident].(*types.Var)
+		if !ok || seen[obj] || (f.values[obj] == source{} && f.vars[obj] == nil) {
+			return true
+		}
+		seen[obj] = true
+		value, _ := f.lookup(obj)
+		inputs = append(inputs, value)
+		node.Inputs = append(node.Inputs, &axon.Port{Name: ident.Name, TypeName: portType(value)})
+		return true
+	})
+
+	f.step(node)
+	for i, value := range inputs {
+		f.connect(value, node, node.Inputs[i].Name)
+	}
+	f.attachComment(node, placeholderComment(what, code))
+	if len(outputs) == 0 {
+		return source{}
+	}
+	return source{node: node, port: outputs[0].Name}
+}
+
+// describeExpr names the kind of an expression for a placeholder's label.
+func describeExpr(info *types.Info, e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.CompositeLit:
+		return "composite literal"
+	case *ast.FuncLit:
+		return "function literal"
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return "index expression"
+	case *ast.SliceExpr:
+		return "slice expression"
+	case *ast.SelectorExpr:
+		return "selector " + e.Sel.Name
+	case *ast.TypeAssertExpr:
+		return "type assertion"
+	case *ast.UnaryExpr:
+		if e.Op == token.ARROW {
+			return "channel receive"
+		}
+		return e.Op.String() + " expression"
+	case *ast.BinaryExpr:
+		return e.Op.String() + " expression"
+	case *ast.CallExpr:
+		if e.Ellipsis.IsValid() {
+			return "call with ... argument"
+		}
+		return "call"
+	case *ast.Ident:
+		if info.Types[e].IsNil() {
+			return "nil"
+		}
+		return "value " + e.Name
+	}
+	return "expression"
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,516 @@
+package importer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// flow builds the execution flow of one function. Statements are appended where the flow has got
+// to: the exits left pending by the statement before, which all lead into the next node.
+type flow struct {
+	*importer
+	exits []exit
+	// Local variables that only ever hold one value at a time on any path, by the output that
+	// value comes from; reassigning one just moves it to the new output.
+	values map[types.Object]source
+	// Local variables that must be VAR_DECLARE nodes, by that node once declared.
+	mutable map[types.Object]bool
+	vars    map[types.Object]*axon.Node
+	// VAR_DECLARE nodes, by ID, that hold a value read once and are never assigned to.
+	fixed map[string]bool
+	loops []loopScope
+	// results are the function's results; named are the variables of named results. In main,
+	// returns lead to the END node instead, once it is added.
+	results []*axon.Port
+	named   []types.Object
+	main    bool
+	end     *axon.Node
+	// closureBody is set while the body of a GO or DEFER node is converted.
+	closureBody bool
+}
+
+// exit is an execution output still waiting for the node that comes next.
+type exit struct {
+	node string
+	pin  string
+}
+
+// loopScope is a loop that break and continue statements in its body can leave or restart.
+type loopScope struct {
+	node  *axon.Node
+	label string
+}
+
+// importFunc converts a function or method. main becomes the START flow; init functions, which
+// Axon has no node for, and generic functions become placeholders.
+func (im *importer) importFunc(decl *ast.FuncDecl) {
+	if decl.Body == nil || decl.Type.TypeParams != nil || (decl.Recv == nil && decl.Name.Name == "init") {
+		im.globalPlaceholder(decl, "func "+decl.Name.Name, nil)
+		return
+	}
+	obj, _ := im.info.Defs[decl.Name].(*types.Func)
+	if obj == nil {
+		im.globalPlaceholder(decl, "func "+decl.Name.Name, nil)
+		return
+	}
+	sig := obj.Type().(*types.Signature)
+	if recv := sig.Recv(); recv != nil {
+		if named, ok := typeNamed(recv.Type()); ok && named.TypeParams() != nil {
+			im.globalPlaceholder(decl, "func "+decl.Name.Name, nil)
+			return
+		}
+	}
+
+	f := &flow{
+		importer: im,
+		values:   make(map[types.Object]source),
+		mutable:  im.mutableVars(decl),
+		vars:     make(map[types.Object]*axon.Node),
+		fixed:    make(map[string]bool),
+	}
+
+	var entry *axon.Node
+	if decl.Recv == nil && decl.Name.Name == "main" {
+		entry = im.add(&axon.Node{Type: axon.NodeType_START, Label: "Start"})
+		f.main = true
+	} else {
+		entry = &axon.Node{Type: axon.NodeType_FUNC_DEF, Label: decl.Name.Name}
+		if recv := sig.Recv(); recv != nil {
+			entry.Inputs = []*axon.Port{{Name: "receiver", TypeName: im.typeName(recv.Type())}}
+		}
+		for i := range sig.Params().Len() {
+			param := sig.Params().At(i)
+			entry.Outputs = append(entry.Outputs, &axon.Port{Name: paramName(param.Name(), i), TypeName: im.typeName(param.Type())})
+		}
+		im.add(entry)
+		im.attachDoc(entry, decl.Doc)
+
+		f.results = resultPorts(im, sig.Results())
+		for i := range sig.Results().Len() {
+			if result := sig.Results().At(i); result.Name() != "" && result.Name() != "_" {
+				f.named = append(f.named, result)
+			}
+		}
+	}
+	f.exits = []exit{{node: entry.Id}}
+
+	// Parameters are the entry node's outputs, copied into variables if they are reassigned.
+	if recv := sig.Recv(); recv != nil && recv.Name() != "" {
+		f.define(recv, source{node: entry, port: "receiver"})
+	}
+	for i := range sig.Params().Len() {
+		if param := sig.Params().At(i); param.Name() != "" && param.Name() != "_" {
+			f.define(param, source{node: entry, port: entry.Outputs[i].Name})
+		}
+	}
+	for _, result := range f.named {
+		if f.mutable[result] {
+			f.declare(result, source{})
+		}
+	}
+
+	f.block(decl.Body.List)
+
+	if f.main {
+		f.jump(f.endNode(), "")
+	} else if len(f.exits) > 0 {
+		// Every path through a FUNC_DEF flow ends at a RETURN node. Only a statement that became a
+		// placeholder can fall off the end of a function with results; the return keeps the
+		// function's results declared.
+		f.ret(&ast.ReturnStmt{})
+	}
+	f.ignoreUnused()
+}
+
+// typeNamed returns the named type a receiver type is, or points to.
+func typeNamed(t types.Type) (*types.Named, bool) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return named, ok
+}
+
+// paramName names the port of the i'th parameter, which may be unnamed.
+func paramName(name string, i int) string {
+	if name == "" || name == "_" || name == "receiver" {
+		return fmt.Sprintf("arg%d", i+1)
+	}
+	return name
+}
+
+// resultPorts are the inputs of a function's RETURN nodes: one per result, named after it, or
+// err for an unnamed error and result for other unnamed results.
+func resultPorts(im *importer, results *types.Tuple) []*axon.Port {
+	var ports []*axon.Port
+	taken := make(map[string]bool)
+	for i := range results.Len() {
+		result := results.At(i)
+		name := result.Name()
+		if name == "" || name == "_" {
+			name = "result"
+			if types.Identical(result.Type(), errorType) {
+				name = "err"
+			}
+		}
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s%d", name, n)
+		}
+		taken[name] = true
+		ports = append(ports, &axon.Port{Name: name, TypeName: im.typeName(result.Type())})
+	}
+	return ports
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// step adds a node to the flow, after whatever is pending, and leaves its default output pending.
+func (f *flow) step(node *axon.Node) *axon.Node {
+	f.add(node)
+	f.jump(node, "")
+	f.exits = []exit{{node: node.Id}}
+	return node
+}
+
+// branching reports whether any of the pending exits is one of several outputs of a node, like a
+// BRANCH, which must all lead somewhere.
+func (f *flow) branching() bool {
+	for _, e := range f.exits {
+		if e.pin != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// endNode returns main's END node, adding it the first time.
+func (f *flow) endNode() *axon.Node {
+	if f.end == nil {
+		f.end = f.add(&axon.Node{Type: axon.NodeType_END, Label: "End"})
+	}
+	return f.end
+}
+
+// jump leads the pending exits into a node's execution input, leaving nothing pending.
+func (f *flow) jump(to *axon.Node, pin string) {
+	for _, e := range f.exits {
+		f.graph.ExecEdges = append(f.graph.ExecEdges, &axon.ExecEdge{FromNodeId: e.node, FromPort: e.pin, ToNodeId: to.Id, ToPort: pin})
+	}
+	f.exits = nil
+}
+
+// define binds a variable to its first value: a VAR_DECLARE node for variables that are
+// reassigned, or else just the output the value comes from.
+func (f *flow) define(obj types.Object, value source) {
+	if f.mutable[obj] {
+		f.declare(obj, value)
+		return
+	}
+	f.values[obj] = f.snapshot(value)
+}
+
+// declare adds the VAR_DECLARE node of a variable, with an initial value unless value is the zero
+// source.
+func (f *flow) declare(obj types.Object, value source) {
+	typeName := f.typeName(obj.Type())
+	node := &axon.Node{Type: axon.NodeType_VAR_DECLARE, Label: obj.Name()}
+	switch {
+	case value.node != nil && value.node.Type == axon.NodeType_CONSTANT && f.graph.Nodes[len(f.graph.Nodes)-1] == value.node:
+		// A constant made just for this declaration is its initial value.
+		node.Config = map[string]string{"type": typeName, "value": value.node.Config["value"]}
+		f.graph.Nodes = f.graph.Nodes[:len(f.graph.Nodes)-1]
+		delete(f.ids, value.node.Id)
+		value = source{}
+	case value.node != nil:
+		node.Inputs = []*axon.Port{{Name: "value", TypeName: typeName}}
+	default:
+		node.Config = map[string]string{"type": typeName}
+	}
+	f.step(node)
+	f.connect(value, node, "value")
+	f.vars[obj] = node
+}
+
+// assign gives a variable that is already declared a new value.
+func (f *flow) assign(obj types.Object, value source) {
+	if decl := f.variable(obj); decl != nil {
+		f.set(decl, value, "")
+		return
+	}
+	f.values[obj] = f.snapshot(value)
+}
+
+// set adds a VAR_SET node, with op for compound assignments like +=.
+func (f *flow) set(decl *axon.Node, value source, op string) {
+	node := &axon.Node{
+		Type:   axon.NodeType_VAR_SET,
+		Label:  "set " + decl.Label,
+		Inputs: []*axon.Port{{Name: "value", TypeName: portType(value)}},
+		Config: map[string]string{"variable": decl.Id},
+	}
+	if op != "" {
+		node.Config["op"] = op
+		node.Label = fmt.Sprintf("%s %s=", decl.Label, op)
+	}
+	f.step(node)
+	f.connect(value, node, "value")
+}
+
+// variable returns the VAR_DECLARE node a variable became, if it is one.
+func (f *flow) variable(obj types.Object) *axon.Node {
+	if decl, ok := f.vars[obj]; ok {
+		return decl
+	}
+	return f.importer.vars[obj]
+}
+
+// lookup returns where the current value of a variable or constant comes from. Variables are read
+// with a new VAR_GET node each time.
+func (f *flow) lookup(obj types.Object) (source, bool) {
+	if obj == nil {
+		return source{}, false
+	}
+	if value, ok := f.values[obj]; ok {
+		return value, true
+	}
+	if value, ok := f.consts[obj]; ok {
+		return value, true
+	}
+	decl := f.variable(obj)
+	if decl == nil {
+		return source{}, false
+	}
+	node := f.add(&axon.Node{
+		Type:    axon.NodeType_VAR_GET,
+		Label:   decl.Label,
+		Outputs: []*axon.Port{{Name: "value", TypeName: f.typeName(obj.Type())}},
+		Config:  map[string]string{"variable": decl.Id},
+	})
+	return source{node: node, port: "value"}, true
+}
+
+// snapshot copies a value read from a variable into a value of its own, with a conversion to its
+// own type, since a VAR_GET is read wherever it is used and would see later assignments.
+func (f *flow) snapshot(value source) source {
+	if value.node == nil || value.node.Type != axon.NodeType_VAR_GET || f.fixed[value.node.Config["variable"]] {
+		return value
+	}
+	typeName := portType(value)
+	op := typeName
+	if typeName[0] == '*' || typeName[0] == '<' || len(typeName) > 4 && typeName[:4] == "func" {
+		op = "(" + typeName + ")"
+	}
+	node := f.step(&axon.Node{
+		Type:    axon.NodeType_OPERATOR,
+		Label:   value.node.Label,
+		Inputs:  []*axon.Port{{Name: "in", TypeName: typeName}},
+		Outputs: []*axon.Port{{Name: "out", TypeName: typeName}},
+		Config:  map[string]string{"op": op},
+	})
+	f.connect(value, node, "in")
+	return source{node: node, port: "out"}
+}
+
+// portType returns the type of the output a value comes from.
+func portType(value source) string {
+	if value.typeName != "" {
+		return value.typeName
+	}
+	if value.node == nil {
+		return "any"
+	}
+	if port := axon.FindPort(value.node.Outputs, value.port); port != nil {
+		return port.TypeName
+	}
+	return "any"
+}
+
+// ignoreUnused discards the results of calls that were assigned to _, or to a variable that was
+// reassigned before being read, into IGNORE nodes, as the transpiler requires.
+func (f *flow) ignoreUnused() {
+	used := make(map[string]bool)
+	for _, edge := range f.graph.DataEdges {
+		used[edge.FromNodeId+"."+edge.FromPort] = true
+	}
+	for _, node := range f.graph.Nodes {
+		if node.Type != axon.NodeType_FUNCTION {
+			continue
+		}
+		for _, port := range node.Outputs {
+			if used[node.Id+"."+port.Name] {
+				continue
+			}
+			ignore := f.add(&axon.Node{
+				Type:   axon.NodeType_IGNORE,
+				Label:  "ignore " + port.Name,
+				Inputs: []*axon.Port{{Name: "in", TypeName: port.TypeName}},
+			})
+			f.connect(source{node: node, port: port.Name}, ignore, "in")
+			used[node.Id+"."+port.Name] = true
+		}
+	}
+}
+
+// mutableVars finds the local variables of a function that need to be VAR_DECLARE nodes: those
+// assigned in a different block from the one that declares them, whose value then depends on the
+// path taken to get somewhere, those whose address is taken, those declared without a value, and
+// those that a go or defer statement's function literal reads and that are assigned to at all, as
+// the literal may run after they change. Any other variable can simply stand for the output of
+// whatever was last assigned to it.
+func (im *importer) mutableVars(decl *ast.FuncDecl) map[types.Object]bool {
+	mutable := make(map[types.Object]bool)
+	declared := make(map[types.Object]ast.Node)
+	for _, list := range []*ast.FieldList{decl.Recv, decl.Type.Params, decl.Type.Results} {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				if obj := im.info.Defs[name]; obj != nil {
+					declared[obj] = decl.Body
+				}
+			}
+		}
+	}
+
+	closureLits := make(map[*ast.FuncLit]bool)
+	// Variables read inside such a literal but declared outside it, and variables assigned to.
+	captured := make(map[types.Object]bool)
+	changed := make(map[types.Object]bool)
+	var stack []ast.Node
+	// block returns the innermost statement that has a scope of its own around n.
+	block := func(n ast.Node) ast.Node {
+		for i := len(stack) - 1; i >= 0; i-- {
+			switch s := stack[i].(type) {
+			case *ast.ForStmt:
+				if s.Post == n {
+					return s.Body // The post statement runs as part of every iteration.
+				}
+				return s
+			case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause, *ast.IfStmt, *ast.RangeStmt,
+				*ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+				return s
+			}
+		}
+		return decl.Body
+	}
+	assigned := func(expr ast.Expr, in ast.Node) {
+		ident, ok := ast.Unparen(expr).(*ast.Ident)
+		if !ok {
+			return
+		}
+		if obj := im.info.Uses[ident]; obj != nil && declared[obj] != nil {
+			changed[obj] = true
+			if declared[obj] != block(in) {
+				mutable[obj] = true
+			}
+		}
+	}
+
+	// isCountPost reports whether n is the increment of a counted loop, which is the loop's own.
+	isCountPost := func(n ast.Stmt) bool {
+		if len(stack) == 0 {
+			return false
+		}
+		loop, ok := stack[len(stack)-1].(*ast.ForStmt)
+		if !ok || loop.Post != n {
+			return false
+		}
+		_, _, ok = im.countLoop(loop)
+		return ok
+	}
+
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+				closureLits[lit] = true
+			}
+		case *ast.DeferStmt:
+			if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+				closureLits[lit] = true
+			}
+		case *ast.FuncLit:
+			if !closureLits[n] {
+				return false // Function literals become placeholders, unless a go or defer statement runs them.
+			}
+			for _, field := range n.Type.Params.List {
+				for _, name := range field.Names {
+					if obj := im.info.Defs[name]; obj != nil {
+						declared[obj] = n.Body
+					}
+				}
+			}
+		case *ast.AssignStmt:
+			if isCountPost(n) {
+				break
+			}
+			for _, lhs := range n.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && im.info.Defs[ident] != nil {
+					declared[im.info.Defs[ident]] = block(n)
+					continue
+				}
+				assigned(lhs, n)
+			}
+		case *ast.IncDecStmt:
+			if !isCountPost(n) {
+				assigned(n.X, n)
+			}
+		case *ast.RangeStmt:
+			for _, expr := range []ast.Expr{n.Key, n.Value} {
+				if ident, ok := expr.(*ast.Ident); ok && im.info.Defs[ident] != nil {
+					declared[im.info.Defs[ident]] = n
+				} else if ident, ok := expr.(*ast.Ident); ok && im.info.Uses[ident] != nil {
+					mutable[im.info.Uses[ident]] = true
+				}
+			}
+		case *ast.ValueSpec:
+			for _, ident := range n.Names {
+				if obj := im.info.Defs[ident]; obj != nil {
+					declared[obj] = block(n)
+					if len(n.Values) == 0 {
+						mutable[obj] = true
+					}
+				}
+			}
+		case *ast.UnaryExpr:
+			if ident, ok := ast.Unparen(n.X).(*ast.Ident); ok && n.Op == token.AND {
+				if obj := im.info.Uses[ident]; obj != nil && declared[obj] != nil {
+					mutable[obj] = true
+				}
+			}
+		case *ast.Ident:
+			if obj := im.info.Uses[n]; obj != nil && declared[obj] != nil {
+				if lit := innermostLit(stack); lit != nil && !(lit.Pos() <= declared[obj].Pos() && declared[obj].End() <= lit.End()) {
+					captured[obj] = true
+				}
+			}
+		}
+		stack = append(stack, n)
+		return true
+	})
+	for obj := range captured {
+		if changed[obj] {
+			mutable[obj] = true
+		}
+	}
+	return mutable
+}
+
+// innermostLit returns the innermost function literal on a stack of nodes, or nil.
+func innermostLit(stack []ast.Node) *ast.FuncLit {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if lit, ok := stack[i].(*ast.FuncLit); ok {
+			return lit
+		}
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+// Package importer converts Go source into Axon graphs, the reverse of what the transpiler does.
+// Code that has no node equivalent becomes PLACEHOLDER nodes that keep the original source, so a
+// file always imports and the placeholders show what is left to redo by hand.
+package importer
+
+import (
+	"fmt"
+	"go/ast"
+	goimporter "go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// maxLabel is the longest label made from source text, in characters.
+const maxLabel = 40
+
+// importer holds what the conversion of one file shares between its declarations.
+type importer struct {
+	fset  *token.FileSet
+	src   []byte
+	info  *types.Info
+	pkg   *types.Package
+	graph *axon.Graph
+	ids   map[string]bool
+	// The names the file imports packages under, by path, so port types are written the way the
+	// graph's imports name them.
+	pkgNames map[string]string
+	// Package-level constants and variables, by the CONSTANT outputs and VAR_DECLARE nodes they
+	// became.
+	consts map[types.Object]source
+	vars   map[types.Object]*axon.Node
+}
+
+// source is the output a value comes from. The zero source is a value with no node, like nil,
+// which leaves the input it would feed unconnected.
+type source struct {
+	node *axon.Node
+	port string
+	// typeName, if set, is the type of the value where it is used, instead of its output's: the
+	// value a type switch binds has the type of the clause it is used in.
+	typeName string
+}
+
+// Import converts a Go source file into a graph. Functions become FUNC_DEF flows (main becomes
+// the START flow), struct types STRUCT_DEF nodes, and their statements and expressions the nodes
+// that do the same. Anything else becomes a PLACEHOLDER node holding the code it stands in for.
+//
+// The file is type-checked to give ports their types. Type errors, such as from packages that are
+// not available, do not stop the import; the ports they affect get the type any. Only a file that
+// does not parse is an error.
+func Import(filename string, src []byte) (*axon.Graph, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Implicits:  make(map[ast.Node]types.Object),
+	}
+	conf := types.Config{Importer: goimporter.Default(), Error: func(error) {}}
+	pkg, _ := conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	im := &importer{
+		fset:     fset,
+		src:      src,
+		info:     info,
+		pkg:      pkg,
+		graph:    &axon.Graph{Id: slug(name), Name: name},
+		ids:      make(map[string]bool),
+		pkgNames: make(map[string]string),
+		consts:   make(map[types.Object]source),
+		vars:     make(map[types.Object]*axon.Node),
+	}
+
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil {
+			im.graph.Imports = append(im.graph.Imports, spec.Name.Name+" "+path)
+			im.pkgNames[path] = spec.Name.Name
+			continue
+		}
+		im.graph.Imports = append(im.graph.Imports, path)
+	}
+	// Declarations first, so the functions can refer to any of them.
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok {
+			im.importGenDecl(decl)
+		}
+	}
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok {
+			im.importFunc(decl)
+		}
+	}
+	return im.graph, nil
+}
+
+// importGenDecl converts a package-level type, constant or variable declaration.
+func (im *importer) importGenDecl(decl *ast.GenDecl) {
+	for _, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			im.importType(decl, spec)
+		case *ast.ValueSpec:
+			for i, ident := range spec.Names {
+				obj := im.info.Defs[ident]
+				if obj == nil || ident.Name == "_" {
+					continue
+				}
+				var value ast.Expr
+				if i < len(spec.Values) {
+					value = spec.Values[i]
+				}
+				if decl.Tok == token.CONST {
+					im.consts[obj] = im.globalConstant(ident.Name, obj.(*types.Const), value)
+				} else {
+					im.vars[obj] = im.globalVariable(ident.Name, obj.Type(), value, spec)
+				}
+			}
+		}
+	}
+}
+
+// importType converts a struct type into a STRUCT_DEF node. Other types, and structs with
+// embedded fields, tags or type parameters, which STRUCT_DEF cannot describe, become placeholders.
+func (im *importer) importType(decl *ast.GenDecl, spec *ast.TypeSpec) {
+	var node ast.Node = spec
+	if len(decl.Specs) == 1 {
+		node = decl // Keep the `type` keyword and the doc comment in the placeholder's source.
+	}
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok || spec.TypeParams != nil || spec.Assign.IsValid() {
+		im.globalPlaceholder(node, "type "+spec.Name.Name, nil)
+		return
+	}
+	def := &axon.Node{Type: axon.NodeType_STRUCT_DEF, Label: spec.Name.Name}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 || field.Tag != nil {
+			im.globalPlaceholder(node, "type "+spec.Name.Name, nil)
+			return
+		}
+		typeName := im.typeName(im.info.TypeOf(field.Type))
+		for _, name := range field.Names {
+			def.Inputs = append(def.Inputs, &axon.Port{Name: name.Name, TypeName: typeName})
+		}
+	}
+	im.add(def)
+	im.attachDoc(def, firstDoc(spec.Doc, decl.Doc))
+}
+
+// globalConstant converts a package-level constant into a CONSTANT node. Constants computed from
+// iota or other constants of the file get their value, since the names they use are not kept.
+func (im *importer) globalConstant(name string, obj *types.Const, value ast.Expr) source {
+	text := constantText(obj.Val())
+	if value != nil && !im.refersToFile(value) {
+		text = im.sourceText(value)
+	}
+	node := im.add(&axon.Node{
+		Type:    axon.NodeType_CONSTANT,
+		Label:   name,
+		Outputs: []*axon.Port{{Name: "out", TypeName: im.typeName(obj.Type())}},
+		Config:  map[string]string{"value": text},
+	})
+	return source{node: node, port: "out"}
+}
+
+// globalVariable converts a package-level variable into a VAR_DECLARE node. An initial value that
+// is not a constant comes from a placeholder, as it would have to be computed before main runs.
+func (im *importer) globalVariable(name string, typ types.Type, value ast.Expr, spec *ast.ValueSpec) *axon.Node {
+	node := &axon.Node{Type: axon.NodeType_VAR_DECLARE, Label: name, Config: map[string]string{"type": im.typeName(typ)}}
+	if value != nil {
+		if tv := im.info.Types[value]; tv.Value != nil {
+			node.Config["value"] = im.constantValue(value, tv)
+		} else {
+			src := im.globalPlaceholder(value, "initial value of "+name, []*axon.Port{{Name: "out", TypeName: im.typeName(typ)}})
+			node.Inputs = []*axon.Port{{Name: "value", TypeName: im.typeName(typ)}}
+			im.add(node)
+			im.connect(source{node: src, port: "out"}, node, "value")
+			return node
+		}
+	}
+	return im.add(node)
+}
+
+// add gives a node an ID made from its label, unique in the graph, and adds it to the graph.
+func (im *importer) add(node *axon.Node) *axon.Node {
+	base := slug(node.Label)
+	if base == "" {
+		base = strings.ToLower(node.Type.String())
+	}
+	id := base
+	for i := 2; im.ids[id]; i++ {
+		id = fmt.Sprintf("%s_%d", base, i)
+	}
+	im.ids[id] = true
+	node.Id = id
+	im.graph.Nodes = append(im.graph.Nodes, node)
+	return node
+}
+
+// connect adds a data edge from a source to a node's input. A zero source adds nothing.
+func (im *importer) connect(from source, to *axon.Node, port string) {
+	if from.node == nil {
+		return
+	}
+	im.graph.DataEdges = append(im.graph.DataEdges, &axon.DataEdge{
+		FromNodeId: from.node.Id,
+		FromPort:   from.port,
+		ToNodeId:   to.Id,
+		ToPort:     port,
+	})
+}
+
+// globalPlaceholder adds a placeholder outside any execution flow, for a declaration.
+func (im *importer) globalPlaceholder(n ast.Node, what string, outputs []*axon.Port) *axon.Node {
+	node := im.add(newPlaceholder(what, im.sourceText(n), outputs))
+	im.attachComment(node, placeholderComment(what, im.sourceText(n)))
+	return node
+}
+
+// newPlaceholder makes a PLACEHOLDER node for the given code.
+func newPlaceholder(what, code string, outputs []*axon.Port) *axon.Node {
+	return &axon.Node{
+		Type:    axon.NodeType_PLACEHOLDER,
+		Label:   "TODO: " + what,
+		Outputs: outputs,
+		Config:  map[string]string{"source": code},
+	}
+}
+
+func placeholderComment(what, code string) string {
+	return fmt.Sprintf("axon import could not convert this %s into nodes:\n\n%s", what, code)
+}
+
+// attachComment adds a comment to the graph's pool and attaches it to a node.
+func (im *importer) attachComment(node *axon.Node, content string) {
+	id := "comment_" + node.Id
+	im.graph.Comments = append(im.graph.Comments, &axon.Comment{Id: id, Content: content})
+	node.CommentIds = append(node.CommentIds, id)
+}
+
+// attachDoc keeps a declaration's doc comment as a comment on the node it became.
+func (im *importer) attachDoc(node *axon.Node, doc *ast.CommentGroup) {
+	if text := strings.TrimSpace(doc.Text()); text != "" {
+		im.attachComment(node, text)
+	}
+}
+
+func firstDoc(docs ...*ast.CommentGroup) *ast.CommentGroup {
+	for _, doc := range docs {
+		if doc != nil {
+			return doc
+		}
+	}
+	return nil
+}
+
+// typeName writes a type the way the graph names types: packages by the name the file imports
+// them under, and the file's own types unqualified. Types that could not be checked are any.
+func (im *importer) typeName(t types.Type) string {
+	if t == nil {
+		return "any"
+	}
+	t = types.Default(t)
+	if b, ok := t.(*types.Basic); ok && (b.Kind() == types.Invalid || b.Kind() == types.UntypedNil) {
+		return "any"
+	}
+	name := types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == im.pkg {
+			return ""
+		}
+		if name, ok := im.pkgNames[pkg.Path()]; ok {
+			return name
+		}
+		return pkg.Name()
+	})
+	if strings.Contains(name, "invalid type") {
+		return "any"
+	}
+	return name
+}
+
+// sourceText returns the code a syntax node was parsed from.
+func (im *importer) sourceText(n ast.Node) string {
+	file := im.fset.File(n.Pos())
+	if file == nil {
+		return ""
+	}
+	return string(im.src[file.Offset(n.Pos()):file.Offset(n.End())])
+}
+
+// label shortens source text to use as a label.
+func label(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxLabel {
+		return string(runes[:maxLabel-1]) + "…"
+	}
+	return text
+}
+
+// identifier makes a Go identifier out of source text for a node that is named in the generated
+// code: its first words in lower camel case, so "config.txt" becomes configTxt and 1.5 becomes
+// v1_5, since a number's digits would run together. Text with no letters or digits is value.
+func identifier(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return r >= unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	if len(words) == 0 {
+		return "value"
+	}
+	if unicode.IsDigit(rune(words[0][0])) {
+		return "v" + strings.Join(words[:min(len(words), 3)], "_")
+	}
+	var sb strings.Builder
+	for i, word := range words[:min(len(words), 4)] {
+		if i == 0 {
+			sb.WriteString(lowerFirst(word))
+			continue
+		}
+		sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	if token.IsKeyword(sb.String()) {
+		sb.WriteString("Value")
+	}
+	return sb.String()
+}
+
+// slug makes a node ID out of a label: lower case, with runs of other characters as underscores.
+func slug(s string) string {
+	var sb strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(s) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if underscore && sb.Len() > 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(r)
+			underscore = false
+			continue
+		}
+		underscore = true
+	}
+	return sb.String()
+}
@@ -0,0 +1,131 @@
+package importer_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Advik-B/Axon/importer"
+	"github.com/Advik-B/Axon/pkg/axon"
+	"github.com/Advik-B/Axon/transpiler"
+)
+
+func TestImportNodeTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want axon.NodeType
+	}{
+		{"if", `if len(os.Args) > 1 { println("args") }`, axon.NodeType_BRANCH},
+		{"for", `for i := 0; i < 3; i++ { println(i) }`, axon.NodeType_LOOP},
+		{"switch", `switch len(os.Args) { case 1: println("one") }`, axon.NodeType_SWITCH},
+		{"error check", `if _, err := os.Getwd(); err != nil { return }`, axon.NodeType_ERROR_CHECK},
+		{"go", `go println("hi")`, axon.NodeType_GO},
+		{"defer", `defer println("bye")`, axon.NodeType_DEFER},
+		{"deferred function literal", `defer func() { println("bye") }()`, axon.NodeType_DEFER},
+		{"make", `ch := make(chan int, 1); close(ch)`, axon.NodeType_CHAN_MAKE},
+		{"send", `ch := make(chan int, 1); ch <- 1`, axon.NodeType_CHAN_SEND},
+		{"receive", `ch := make(chan int, 1); ch <- 1; println(<-ch)`, axon.NodeType_CHAN_RECEIVE},
+		{"comma-ok receive", `ch := make(chan int); close(ch); _, ok := <-ch; println(ok)`, axon.NodeType_CHAN_RECEIVE},
+		{"select", `select {}`, axon.NodeType_PLACEHOLDER},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package main\n\nimport \"os\"\n\nvar _ = os.Args\n\nfunc main() {\n" + tt.body + "\n}\n"
+			graph, err := importer.Import("main.go", []byte(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var types []axon.NodeType
+			for _, node := range graph.Nodes {
+				types = append(types, node.Type)
+			}
+			if !slices.Contains(types, tt.want) {
+				t.Errorf("no %s node among %v", tt.want, types)
+			}
+			if tt.want != axon.NodeType_PLACEHOLDER && slices.Contains(types, axon.NodeType_PLACEHOLDER) {
+				t.Errorf("unexpected placeholder among %v", types)
+			}
+		})
+	}
+}
+
+// TestImportRoundTrip imports each program in testdata, which the importer converts completely,
+// and checks that the graph builds and prints what the program does.
+func TestImportRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds every program with the Go toolchain")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the Go toolchain is not in PATH")
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "*.go"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no programs found: %v", err)
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".go")
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			graph, err := importer.Import(file, src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, node := range graph.Nodes {
+				if node.Type == axon.NodeType_PLACEHOLDER {
+					t.Errorf("placeholder %q for %s", node.Label, node.Config["source"])
+				}
+			}
+			result, err := transpiler.Compile(context.Background(), graph, transpiler.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Diagnostics.HasErrors() {
+				t.Fatalf("imported graph does not transpile: %v", result.Diagnostics)
+			}
+
+			want := goRun(t, goTool, map[string][]byte{"main.go": src})
+			generated := make(map[string][]byte)
+			for _, f := range result.Files {
+				generated[f.Name] = f.Content
+			}
+			if got := goRun(t, goTool, generated); got != want {
+				t.Errorf("the imported graph prints:\n%s\nbut the program prints:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// goRun runs the files given as a module of their own and returns what they print.
+func goRun(t *testing.T, goTool string, files map[string][]byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"mod", "init", "axon.test/imported"}, {"run", "."}} {
+		var out bytes.Buffer
+		cmd := exec.Command(goTool, args...)
+		cmd.Dir, cmd.Stdout, cmd.Stderr = dir, &out, &out
+		cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out.String())
+		}
+		if args[0] == "run" {
+			return out.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,962 @@
+package importer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// block converts a list of statements. Statements after one that leaves the block, like a return,
+// are never reached and are dropped.
+func (f *flow) block(list []ast.Stmt) {
+	for _, s := range list {
+		if len(f.exits) == 0 {
+			return
+		}
+		f.stmt(s)
+	}
+}
+
+func (f *flow) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.EmptyStmt:
+	case *ast.BlockStmt:
+		f.block(s.List)
+	case *ast.ExprStmt:
+		switch x := ast.Unparen(s.X).(type) {
+		case *ast.CallExpr:
+			if f.call(x, nil, "") != nil {
+				return
+			}
+		case *ast.UnaryExpr:
+			if x.Op == token.ARROW && f.receive(x, nil, "") != nil {
+				return
+			}
+		}
+		f.placeholder(s, describeStmt(s), nil, "")
+	case *ast.AssignStmt:
+		f.assignStmt(s)
+	case *ast.IncDecStmt:
+		op := "+"
+		if s.Tok == token.DEC {
+			op = "-"
+		}
+		ident, ok := ast.Unparen(s.X).(*ast.Ident)
+		if !ok {
+			f.placeholder(s, describeStmt(s), nil, "")
+			return
+		}
+		f.update(ident, op, f.newConstant("1", f.typeName(f.info.TypeOf(s.X)), "one"))
+	case *ast.DeclStmt:
+		f.declStmt(s)
+	case *ast.ReturnStmt:
+		f.ret(s)
+	case *ast.IfStmt:
+		f.ifStmt(s)
+	case *ast.ForStmt:
+		f.forStmt(s, "")
+	case *ast.RangeStmt:
+		f.rangeStmt(s, "")
+	case *ast.LabeledStmt:
+		switch loop := s.Stmt.(type) {
+		case *ast.ForStmt:
+			f.forStmt(loop, s.Label.Name)
+		case *ast.RangeStmt:
+			f.rangeStmt(loop, s.Label.Name)
+		default:
+			f.placeholder(s, describeStmt(s), nil, "")
+		}
+	case *ast.BranchStmt:
+		f.branchStmt(s)
+	case *ast.SwitchStmt:
+		f.switchStmt(s)
+	case *ast.TypeSwitchStmt:
+		f.typeSwitchStmt(s)
+	case *ast.GoStmt:
+		f.spawn(s, s.Call, axon.NodeType_GO, "go")
+	case *ast.DeferStmt:
+		f.spawn(s, s.Call, axon.NodeType_DEFER, "defer")
+	case *ast.SendStmt:
+		f.send(s)
+	default:
+		f.placeholder(s, describeStmt(s), nil, "")
+	}
+}
+
+// assignStmt converts a definition or an assignment. Only variables can be assigned to; storing
+// into a field, an element or through a pointer becomes a placeholder.
+func (f *flow) assignStmt(s *ast.AssignStmt) {
+	for _, lhs := range s.Lhs {
+		if _, ok := lhs.(*ast.Ident); !ok {
+			f.placeholder(s, describeStmt(s), nil, "")
+			return
+		}
+	}
+	idents := make([]*ast.Ident, len(s.Lhs))
+	for i, lhs := range s.Lhs {
+		idents[i] = lhs.(*ast.Ident)
+	}
+
+	if s.Tok != token.ASSIGN && s.Tok != token.DEFINE {
+		// x op= y
+		op := strings.TrimSuffix(s.Tok.String(), "=")
+		f.update(idents[0], op, f.expr(s.Rhs[0], ""))
+		return
+	}
+
+	var values []source
+	if len(s.Rhs) == 1 && len(s.Lhs) > 1 {
+		// a, b := f(), or a comma-ok form like v, ok := m[k]
+		names := make([]string, len(idents))
+		for i, ident := range idents {
+			names[i] = ident.Name
+		}
+		node := f.multiValue(s.Rhs[0], names)
+		for _, port := range node.Outputs {
+			values = append(values, source{node: node, port: port.Name})
+		}
+	} else {
+		for i, rhs := range s.Rhs {
+			values = append(values, f.expr(rhs, f.valueName(idents[i])))
+		}
+		if len(values) > 1 {
+			// Every value is read before any variable changes, as in a, b = b, a.
+			for i := range values {
+				values[i] = f.snapshot(values[i])
+			}
+		}
+	}
+
+	for i, ident := range idents {
+		if ident.Name == "_" {
+			continue
+		}
+		if obj := f.info.Defs[ident]; obj != nil {
+			f.define(obj, values[i])
+		} else if obj := f.info.Uses[ident]; obj != nil {
+			f.assign(obj, values[i])
+		}
+	}
+}
+
+// valueName returns the name for the node computing the value assigned to a variable: the
+// variable's own, unless it is a VAR_DECLARE node that already has that name.
+func (f *flow) valueName(ident *ast.Ident) string {
+	obj := f.info.Defs[ident]
+	if obj == nil {
+		obj = f.info.Uses[ident]
+	}
+	if ident.Name == "_" || f.mutable[obj] || f.variable(obj) != nil {
+		return ""
+	}
+	return ident.Name
+}
+
+// update converts x op= y, and x++ and x--: a compound VAR_SET for variables, and for values an
+// OPERATOR whose result x then stands for.
+func (f *flow) update(ident *ast.Ident, op string, value source) {
+	obj := f.info.Uses[ident]
+	if decl := f.variable(obj); decl != nil {
+		f.set(decl, value, op)
+		return
+	}
+	current, ok := f.lookup(obj)
+	if !ok {
+		current = f.placeholder(ident, "read of "+ident.Name, []*axon.Port{{Name: "out", TypeName: f.typeName(f.info.TypeOf(ident))}}, "")
+	}
+	node := f.step(&axon.Node{
+		Type:    axon.NodeType_OPERATOR,
+		Label:   ident.Name,
+		Inputs:  []*axon.Port{{Name: "a", TypeName: portType(current)}, {Name: "b", TypeName: portType(value)}},
+		Outputs: []*axon.Port{{Name: "out", TypeName: portType(current)}},
+		Config:  map[string]string{"op": op},
+	})
+	f.connect(current, node, "a")
+	f.connect(value, node, "b")
+	f.values[obj] = source{node: node, port: "out"}
+}
+
+// declStmt converts the declarations in a function body. Local types become placeholders.
+func (f *flow) declStmt(s *ast.DeclStmt) {
+	decl, ok := s.Decl.(*ast.GenDecl)
+	if !ok || decl.Tok == token.TYPE {
+		f.placeholder(s, describeStmt(s), nil, "")
+		return
+	}
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.ValueSpec)
+		var values []source
+		switch {
+		case len(spec.Values) == 1 && len(spec.Names) > 1:
+			names := make([]string, len(spec.Names))
+			for i, ident := range spec.Names {
+				names[i] = ident.Name
+			}
+			node := f.multiValue(spec.Values[0], names)
+			for _, port := range node.Outputs {
+				values = append(values, source{node: node, port: port.Name})
+			}
+		default:
+			for i, value := range spec.Values {
+				values = append(values, f.expr(value, f.valueName(spec.Names[i])))
+			}
+		}
+		for i, ident := range spec.Names {
+			obj := f.info.Defs[ident]
+			if obj == nil || ident.Name == "_" {
+				continue
+			}
+			switch {
+			case decl.Tok == token.CONST:
+				f.values[obj] = values[i]
+			case len(values) == 0:
+				f.declare(obj, source{})
+			default:
+				f.define(obj, values[i])
+			}
+		}
+	}
+}
+
+// ret converts a return statement: in main a jump to the END node, in the body of a GO or DEFER
+// node the end of the path, and elsewhere a RETURN node.
+// nil results are left unconnected, which returns the zero value.
+func (f *flow) ret(s *ast.ReturnStmt) {
+	if f.closureBody {
+		f.exits = nil
+		return
+	}
+	if f.main {
+		f.jump(f.endNode(), "")
+		return
+	}
+
+	values := make([]source, len(f.results))
+	switch {
+	case len(s.Results) == 0:
+		// A bare return returns the named results as they are.
+		for i, obj := range f.named {
+			values[i], _ = f.lookup(obj)
+		}
+	case len(s.Results) == 1 && len(f.results) > 1:
+		names := make([]string, len(f.results))
+		for i, port := range f.results {
+			names[i] = port.Name
+		}
+		node := f.multiValue(s.Results[0], names)
+		for i, port := range node.Outputs {
+			values[i] = source{node: node, port: port.Name}
+		}
+	default:
+		for i, result := range s.Results {
+			if f.info.Types[result].IsNil() {
+				continue
+			}
+			values[i] = f.expr(result, f.results[i].Name)
+		}
+	}
+
+	node := &axon.Node{Type: axon.NodeType_RETURN, Label: "Return"}
+	for _, port := range f.results {
+		node.Inputs = append(node.Inputs, &axon.Port{Name: port.Name, TypeName: port.TypeName})
+	}
+	f.step(node)
+	for i, port := range f.results {
+		f.connect(values[i], node, port.Name)
+	}
+	f.exits = nil
+}
+
+// ifStmt converts an if statement into a BRANCH, or an ERROR_CHECK when it tests an error
+// against nil.
+func (f *flow) ifStmt(s *ast.IfStmt) {
+	if s.Init != nil {
+		f.stmt(s.Init)
+	}
+	if f.errorCheck(s) {
+		return
+	}
+
+	cond := f.expr(s.Cond, "")
+	branch := f.step(&axon.Node{
+		Type:   axon.NodeType_BRANCH,
+		Label:  label("if " + f.sourceText(s.Cond)),
+		Inputs: []*axon.Port{{Name: "condition", TypeName: "bool"}},
+	})
+	f.connect(cond, branch, "condition")
+	f.arms(branch, "true", "false", s.Body, s.Else)
+}
+
+// arms converts the two ways out of a BRANCH or ERROR_CHECK, leaving both pending after it.
+func (f *flow) arms(node *axon.Node, thenPin, elsePin string, then *ast.BlockStmt, els ast.Stmt) {
+	f.exits = []exit{{node.Id, thenPin}}
+	f.block(then.List)
+	thenExits := f.exits
+
+	f.exits = []exit{{node.Id, elsePin}}
+	if els != nil {
+		f.stmt(els)
+	}
+	f.exits = append(thenExits, f.exits...)
+}
+
+// errorCheck converts `if err != nil` into an ERROR_CHECK node. When all the check does is return
+// the error, possibly wrapped with fmt.Errorf("...: %w", err), and zero values for the other
+// results, its "failed" output is left unconnected, which does the same.
+func (f *flow) errorCheck(s *ast.IfStmt) bool {
+	cond, ok := ast.Unparen(s.Cond).(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ || !f.info.Types[cond.Y].IsNil() || !types.Identical(f.info.TypeOf(cond.X), errorType) {
+		return false
+	}
+	errValue := f.expr(cond.X, "")
+	check := &axon.Node{
+		Type:   axon.NodeType_ERROR_CHECK,
+		Label:  label("check " + f.sourceText(cond.X)),
+		Inputs: []*axon.Port{{Name: "err", TypeName: "error"}},
+	}
+	wrap, returnsErr := f.returnsError(s.Body, cond.X)
+	if returnsErr {
+		if wrap != "" {
+			check.Config = map[string]string{"wrap": wrap}
+		}
+		f.step(check)
+		f.connect(errValue, check, "err")
+		f.exits = []exit{{check.Id, "ok"}}
+		if s.Else != nil {
+			f.stmt(s.Else)
+		}
+		return true
+	}
+	f.step(check)
+	f.connect(errValue, check, "err")
+	f.arms(check, "failed", "ok", s.Body, s.Else)
+	return true
+}
+
+// returnsError reports whether a block only returns the error err, with zero values for any other
+// results, and the message it wraps the error with, if any.
+func (f *flow) returnsError(body *ast.BlockStmt, err ast.Expr) (wrap string, ok bool) {
+	n := len(f.results)
+	if n == 0 || f.results[n-1].TypeName != "error" || len(body.List) != 1 {
+		return "", false
+	}
+	ret, isReturn := body.List[0].(*ast.ReturnStmt)
+	if !isReturn || len(ret.Results) != n {
+		return "", false
+	}
+	for _, result := range ret.Results[:n-1] {
+		if !f.isZero(result) {
+			return "", false
+		}
+	}
+	last := ast.Unparen(ret.Results[n-1])
+	if f.sameVar(last, err) {
+		return "", true
+	}
+	// fmt.Errorf("reading config: %w", err)
+	call, isCall := last.(*ast.CallExpr)
+	if !isCall || len(call.Args) != 2 || f.callee(call) != "fmt.Errorf" || !f.sameVar(call.Args[1], err) {
+		return "", false
+	}
+	format := f.info.Types[call.Args[0]].Value
+	if format == nil || format.Kind() != constant.String {
+		return "", false
+	}
+	msg, wrapped := strings.CutSuffix(constant.StringVal(format), ": %w")
+	if !wrapped || strings.Contains(strings.ReplaceAll(msg, "%%", ""), "%") {
+		return "", false
+	}
+	return strings.ReplaceAll(msg, "%%", "%"), true
+}
+
+// sameVar reports whether two expressions name the same variable.
+func (f *flow) sameVar(a, b ast.Expr) bool {
+	x, ok1 := ast.Unparen(a).(*ast.Ident)
+	y, ok2 := ast.Unparen(b).(*ast.Ident)
+	return ok1 && ok2 && f.info.Uses[x] != nil && f.info.Uses[x] == f.info.Uses[y]
+}
+
+// isZero reports whether an expression is the zero value of its type: nil, 0, "", false or an
+// empty composite literal.
+func (f *flow) isZero(expr ast.Expr) bool {
+	tv := f.info.Types[expr]
+	if tv.IsNil() {
+		return true
+	}
+	if lit, ok := ast.Unparen(expr).(*ast.CompositeLit); ok {
+		return len(lit.Elts) == 0
+	}
+	if tv.Value == nil {
+		return false
+	}
+	switch tv.Value.Kind() {
+	case constant.String:
+		return constant.StringVal(tv.Value) == ""
+	case constant.Bool:
+		return !constant.BoolVal(tv.Value)
+	case constant.Int, constant.Float, constant.Complex:
+		return constant.Sign(tv.Value) == 0
+	}
+	return false
+}
+
+// forStmt converts a for loop: `for i := 0; i < n; i++` into a counted LOOP, and any other into a
+// LOOP that runs until a BRANCH on its condition breaks out of it. A post statement is run at the
+// end of the body, so loops that continue past it become placeholders.
+func (f *flow) forStmt(s *ast.ForStmt, name string) {
+	if index, count, ok := f.countLoop(s); ok {
+		countValue := f.expr(count, "")
+		loop := f.step(&axon.Node{
+			Type:    axon.NodeType_LOOP,
+			Label:   loopName(name, "repeat "+f.sourceText(count)),
+			Inputs:  []*axon.Port{{Name: "count", TypeName: "int"}},
+			Outputs: []*axon.Port{{Name: index.Name, TypeName: "int"}},
+			Config:  map[string]string{"mode": "count"},
+		})
+		f.connect(countValue, loop, "count")
+		f.loopBody(loop, name, func() {
+			f.define(f.info.Defs[index], source{node: loop, port: index.Name})
+			f.block(s.Body.List)
+		})
+		return
+	}
+	if s.Post != nil && f.continues(s, name) {
+		f.placeholder(s, "for loop", nil, "")
+		return
+	}
+
+	if s.Init != nil {
+		f.stmt(s.Init)
+	}
+	loop := f.step(&axon.Node{
+		Type:   axon.NodeType_LOOP,
+		Label:  loopName(name, "loop"),
+		Config: map[string]string{"mode": "while"},
+	})
+	f.loopBody(loop, name, func() {
+		if s.Cond != nil {
+			// The condition is checked at the start of every iteration.
+			cond := f.expr(s.Cond, "")
+			branch := f.step(&axon.Node{
+				Type:   axon.NodeType_BRANCH,
+				Label:  label("while " + f.sourceText(s.Cond)),
+				Inputs: []*axon.Port{{Name: "condition", TypeName: "bool"}},
+			})
+			f.connect(cond, branch, "condition")
+			f.exits = []exit{{branch.Id, "false"}}
+			f.jump(loop, "break")
+			f.exits = []exit{{branch.Id, "true"}}
+		}
+		f.block(s.Body.List)
+		if s.Post != nil && len(f.exits) > 0 {
+			f.stmt(s.Post)
+		}
+	})
+}
+
+// rangeStmt converts a range loop into a range LOOP, or a counted one when it ranges over an int.
+// Ranging with = into existing variables becomes a placeholder.
+func (f *flow) rangeStmt(s *ast.RangeStmt, name string) {
+	if s.Tok == token.ASSIGN {
+		f.placeholder(s, "range loop", nil, "")
+		return
+	}
+	collection := f.expr(s.X, "")
+	loop := &axon.Node{Type: axon.NodeType_LOOP, Config: map[string]string{"mode": "range"}}
+
+	typ := f.info.TypeOf(s.X)
+	if basic, ok := types.Default(typ).Underlying().(*types.Basic); ok && basic.Info()&types.IsInteger != 0 {
+		// for i := range n
+		loop.Config["mode"] = "count"
+		loop.Label = loopName(name, "repeat "+f.sourceText(s.X))
+		loop.Inputs = []*axon.Port{{Name: "count", TypeName: "int"}}
+	} else {
+		loop.Label = loopName(name, "range "+f.sourceText(s.X))
+		loop.Inputs = []*axon.Port{{Name: "collection", TypeName: f.typeName(typ)}}
+	}
+
+	var vars []*ast.Ident
+	for i, expr := range []ast.Expr{s.Key, s.Value} {
+		ident, _ := expr.(*ast.Ident)
+		if ident == nil {
+			break
+		}
+		port := ident.Name
+		if port == "_" {
+			port = []string{"key", "value"}[i]
+		}
+		loop.Outputs = append(loop.Outputs, &axon.Port{Name: port, TypeName: f.typeName(f.info.TypeOf(ident))})
+		vars = append(vars, ident)
+	}
+	for i, port := range loop.Outputs {
+		if port.TypeName == "any" && vars[i].Name == "_" {
+			port.TypeName = f.typeName(rangeTypes(typ)[i])
+		}
+	}
+
+	f.step(loop)
+	f.connect(collection, loop, loop.Inputs[0].Name)
+	f.loopBody(loop, name, func() {
+		for i, ident := range vars {
+			if obj := f.info.Defs[ident]; obj != nil {
+				f.define(obj, source{node: loop, port: loop.Outputs[i].Name})
+			}
+		}
+		f.block(s.Body.List)
+	})
+}
+
+// rangeTypes returns the types of the key and value ranging over a collection gives.
+func rangeTypes(t types.Type) [2]types.Type {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	intType := types.Typ[types.Int]
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		return [2]types.Type{intType, u.Elem()}
+	case *types.Array:
+		return [2]types.Type{intType, u.Elem()}
+	case *types.Map:
+		return [2]types.Type{u.Key(), u.Elem()}
+	case *types.Chan:
+		return [2]types.Type{u.Elem(), nil}
+	case *types.Basic:
+		return [2]types.Type{intType, types.Typ[types.Rune]}
+	}
+	return [2]types.Type{}
+}
+
+// loopBody converts the body of a loop, leaving its "completed" output pending afterwards. The
+// body ends wherever its flow does, and the loop goes on to its next iteration; only the outputs
+// of nodes like BRANCH, which must all be connected, are led back into it.
+func (f *flow) loopBody(loop *axon.Node, name string, body func()) {
+	f.loops = append(f.loops, loopScope{node: loop, label: name})
+	f.exits = []exit{{loop.Id, "body"}}
+	body()
+	if f.branching() {
+		f.jump(loop, "continue")
+	}
+	f.loops = f.loops[:len(f.loops)-1]
+	f.exits = []exit{{loop.Id, "completed"}}
+}
+
+func loopName(name, fallback string) string {
+	if name != "" {
+		return name // Labelled loops keep their label, which the generated code uses too.
+	}
+	return label(fallback)
+}
+
+// countLoop reports whether a for loop counts an int index up from 0 to a limit that cannot change
+// while it runs, with nothing but its post statement changing the index, and returns the index and
+// the limit.
+func (im *importer) countLoop(s *ast.ForStmt) (*ast.Ident, ast.Expr, bool) {
+	init, ok := s.Init.(*ast.AssignStmt)
+	if !ok || init.Tok != token.DEFINE || len(init.Lhs) != 1 || len(init.Rhs) != 1 {
+		return nil, nil, false
+	}
+	index, ok := init.Lhs[0].(*ast.Ident)
+	obj := im.info.Defs[index]
+	if !ok || obj == nil || !types.Identical(obj.Type(), types.Typ[types.Int]) {
+		return nil, nil, false
+	}
+	if start := im.info.Types[init.Rhs[0]].Value; start == nil || start.ExactString() != "0" {
+		return nil, nil, false
+	}
+	cond, ok := s.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.LSS || !im.isObj(cond.X, obj) || !types.Identical(types.Default(im.info.TypeOf(cond.Y)), types.Typ[types.Int]) {
+		return nil, nil, false
+	}
+	switch post := s.Post.(type) {
+	case *ast.IncDecStmt:
+		if post.Tok != token.INC || !im.isObj(post.X, obj) {
+			return nil, nil, false
+		}
+	case *ast.AssignStmt:
+		one := im.info.Types[post.Rhs[0]].Value
+		if post.Tok != token.ADD_ASSIGN || !im.isObj(post.Lhs[0], obj) || one == nil || one.ExactString() != "1" {
+			return nil, nil, false
+		}
+	default:
+		return nil, nil, false
+	}
+
+	// The limit is read once, so it must be a constant or a variable the body leaves alone, or
+	// the length of one.
+	limit := ast.Unparen(cond.Y)
+	if call, ok := limit.(*ast.CallExpr); ok && len(call.Args) == 1 && im.callee(call) == "len" {
+		limit = ast.Unparen(call.Args[0])
+	}
+	var fixed []types.Object
+	if im.info.Types[cond.Y].Value == nil {
+		ident, ok := limit.(*ast.Ident)
+		if !ok || im.info.Uses[ident] == nil {
+			return nil, nil, false
+		}
+		fixed = append(fixed, im.info.Uses[ident])
+	}
+	for _, o := range append(fixed, obj) {
+		if im.changes(s.Body, o) {
+			return nil, nil, false
+		}
+	}
+	return index, cond.Y, true
+}
+
+// changes reports whether a statement assigns to a variable, or takes its address, anywhere.
+func (im *importer) changes(body ast.Node, obj types.Object) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				found = found || im.isObj(lhs, obj)
+			}
+		case *ast.IncDecStmt:
+			found = found || im.isObj(n.X, obj)
+		case *ast.RangeStmt:
+			found = found || (n.Tok == token.ASSIGN && (im.isObj(n.Key, obj) || im.isObj(n.Value, obj)))
+		case *ast.UnaryExpr:
+			found = found || (n.Op == token.AND && im.isObj(n.X, obj))
+		}
+		return !found
+	})
+	return found
+}
+
+// isObj reports whether an expression is an identifier that refers to obj.
+func (im *importer) isObj(expr ast.Expr, obj types.Object) bool {
+	if expr == nil {
+		return false
+	}
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	return ok && im.info.Uses[ident] == obj
+}
+
+// continues reports whether the body of a loop has a continue statement for it.
+func (f *flow) continues(s *ast.ForStmt, name string) bool {
+	found := false
+	depth := 0
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ForStmt, *ast.RangeStmt:
+			// An unlabelled continue in a nested loop is that loop's.
+			depth++
+			ast.Inspect(loopBodyOf(n), visit)
+			depth--
+			return false
+		case *ast.BranchStmt:
+			if n.Tok == token.CONTINUE && ((n.Label == nil && depth == 0) || (n.Label != nil && n.Label.Name == name)) {
+				found = true
+			}
+		}
+		return !found
+	}
+	ast.Inspect(s.Body, visit)
+	return found
+}
+
+func loopBodyOf(n ast.Node) *ast.BlockStmt {
+	if s, ok := n.(*ast.ForStmt); ok {
+		return s.Body
+	}
+	return n.(*ast.RangeStmt).Body
+}
+
+// branchStmt converts break and continue into jumps back into the loop they leave or restart.
+// goto and fallthrough, and jumps out of statements that became placeholders, become placeholders.
+func (f *flow) branchStmt(s *ast.BranchStmt) {
+	pin := map[token.Token]string{token.BREAK: "break", token.CONTINUE: "continue"}[s.Tok]
+	for i := len(f.loops) - 1; i >= 0 && pin != ""; i-- {
+		if s.Label == nil || s.Label.Name == f.loops[i].label {
+			f.jump(f.loops[i].node, pin)
+			return
+		}
+	}
+	f.placeholder(s, describeStmt(s), nil, "")
+}
+
+// switchStmt converts a switch into a SWITCH node. The cases are Go code in the node's config, so
+// they must be constants; a switch without a tag, or with other cases, becomes a placeholder.
+func (f *flow) switchStmt(s *ast.SwitchStmt) {
+	cases, ok := f.switchCases(s.Body, func(e ast.Expr) (string, bool) {
+		tv := f.info.Types[e]
+		if tv.Value == nil {
+			return "", false
+		}
+		return f.constantValue(e, tv), true
+	})
+	if s.Tag == nil || !ok || f.leavesSwitch(s.Body) {
+		f.placeholder(s, describeStmt(s), nil, "")
+		return
+	}
+	if s.Init != nil {
+		f.stmt(s.Init)
+	}
+	tag := f.expr(s.Tag, "")
+	cases["kind"] = "value"
+	node := f.step(&axon.Node{
+		Type:   axon.NodeType_SWITCH,
+		Label:  label("switch " + f.sourceText(s.Tag)),
+		Inputs: []*axon.Port{{Name: "value", TypeName: portType(tag)}},
+		Config: cases,
+	})
+	f.connect(tag, node, "value")
+	f.clauses(node, s.Body, nil)
+}
+
+// typeSwitchStmt converts a type switch into a SWITCH node of kind "type". The value it binds, as
+// in switch v := x.(type), is the node's output, named after it.
+func (f *flow) typeSwitchStmt(s *ast.TypeSwitchStmt) {
+	cases, _ := f.switchCases(s.Body, func(e ast.Expr) (string, bool) {
+		if f.info.Types[e].IsNil() {
+			return "nil", true
+		}
+		return f.typeName(f.info.TypeOf(e)), true
+	})
+	if f.leavesSwitch(s.Body) {
+		f.placeholder(s, describeStmt(s), nil, "")
+		return
+	}
+	if s.Init != nil {
+		f.stmt(s.Init)
+	}
+	var bound *ast.Ident
+	var assert *ast.TypeAssertExpr
+	switch a := s.Assign.(type) {
+	case *ast.AssignStmt:
+		bound, assert = a.Lhs[0].(*ast.Ident), ast.Unparen(a.Rhs[0]).(*ast.TypeAssertExpr)
+	case *ast.ExprStmt:
+		assert = ast.Unparen(a.X).(*ast.TypeAssertExpr)
+	}
+	subject := f.expr(assert.X, "")
+	cases["kind"] = "type"
+	node := &axon.Node{
+		Type:   axon.NodeType_SWITCH,
+		Label:  label("switch " + f.sourceText(assert.X) + ".(type)"),
+		Inputs: []*axon.Port{{Name: "value", TypeName: portType(subject)}},
+		Config: cases,
+	}
+	if bound != nil {
+		node.Outputs = []*axon.Port{{Name: bound.Name, TypeName: portType(subject)}}
+	}
+	f.step(node)
+	f.connect(subject, node, "value")
+	f.clauses(node, s.Body, func(clause *ast.CaseClause) {
+		// Each clause has a variable of its own, of the type it matches.
+		if obj := f.info.Implicits[clause]; obj != nil && bound != nil {
+			value := source{node: node, port: bound.Name}
+			if typeName := f.typeName(obj.Type()); typeName != node.Outputs[0].TypeName {
+				value.typeName = typeName
+			}
+			f.define(obj, value)
+		}
+	})
+}
+
+// switchCases returns the config entries of a SWITCH node for the clauses of a switch, with what
+// each case matches written by caseText, and whether caseText could write all of them.
+func (f *flow) switchCases(body *ast.BlockStmt, caseText func(ast.Expr) (string, bool)) (map[string]string, bool) {
+	cases := make(map[string]string)
+	pins := casePins(body)
+	for i, stmt := range body.List {
+		clause := stmt.(*ast.CaseClause)
+		if clause.List == nil {
+			continue
+		}
+		var texts []string
+		for _, e := range clause.List {
+			text, ok := caseText(e)
+			if !ok {
+				return nil, false
+			}
+			texts = append(texts, text)
+		}
+		cases[axon.SwitchCasePrefix+pins[i]] = strings.Join(texts, ", ")
+	}
+	return cases, true
+}
+
+// casePins names the execution output of each clause of a switch: default, or case1, case2 and so
+// on, numbered with enough digits that sorting them, as a SWITCH node does, keeps them in order.
+func casePins(body *ast.BlockStmt) []string {
+	count := 0
+	for _, stmt := range body.List {
+		if stmt.(*ast.CaseClause).List != nil {
+			count++
+		}
+	}
+	width := len(strconv.Itoa(count))
+	var pins []string
+	n := 0
+	for _, stmt := range body.List {
+		if stmt.(*ast.CaseClause).List == nil {
+			pins = append(pins, "default")
+			continue
+		}
+		n++
+		pins = append(pins, fmt.Sprintf("case%0*d", width, n))
+	}
+	return pins
+}
+
+// clauses converts the clauses of a switch, each after its output of the SWITCH node, leaving
+// them all pending after it, along with the default output when there is no default clause.
+// enter, if not nil, is called before each clause is converted.
+func (f *flow) clauses(node *axon.Node, body *ast.BlockStmt, enter func(*ast.CaseClause)) {
+	var exits []exit
+	hasDefault := false
+	for i, pin := range casePins(body) {
+		clause := body.List[i].(*ast.CaseClause)
+		hasDefault = hasDefault || clause.List == nil
+		f.exits = []exit{{node.Id, pin}}
+		if enter != nil {
+			enter(clause)
+		}
+		f.block(clause.Body)
+		exits = append(exits, f.exits...)
+	}
+	if !hasDefault {
+		exits = append(exits, exit{node.Id, "default"})
+	}
+	f.exits = exits
+}
+
+// leavesSwitch reports whether a clause of a switch falls through, or breaks out of the switch,
+// which the jumps the flow is built from have no way to do.
+func (f *flow) leavesSwitch(body *ast.BlockStmt) bool {
+	found := false
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			// An unlabelled break in these is their own; fallthrough only ends a clause of this one.
+			return false
+		case *ast.BranchStmt:
+			found = n.Tok == token.FALLTHROUGH || (n.Tok == token.BREAK && n.Label == nil)
+		}
+		return !found
+	}
+	for _, stmt := range body.List {
+		ast.Inspect(stmt.(*ast.CaseClause), visit)
+	}
+	return found
+}
+
+// spawn converts the call of a go or defer statement into a GO or DEFER node: one that calls the
+// function, or for a function literal, one whose body is the literal's, with an input and an
+// output for each parameter. A literal with results or a ... parameter, or a call that cannot be a
+// node, becomes a placeholder.
+func (f *flow) spawn(s ast.Stmt, call *ast.CallExpr, nodeType axon.NodeType, keyword string) {
+	lit, ok := ast.Unparen(call.Fun).(*ast.FuncLit)
+	if !ok {
+		if node := f.call(call, nil, ""); node != nil {
+			node.Type = nodeType
+			node.Label = keyword + " " + node.Label
+			return
+		}
+		f.placeholder(s, describeStmt(s), nil, "")
+		return
+	}
+	sig, _ := f.info.TypeOf(lit).(*types.Signature)
+	if sig == nil || sig.Results().Len() > 0 || sig.Variadic() {
+		f.placeholder(s, describeStmt(s), nil, "")
+		return
+	}
+
+	var args []source
+	for _, arg := range call.Args {
+		args = append(args, f.expr(arg, ""))
+	}
+	node := &axon.Node{Type: nodeType, Label: keyword + " func"}
+	for i := range sig.Params().Len() {
+		param := sig.Params().At(i)
+		port := &axon.Port{Name: paramName(param.Name(), i), TypeName: f.typeName(param.Type())}
+		node.Inputs = append(node.Inputs, port)
+		node.Outputs = append(node.Outputs, &axon.Port{Name: port.Name, TypeName: port.TypeName})
+	}
+	f.step(node)
+	for i, arg := range args {
+		f.connect(arg, node, node.Inputs[i].Name)
+	}
+
+	// The body runs in a function literal: it cannot leave the loops around it, and a return just
+	// ends the path it is on.
+	loops, closureBody := f.loops, f.closureBody
+	f.loops, f.closureBody = nil, true
+	f.exits = []exit{{node.Id, "body"}}
+	for i := range sig.Params().Len() {
+		if param := sig.Params().At(i); param.Name() != "" && param.Name() != "_" {
+			f.define(param, source{node: node, port: node.Outputs[i].Name})
+		}
+	}
+	f.block(lit.Body.List)
+	f.loops, f.closureBody = loops, closureBody
+	// With a body, the way on is one of two outputs, which must both lead somewhere.
+	f.exits = []exit{{node.Id, axon.DefaultExecOutput}}
+}
+
+// send converts a channel send into a CHAN_SEND node.
+func (f *flow) send(s *ast.SendStmt) {
+	ch, ok := f.info.TypeOf(s.Chan).Underlying().(*types.Chan)
+	if !ok {
+		f.placeholder(s, describeStmt(s), nil, "")
+		return
+	}
+	channel, value := f.expr(s.Chan, ""), f.expr(s.Value, "")
+	node := f.step(&axon.Node{
+		Type:  axon.NodeType_CHAN_SEND,
+		Label: "send",
+		Inputs: []*axon.Port{
+			{Name: "channel", TypeName: portType(channel)},
+			{Name: "value", TypeName: f.typeName(ch.Elem())},
+		},
+	})
+	f.connect(channel, node, "channel")
+	f.connect(value, node, "value")
+}
+
+// describeStmt names the kind of a statement for a placeholder's label.
+func describeStmt(s ast.Stmt) string {
+	switch s := s.(type) {
+	case *ast.AssignStmt:
+		return "assignment"
+	case *ast.IncDecStmt:
+		return s.Tok.String() + " statement"
+	case *ast.ExprStmt:
+		return "expression statement"
+	case *ast.DeclStmt:
+		return "declaration"
+	case *ast.SwitchStmt:
+		return "switch"
+	case *ast.TypeSwitchStmt:
+		return "type switch"
+	case *ast.SelectStmt:
+		return "select"
+	case *ast.GoStmt:
+		return "go statement"
+	case *ast.DeferStmt:
+		return "defer"
+	case *ast.SendStmt:
+		return "channel send"
+	case *ast.LabeledStmt:
+		return "labelled " + describeStmt(s.Stmt)
+	case *ast.BranchStmt:
+		return s.Tok.String()
+	}
+	// The rest, like goto, are named after their syntax: *ast.GoStmt is a "go statement".
+	name := strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", s), "*ast."), "Stmt")
+	return strings.ToLower(name) + " statement"
+}
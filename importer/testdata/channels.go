@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func produce(out chan int, n int) {
+	for i := 0; i < n; i++ {
+		out <- i * i
+	}
+	close(out)
+}
+
+func parse(s string) int {
+	defer fmt.Println("parsed", s)
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func main() {
+	defer fmt.Println("bye")
+	count := 0
+	defer func() {
+		fmt.Println("counted", count)
+	}()
+	ch := make(chan int, 3)
+	go produce(ch, 4)
+	for v := range ch {
+		fmt.Println(v)
+		count++
+	}
+	done := make(chan bool)
+	go func() {
+		done <- true
+	}()
+	<-done
+	msgs := make(chan string, 1)
+	msgs <- "hi"
+	close(msgs)
+	m := <-msgs
+	_, ok := <-msgs
+	fmt.Println(m, ok)
+	fmt.Println(parse("12"), parse("twelve"))
+}
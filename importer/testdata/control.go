@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func describe(x any) string {
+	switch v := x.(type) {
+	case int:
+		return fmt.Sprint("int ", v+1)
+	case string, bool:
+		return fmt.Sprint("text or flag ", v)
+	case nil:
+		return "nothing"
+	}
+	return "other"
+}
+
+func day(d string) {
+	switch d {
+	case "Saturday", "Sunday":
+		fmt.Println("weekend")
+	case "Monday":
+	default:
+		fmt.Println("weekday")
+	}
+}
+
+func worker(id int) {
+	fmt.Println("worker", id)
+}
+
+func main() {
+	primes := []int{2, 3, 5, 7}
+	ages := map[string]int{"ann": 31, "bob": 42}
+	fmt.Println(primes, len(ages))
+	fmt.Println(describe(4), describe("a"), describe(1.5))
+	day("Sunday")
+	day("Monday")
+	day("Tuesday")
+	go worker(1)
+	time.Sleep(50 * time.Millisecond)
+	go func(n int) {
+		if n > 1 {
+			fmt.Println("big", n)
+			return
+		}
+		fmt.Println("small", n)
+	}(3)
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		switch i {
+		case 1:
+			continue
+		}
+		fmt.Println("i", i)
+	}
+	switch x := len(primes); x {
+	case 1, 2, 3, 4:
+		fmt.Println("few")
+	}
+}
@@ -0,0 +1,312 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"reflect"
+	"slices"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// errorType is the reflect type of the error interface.
+var errorType = reflect.TypeFor[error]()
+
+// runVarDeclare creates the variable a VAR_DECLARE node declares, initialised from its input, or
+// from the "value" in its config.
+func (in *Interpreter) runVarDeclare(ctx context.Context, f *frame, node *axon.Node) error {
+	typeName, value := node.Config["type"], node.Config["value"]
+	var initial reflect.Value
+	var err error
+	switch {
+	case len(node.Inputs) > 0:
+		initial, err = in.input(ctx, f, node, node.Inputs[0])
+	case value != "":
+		initial, err = in.evalExpr(value, typeName)
+	case typeName != "":
+		initial, err = in.zero(typeName)
+	default:
+		return fmt.Errorf("variable needs a 'type' in config or an initial value")
+	}
+	if err != nil {
+		return err
+	}
+	if typeName != "" {
+		if initial, err = in.coerce(initial, typeName); err != nil {
+			return err
+		}
+	}
+	if !initial.IsValid() {
+		return fmt.Errorf("variable needs a 'type' in config to be initialised to nil")
+	}
+	variable := reflect.New(initial.Type()).Elem()
+	variable.Set(initial)
+	f.vars[node.Id] = variable
+	return nil
+}
+
+// runVarSet assigns to a variable, or updates it with the operator in config "op", as in x += v.
+func (in *Interpreter) runVarSet(ctx context.Context, f *frame, node *axon.Node) error {
+	if len(node.Inputs) != 1 {
+		return fmt.Errorf("variable set must have exactly one input, found %d", len(node.Inputs))
+	}
+	target, err := in.variable(f, node)
+	if err != nil {
+		return err
+	}
+	value, err := in.input(ctx, f, node, node.Inputs[0])
+	if err != nil {
+		return err
+	}
+	if op := node.Config["op"]; op != "" {
+		if value, err = binary(op, target, value); err != nil {
+			return err
+		}
+	}
+	value, err = convert(value, target.Type())
+	if err != nil {
+		return err
+	}
+	target.Set(value)
+	return nil
+}
+
+// variable returns the variable a VAR_SET or VAR_GET node names in config "variable".
+func (in *Interpreter) variable(f *frame, node *axon.Node) (reflect.Value, error) {
+	id := node.Config["variable"]
+	if variable, ok := f.vars[id]; ok {
+		return variable, nil
+	}
+	if variable, ok := in.globalVars[id]; ok {
+		return variable, nil
+	}
+	return reflect.Value{}, fmt.Errorf("variable '%s' has not been declared yet", id)
+}
+
+// runSwitch returns the output of a value SWITCH whose case matches its input, or "default". A
+// switch without an input matches the first case that is true.
+func (in *Interpreter) runSwitch(ctx context.Context, f *frame, node *axon.Node) (string, error) {
+	if kind := node.Config["kind"]; kind != "" && kind != "value" {
+		return "", fmt.Errorf("%s switches cannot be interpreted; build the graph with 'axon build' instead", kind)
+	}
+	tag := reflect.ValueOf(true)
+	switch len(node.Inputs) {
+	case 0:
+	case 1:
+		var err error
+		if tag, err = in.input(ctx, f, node, node.Inputs[0]); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("switch can have at most one input, found %d", len(node.Inputs))
+	}
+	var t reflect.Type
+	if tag.IsValid() {
+		t = tag.Type()
+	}
+	for _, pin := range node.SwitchCases() {
+		// A case may list several values, as in `case 1, 2:`.
+		list := node.Config[axon.SwitchCasePrefix+pin]
+		expr, err := parser.ParseExpr("case_(" + list + ")")
+		if err != nil {
+			return "", fmt.Errorf("case '%s' is not a valid list of Go expressions", list)
+		}
+		for _, arg := range expr.(*ast.CallExpr).Args {
+			value, err := in.evalConstExpr(arg, t)
+			if err != nil {
+				return "", fmt.Errorf("case '%s': %w", pin, err)
+			}
+			if match, err := equal(tag, value); err != nil || match {
+				return pin, err
+			}
+		}
+	}
+	return "default", nil
+}
+
+// runErrorCheck follows the "ok" or "failed" output of an ERROR_CHECK. Without a "failed" path, the
+// function returns the error, with every other result at its zero value.
+func (in *Interpreter) runErrorCheck(ctx context.Context, f *frame, node *axon.Node) (*axon.ExecEdge, outcome, error) {
+	if len(node.Inputs) != 1 {
+		return nil, outcome{}, fmt.Errorf("error check must have exactly one input")
+	}
+	value, err := in.input(ctx, f, node, node.Inputs[0])
+	if err != nil {
+		return nil, outcome{}, err
+	}
+	if isNil(value) {
+		return in.nextEdge(node.Id, "ok"), outcome{}, nil
+	}
+	failure, ok := value.Interface().(error)
+	if !ok {
+		return nil, outcome{}, fmt.Errorf("error check input is %s, not error", value.Type())
+	}
+	if msg := node.Config["wrap"]; msg != "" {
+		failure = fmt.Errorf("%s: %w", msg, failure)
+	}
+	wrapped := reflect.ValueOf(&failure).Elem()
+
+	if failed := in.nextEdge(node.Id, "failed"); failed != nil {
+		if len(node.Outputs) > 0 {
+			f.values[node.Id+"."+node.Outputs[0].Name] = wrapped
+		}
+		return failed, outcome{}, nil
+	}
+	ports := returnPorts(f.flow)
+	if f.flow.Entry.Type != axon.NodeType_FUNC_DEF || len(ports) == 0 || ports[len(ports)-1].TypeName != "error" {
+		return nil, outcome{}, fmt.Errorf("unhandled error: %w", failure)
+	}
+	var results []reflect.Value
+	for _, port := range ports[:len(ports)-1] {
+		zero, err := in.zero(port.TypeName)
+		if err != nil {
+			return nil, outcome{}, err
+		}
+		results = append(results, zero)
+	}
+	return nil, outcome{returned: true, results: append(results, wrapped)}, nil
+}
+
+// runLoop runs a LOOP node's body until the loop is done, or the body breaks out of it, returns, or
+// jumps to an enclosing loop. Config "mode" is count, while or range.
+func (in *Interpreter) runLoop(ctx context.Context, f *frame, node *axon.Node, loops []string) (outcome, error) {
+	body := in.nextEdge(node.Id, "body")
+	inner := append(slices.Clip(loops), node.Id)
+	// iterate runs the body once, and reports whether the loop should stop.
+	iterate := func(outputs ...reflect.Value) (bool, outcome, error) {
+		for i, value := range outputs {
+			if i < len(node.Outputs) {
+				f.values[node.Id+"."+node.Outputs[i].Name] = value
+			}
+		}
+		out, err := in.runBlock(ctx, f, body, inner)
+		switch {
+		case err != nil || out.returned:
+			return true, out, err
+		case out.jump == nil:
+			return false, outcome{}, nil
+		case out.jump.ToNodeId != node.Id:
+			return true, out, nil // To an enclosing loop.
+		default:
+			return out.jump.TargetPin() == "break", outcome{}, nil
+		}
+	}
+
+	switch mode := node.Config["mode"]; mode {
+	case "count":
+		if len(node.Inputs) != 1 {
+			return outcome{}, fmt.Errorf("counted loop must have exactly one input (the count)")
+		}
+		count, err := in.input(ctx, f, node, node.Inputs[0])
+		if err != nil {
+			return outcome{}, err
+		}
+		n, err := convert(count, reflect.TypeFor[int]())
+		if err != nil {
+			return outcome{}, err
+		}
+		for i := 0; i < int(n.Int()); i++ {
+			if stop, out, err := iterate(reflect.ValueOf(i)); stop {
+				return out, err
+			}
+		}
+
+	case "while":
+		for {
+			if len(node.Inputs) > 0 {
+				cond, err := in.input(ctx, f, node, node.Inputs[0])
+				if err != nil {
+					return outcome{}, err
+				}
+				if cond.Kind() != reflect.Bool {
+					return outcome{}, fmt.Errorf("loop condition is %s, not bool", cond.Type())
+				}
+				if !cond.Bool() {
+					break
+				}
+			}
+			if stop, out, err := iterate(); stop {
+				return out, err
+			}
+		}
+
+	case "range":
+		if len(node.Inputs) != 1 {
+			return outcome{}, fmt.Errorf("range loop must have exactly one input (the collection)")
+		}
+		collection, err := in.input(ctx, f, node, node.Inputs[0])
+		if err != nil {
+			return outcome{}, err
+		}
+		return rangeOver(collection, iterate)
+
+	default:
+		return outcome{}, fmt.Errorf("loop has unknown mode '%s' (expected count, while or range)", mode)
+	}
+	return outcome{}, nil
+}
+
+// rangeOver calls iterate with the key (or index) and value of each element of a collection, as a
+// range clause would, until it asks to stop.
+func rangeOver(collection reflect.Value, iterate func(...reflect.Value) (bool, outcome, error)) (outcome, error) {
+	if collection.Kind() == reflect.Interface {
+		collection = collection.Elem()
+	}
+	switch collection.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < collection.Len(); i++ {
+			if stop, out, err := iterate(reflect.ValueOf(i), collection.Index(i)); stop {
+				return out, err
+			}
+		}
+	case reflect.String:
+		for i, r := range collection.String() {
+			if stop, out, err := iterate(reflect.ValueOf(i), reflect.ValueOf(r)); stop {
+				return out, err
+			}
+		}
+	case reflect.Map:
+		it := collection.MapRange()
+		for it.Next() {
+			if stop, out, err := iterate(it.Key(), it.Value()); stop {
+				return out, err
+			}
+		}
+	case reflect.Chan:
+		for {
+			value, ok := collection.Recv()
+			if !ok {
+				break
+			}
+			if stop, out, err := iterate(value); stop {
+				return out, err
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		for i := int64(0); i < collection.Int(); i++ {
+			if stop, out, err := iterate(reflect.ValueOf(i).Convert(collection.Type())); stop {
+				return out, err
+			}
+		}
+	default:
+		if !collection.IsValid() {
+			return outcome{}, fmt.Errorf("cannot range over nil")
+		}
+		return outcome{}, fmt.Errorf("cannot range over %s", collection.Type())
+	}
+	return outcome{}, nil
+}
+
+// isNil reports whether a value is nil, as an error that did not occur is.
+func isNil(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,828 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// basicTypes maps the names of Go's predeclared types to their reflect types.
+var basicTypes = map[string]reflect.Type{
+	"bool":       reflect.TypeFor[bool](),
+	"string":     reflect.TypeFor[string](),
+	"int":        reflect.TypeFor[int](),
+	"int8":       reflect.TypeFor[int8](),
+	"int16":      reflect.TypeFor[int16](),
+	"int32":      reflect.TypeFor[int32](),
+	"int64":      reflect.TypeFor[int64](),
+	"uint":       reflect.TypeFor[uint](),
+	"uint8":      reflect.TypeFor[uint8](),
+	"uint16":     reflect.TypeFor[uint16](),
+	"uint32":     reflect.TypeFor[uint32](),
+	"uint64":     reflect.TypeFor[uint64](),
+	"uintptr":    reflect.TypeFor[uintptr](),
+	"byte":       reflect.TypeFor[byte](),
+	"rune":       reflect.TypeFor[rune](),
+	"float32":    reflect.TypeFor[float32](),
+	"float64":    reflect.TypeFor[float64](),
+	"complex64":  reflect.TypeFor[complex64](),
+	"complex128": reflect.TypeFor[complex128](),
+	"error":      errorType,
+	"any":        reflect.TypeFor[any](),
+}
+
+// typeOf returns the reflect type a type name denotes. Named types from packages must be in the
+// registry.
+func (in *Interpreter) typeOf(typeName string) (reflect.Type, error) {
+	if t, ok := in.types[typeName]; ok {
+		return t, nil
+	}
+	expr, err := parser.ParseExpr(typeName)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a valid Go type", typeName)
+	}
+	t, err := in.typeOfExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	in.types[typeName] = t
+	return t, nil
+}
+
+func (in *Interpreter) typeOfExpr(expr ast.Expr) (reflect.Type, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if t, ok := basicTypes[e.Name]; ok {
+			return t, nil
+		}
+	case *ast.SelectorExpr:
+		v, _ := in.lookup(exprString(e))
+		if t, ok := v.(reflect.Type); ok {
+			return t, nil
+		}
+	case *ast.ParenExpr:
+		return in.typeOfExpr(e.X)
+	case *ast.StarExpr:
+		elem, err := in.typeOfExpr(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PointerTo(elem), nil
+	case *ast.ArrayType:
+		elem, err := in.typeOfExpr(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+		if e.Len == nil {
+			return reflect.SliceOf(elem), nil
+		}
+		n, err := in.evalConstExpr(e.Len, reflect.TypeFor[int]())
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(int(n.Int()), elem), nil
+	case *ast.MapType:
+		key, err := in.typeOfExpr(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := in.typeOfExpr(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(key, value), nil
+	case *ast.ChanType:
+		elem, err := in.typeOfExpr(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		dir := reflect.BothDir
+		switch e.Dir {
+		case ast.SEND:
+			dir = reflect.SendDir
+		case ast.RECV:
+			dir = reflect.RecvDir
+		}
+		return reflect.ChanOf(dir, elem), nil
+	case *ast.InterfaceType:
+		if len(e.Methods.List) == 0 {
+			return basicTypes["any"], nil
+		}
+	}
+	return nil, fmt.Errorf("type '%s' is not known to the interpreter", exprString(expr))
+}
+
+// portType returns the type of a port, or nil if the interpreter does not know it, as for a struct
+// the graph defines. Values for such ports are used as they are.
+func (in *Interpreter) portType(typeName string) reflect.Type {
+	if typeName == "" {
+		return nil
+	}
+	t, err := in.typeOf(typeName)
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+// coerce converts a value to the type a port declares, as Go would on assignment.
+func (in *Interpreter) coerce(v reflect.Value, typeName string) (reflect.Value, error) {
+	t := in.portType(typeName)
+	if t == nil {
+		if v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+			return v.Elem(), nil
+		}
+		return v, nil
+	}
+	return convert(v, t)
+}
+
+// zero returns the zero value of a type.
+func (in *Interpreter) zero(typeName string) (reflect.Value, error) {
+	t, err := in.typeOf(typeName)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.Zero(t), nil
+}
+
+// convert converts a value to a type: interface values are unwrapped, nil becomes the type's nil,
+// and values of other types are converted where Go allows it.
+func convert(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if !v.IsValid() {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use nil as %s", t)
+	}
+	if v.Kind() == reflect.Interface && t.Kind() != reflect.Interface {
+		if v.IsNil() {
+			return convert(reflect.Value{}, t)
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Type() == t:
+		return v, nil
+	case v.Type().AssignableTo(t):
+		converted := reflect.New(t).Elem()
+		converted.Set(v)
+		return converted, nil
+	case v.Type().ConvertibleTo(t) && convertible(v.Type(), t):
+		return v.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", v.Type(), t)
+}
+
+// convertible reports whether a value of one type may be used as another without an explicit
+// conversion in the graph: between numeric types, or types with the same underlying kind.
+func convertible(from, to reflect.Type) bool {
+	if isNumeric(from.Kind()) && isNumeric(to.Kind()) {
+		return true
+	}
+	return from.Kind() == to.Kind() && from.Kind() != reflect.String
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	return reflect.Int <= kind && kind <= reflect.Complex128
+}
+
+// evalExpr evaluates the Go expression in a CONSTANT or VAR_DECLARE config value, like `42`,
+// `"hello"`, `1 << 10`, `time.Second` or `[]string{"a", "b"}`, as a value of the given type.
+func (in *Interpreter) evalExpr(src string, typeName string) (reflect.Value, error) {
+	expr, err := parser.ParseExpr(src)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("'%s' is not a valid Go expression", src)
+	}
+	return in.evalConstExpr(expr, in.portType(typeName))
+}
+
+// evalConstExpr evaluates an expression as a value of type t, or of its default type if t is nil.
+func (in *Interpreter) evalConstExpr(expr ast.Expr, t reflect.Type) (reflect.Value, error) {
+	op, err := in.eval(expr)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	v, err := op.value(t)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if t == nil {
+		return v, nil
+	}
+	return convert(v, t)
+}
+
+// operand is the result of evaluating part of an expression: an untyped constant, as Go keeps
+// literals until it knows their type, or a typed value.
+type operand struct {
+	c    constant.Value
+	kind token.Token // The kind of literal an untyped constant came from, which decides its default type.
+	v    reflect.Value
+}
+
+// value returns an operand as a value of type t, or of its default type if t is nil.
+func (op operand) value(t reflect.Type) (reflect.Value, error) {
+	if op.c == nil {
+		return op.v, nil
+	}
+	if t == nil || t.Kind() == reflect.Interface {
+		t = defaultType(op)
+	}
+	v := reflect.New(t).Elem()
+	c := op.c
+	switch {
+	case t.Kind() == reflect.Bool && c.Kind() == constant.Bool:
+		v.SetBool(constant.BoolVal(c))
+	case t.Kind() == reflect.String && c.Kind() == constant.String:
+		v.SetString(constant.StringVal(c))
+	case reflect.Int <= t.Kind() && t.Kind() <= reflect.Int64:
+		n, exact := constant.Int64Val(constant.ToInt(c))
+		if !exact || v.OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("%s overflows %s", c, t)
+		}
+		v.SetInt(n)
+	case reflect.Uint <= t.Kind() && t.Kind() <= reflect.Uintptr:
+		n, exact := constant.Uint64Val(constant.ToInt(c))
+		if !exact || v.OverflowUint(n) {
+			return reflect.Value{}, fmt.Errorf("%s overflows %s", c, t)
+		}
+		v.SetUint(n)
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		f, _ := constant.Float64Val(constant.ToFloat(c))
+		v.SetFloat(f)
+	case t.Kind() == reflect.Complex64 || t.Kind() == reflect.Complex128:
+		c = constant.ToComplex(c)
+		re, _ := constant.Float64Val(constant.Real(c))
+		im, _ := constant.Float64Val(constant.Imag(c))
+		v.SetComplex(complex(re, im))
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot use %s as %s", c, t)
+	}
+	return v, nil
+}
+
+// defaultType returns the type an untyped constant gets when nothing else decides it.
+func defaultType(op operand) reflect.Type {
+	switch op.kind {
+	case token.CHAR:
+		return basicTypes["rune"]
+	case token.FLOAT:
+		return basicTypes["float64"]
+	case token.IMAG:
+		return basicTypes["complex128"]
+	}
+	switch op.c.Kind() {
+	case constant.Bool:
+		return basicTypes["bool"]
+	case constant.String:
+		return basicTypes["string"]
+	case constant.Float:
+		return basicTypes["float64"]
+	case constant.Complex:
+		return basicTypes["complex128"]
+	}
+	return basicTypes["int"]
+}
+
+// eval evaluates an expression made of literals, operators, conversions and the registry's values
+// and functions.
+func (in *Interpreter) eval(expr ast.Expr) (operand, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		c := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		if c.Kind() == constant.Unknown {
+			return operand{}, fmt.Errorf("invalid literal %s", e.Value)
+		}
+		return operand{c: c, kind: e.Kind}, nil
+	case *ast.ParenExpr:
+		return in.eval(e.X)
+	case *ast.Ident:
+		switch e.Name {
+		case "true", "false":
+			return operand{c: constant.MakeBool(e.Name == "true")}, nil
+		case "nil":
+			return operand{}, nil
+		}
+		if v, ok := in.lookup(e.Name); ok {
+			return operand{v: reflect.ValueOf(v)}, nil
+		}
+	case *ast.SelectorExpr:
+		switch v, _ := in.lookup(exprString(e)); v.(type) {
+		case nil:
+		case reflect.Type:
+			return operand{}, fmt.Errorf("%s is a type, not a value", exprString(e))
+		default:
+			return operand{v: reflect.ValueOf(v)}, nil
+		}
+	case *ast.UnaryExpr:
+		x, err := in.eval(e.X)
+		if err != nil {
+			return operand{}, err
+		}
+		if x.c != nil {
+			if e.Op == token.NOT && x.c.Kind() != constant.Bool {
+				return operand{}, fmt.Errorf("operator ! is not defined on %s", x.c)
+			}
+			return operand{c: constant.UnaryOp(e.Op, x.c, 0), kind: x.kind}, nil
+		}
+		v, err := unary(e.Op.String(), x.v)
+		return operand{v: v}, err
+	case *ast.BinaryExpr:
+		x, err := in.eval(e.X)
+		if err != nil {
+			return operand{}, err
+		}
+		y, err := in.eval(e.Y)
+		if err != nil {
+			return operand{}, err
+		}
+		return evalBinary(e.Op, x, y)
+	case *ast.CallExpr:
+		return in.evalCall(e)
+	case *ast.CompositeLit:
+		return in.evalCompositeLit(e)
+	}
+	return operand{}, fmt.Errorf("'%s' cannot be evaluated by the interpreter", exprString(expr))
+}
+
+// evalBinary applies a binary operator to two operands, folding constants as Go does.
+func evalBinary(op token.Token, x, y operand) (operand, error) {
+	if x.c != nil && y.c != nil {
+		kind := max(x.kind, y.kind) // INT < FLOAT < IMAG < CHAR: the "larger" literal kind wins.
+		switch op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return operand{c: constant.MakeBool(constant.Compare(x.c, op, y.c))}, nil
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(y.c)
+			if !ok {
+				return operand{}, fmt.Errorf("invalid shift count %s", y.c)
+			}
+			return operand{c: constant.Shift(x.c, op, uint(s)), kind: x.kind}, nil
+		case token.QUO:
+			if constant.Sign(y.c) == 0 {
+				return operand{}, fmt.Errorf("division by zero")
+			}
+			if x.c.Kind() == constant.Int && y.c.Kind() == constant.Int {
+				op = token.QUO_ASSIGN // Integer division.
+			}
+		case token.REM:
+			if constant.Sign(y.c) == 0 {
+				return operand{}, fmt.Errorf("division by zero")
+			}
+		}
+		return operand{c: constant.BinaryOp(x.c, op, y.c), kind: kind}, nil
+	}
+	// A constant takes the type of the typed value it meets.
+	if x.c != nil {
+		v, err := x.value(y.v.Type())
+		if err != nil {
+			return operand{}, err
+		}
+		x = operand{v: v}
+	}
+	if y.c != nil && op != token.SHL && op != token.SHR {
+		v, err := y.value(x.v.Type())
+		if err != nil {
+			return operand{}, err
+		}
+		y = operand{v: v}
+	} else if y.c != nil {
+		v, err := y.value(basicTypes["uint"])
+		if err != nil {
+			return operand{}, err
+		}
+		y = operand{v: v}
+	}
+	v, err := binary(op.String(), x.v, y.v)
+	return operand{v: v}, err
+}
+
+// evalCall evaluates a conversion, like float64(2), or a call to a function in the registry, like
+// errors.New("boom").
+func (in *Interpreter) evalCall(e *ast.CallExpr) (operand, error) {
+	if t, err := in.typeOfExpr(e.Fun); err == nil {
+		if len(e.Args) != 1 {
+			return operand{}, fmt.Errorf("conversion to %s takes exactly one argument", t)
+		}
+		x, err := in.eval(e.Args[0])
+		if err != nil {
+			return operand{}, err
+		}
+		v, err := x.value(t)
+		if err != nil && x.c == nil {
+			if x.v.Type().ConvertibleTo(t) {
+				return operand{v: x.v.Convert(t)}, nil
+			}
+			return operand{}, fmt.Errorf("cannot convert %s to %s", x.v.Type(), t)
+		}
+		return operand{v: v}, err
+	}
+	fn, err := in.eval(e.Fun)
+	if err != nil {
+		return operand{}, err
+	}
+	if fn.c != nil || !fn.v.IsValid() || fn.v.Kind() != reflect.Func {
+		return operand{}, fmt.Errorf("%s is not a function", exprString(e.Fun))
+	}
+	var args []reflect.Value
+	for _, arg := range e.Args {
+		x, err := in.eval(arg)
+		if err != nil {
+			return operand{}, err
+		}
+		v, err := x.value(nil)
+		if err != nil {
+			return operand{}, err
+		}
+		args = append(args, v)
+	}
+	results, err := callGo(fn.v, args)
+	if err != nil {
+		return operand{}, err
+	}
+	if len(results) != 1 {
+		return operand{}, fmt.Errorf("%s returns %d values, not one", exprString(e.Fun), len(results))
+	}
+	return operand{v: results[0]}, nil
+}
+
+// evalCompositeLit evaluates a slice, array or map literal.
+func (in *Interpreter) evalCompositeLit(e *ast.CompositeLit) (operand, error) {
+	if e.Type == nil {
+		return operand{}, fmt.Errorf("composite literal needs a type")
+	}
+	t, err := in.typeOfExpr(e.Type)
+	if err != nil {
+		return operand{}, err
+	}
+	element := func(expr ast.Expr, t reflect.Type) (reflect.Value, error) {
+		if lit, ok := expr.(*ast.CompositeLit); ok && lit.Type == nil {
+			lit.Type = typeExpr(t)
+		}
+		return in.evalConstExpr(expr, t)
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		v := reflect.New(t).Elem()
+		if t.Kind() == reflect.Slice {
+			v = reflect.MakeSlice(t, len(e.Elts), len(e.Elts))
+		} else if len(e.Elts) > t.Len() {
+			return operand{}, fmt.Errorf("too many elements for %s", t)
+		}
+		for i, elt := range e.Elts {
+			if _, ok := elt.(*ast.KeyValueExpr); ok {
+				return operand{}, fmt.Errorf("indexed elements are not supported by the interpreter")
+			}
+			elem, err := element(elt, t.Elem())
+			if err != nil {
+				return operand{}, err
+			}
+			v.Index(i).Set(elem)
+		}
+		return operand{v: v}, nil
+	case reflect.Map:
+		v := reflect.MakeMapWithSize(t, len(e.Elts))
+		for _, elt := range e.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return operand{}, fmt.Errorf("map literal elements need a key")
+			}
+			key, err := element(kv.Key, t.Key())
+			if err != nil {
+				return operand{}, err
+			}
+			value, err := element(kv.Value, t.Elem())
+			if err != nil {
+				return operand{}, err
+			}
+			v.SetMapIndex(key, value)
+		}
+		return operand{v: v}, nil
+	}
+	return operand{}, fmt.Errorf("%s literals are not supported by the interpreter", t)
+}
+
+// typeExpr returns an expression for a type, for the elements of a composite literal that leave
+// their type out.
+func typeExpr(t reflect.Type) ast.Expr {
+	expr, _ := parser.ParseExpr(t.String())
+	return expr
+}
+
+// evalOperator computes the output of an OPERATOR node: a binary operation on inputs "a" and "b",
+// or, with one input, a conversion to the type its op names or a unary operator.
+func (in *Interpreter) evalOperator(ctx context.Context, f *frame, node *axon.Node) (reflect.Value, error) {
+	op, ok := node.Config["op"]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("operator has no 'op' in config")
+	}
+	if len(node.Outputs) == 0 {
+		return reflect.Value{}, fmt.Errorf("operator has no output")
+	}
+	var result reflect.Value
+	switch len(node.Inputs) {
+	case 1:
+		x, err := in.input(ctx, f, node, node.Inputs[0])
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if t, err := in.typeOf(op); err == nil {
+			if !x.IsValid() || !x.Type().ConvertibleTo(t) {
+				return reflect.Value{}, fmt.Errorf("cannot convert %s to %s", typeString(x), t)
+			}
+			result = x.Convert(t)
+		} else if fn, ok := in.lookup(op); ok && reflect.TypeOf(fn).Kind() == reflect.Func {
+			results, err := callGo(reflect.ValueOf(fn), []reflect.Value{x})
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if len(results) != 1 {
+				return reflect.Value{}, fmt.Errorf("%s returns %d values, not one", op, len(results))
+			}
+			result = results[0]
+		} else if result, err = unary(op, x); err != nil {
+			return reflect.Value{}, err
+		}
+	case 2:
+		if strings.HasPrefix(op, "&") || (len(op) > 0 && 'A' <= op[0] && op[0] <= 'Z') {
+			return reflect.Value{}, fmt.Errorf("struct literals like %s{...} are not supported by the interpreter", op)
+		}
+		a, err := in.input(ctx, f, node, node.Inputs[0])
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		b, err := in.input(ctx, f, node, node.Inputs[1])
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if result, err = binary(op, a, b); err != nil {
+			return reflect.Value{}, err
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("operator has an unsupported number of inputs (%d)", len(node.Inputs))
+	}
+	return in.coerce(result, node.Outputs[0].TypeName)
+}
+
+// unary applies a unary operator to a value.
+func unary(op string, x reflect.Value) (reflect.Value, error) {
+	if x.Kind() == reflect.Interface && !x.IsNil() {
+		x = x.Elem()
+	}
+	result := reflect.New(x.Type()).Elem()
+	switch kind := x.Kind(); {
+	case op == "!" && kind == reflect.Bool:
+		result.SetBool(!x.Bool())
+	case op == "+" && isNumeric(kind):
+		result.Set(x)
+	case op == "-" && reflect.Int <= kind && kind <= reflect.Int64:
+		result.SetInt(-x.Int())
+	case op == "-" && reflect.Uint <= kind && kind <= reflect.Uintptr:
+		result.SetUint(-x.Uint())
+	case op == "-" && (kind == reflect.Float32 || kind == reflect.Float64):
+		result.SetFloat(-x.Float())
+	case op == "-" && (kind == reflect.Complex64 || kind == reflect.Complex128):
+		result.SetComplex(-x.Complex())
+	case op == "^" && reflect.Int <= kind && kind <= reflect.Int64:
+		result.SetInt(^x.Int())
+	case op == "^" && reflect.Uint <= kind && kind <= reflect.Uintptr:
+		result.SetUint(^x.Uint())
+	default:
+		return reflect.Value{}, fmt.Errorf("operator %s is not defined on %s", op, x.Type())
+	}
+	return result, nil
+}
+
+// binary applies a binary operator to two values, with Go's semantics: integers wrap around,
+// integer division truncates, and dividing an integer by zero is an error.
+func binary(op string, a, b reflect.Value) (reflect.Value, error) {
+	if a.IsValid() && a.Kind() == reflect.Interface && !a.IsNil() && op != "==" && op != "!=" {
+		a = a.Elem()
+	}
+	if b.IsValid() && b.Kind() == reflect.Interface && !b.IsNil() && op != "==" && op != "!=" {
+		b = b.Elem()
+	}
+	switch op {
+	case "==", "!=":
+		equal, err := equal(a, b)
+		return reflect.ValueOf(equal == (op == "==")), err
+	case "&&", "||":
+		if a.Kind() != reflect.Bool || b.Kind() != reflect.Bool {
+			return reflect.Value{}, fmt.Errorf("operator %s is not defined on %s and %s", op, typeString(a), typeString(b))
+		}
+		if op == "&&" {
+			return reflect.ValueOf(a.Bool() && b.Bool()), nil
+		}
+		return reflect.ValueOf(a.Bool() || b.Bool()), nil
+	case "<<", ">>":
+		return shift(op, a, b)
+	}
+	if !a.IsValid() || !b.IsValid() {
+		return reflect.Value{}, fmt.Errorf("operator %s is not defined on nil", op)
+	}
+	if a.Type() != b.Type() {
+		converted, err := convert(b, a.Type())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("mismatched types %s and %s for operator %s", a.Type(), b.Type(), op)
+		}
+		b = converted
+	}
+
+	result := reflect.New(a.Type()).Elem()
+	switch kind := a.Kind(); {
+	case reflect.Int <= kind && kind <= reflect.Int64:
+		x, y := a.Int(), b.Int()
+		if (op == "/" || op == "%") && y == 0 {
+			return reflect.Value{}, fmt.Errorf("integer divide by zero")
+		}
+		switch op {
+		case "<", "<=", ">", ">=":
+			return reflect.ValueOf(compare(op, x, y)), nil
+		case "+":
+			result.SetInt(x + y)
+		case "-":
+			result.SetInt(x - y)
+		case "*":
+			result.SetInt(x * y)
+		case "/":
+			result.SetInt(x / y)
+		case "%":
+			result.SetInt(x % y)
+		case "&":
+			result.SetInt(x & y)
+		case "|":
+			result.SetInt(x | y)
+		case "^":
+			result.SetInt(x ^ y)
+		case "&^":
+			result.SetInt(x &^ y)
+		default:
+			return reflect.Value{}, fmt.Errorf("operator %s is not defined on %s", op, a.Type())
+		}
+	case reflect.Uint <= kind && kind <= reflect.Uintptr:
+		x, y := a.Uint(), b.Uint()
+		if (op == "/" || op == "%") && y == 0 {
+			return reflect.Value{}, fmt.Errorf("integer divide by zero")
+		}
+		switch op {
+		case "<", "<=", ">", ">=":
+			return reflect.ValueOf(compare(op, x, y)), nil
+		case "+":
+			result.SetUint(x + y)
+		case "-":
+			result.SetUint(x - y)
+		case "*":
+			result.SetUint(x * y)
+		case "/":
+			result.SetUint(x / y)
+		case "%":
+			result.SetUint(x % y)
+		case "&":
+			result.SetUint(x & y)
+		case "|":
+			result.SetUint(x | y)
+		case "^":
+			result.SetUint(x ^ y)
+		case "&^":
+			result.SetUint(x &^ y)
+		default:
+			return reflect.Value{}, fmt.Errorf("operator %s is not defined on %s", op, a.Type())
+		}
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		x, y := a.Float(), b.Float()
+		switch op {
+		case "<", "<=", ">", ">=":
+			return reflect.ValueOf(compare(op, x, y)), nil
+		case "+":
+			result.SetFloat(x + y)
+		case "-":
+			result.SetFloat(x - y)
+		case "*":
+			result.SetFloat(x * y)
+		case "/":
+			result.SetFloat(x / y)
+		default:
+			return reflect.Value{}, fmt.Errorf("operator %s is not defined on %s", op, a.Type())
+		}
+	case kind == reflect.Complex64 || kind == reflect.Complex128:
+		x, y := a.Complex(), b.Complex()
+		switch op {
+		case "+":
+			result.SetComplex(x + y)
+		case "-":
+			result.SetComplex(x - y)
+		case "*":
+			result.SetComplex(x * y)
+		case "/":
+			result.SetComplex(x / y)
+		default:
+			return reflect.Value{}, fmt.Errorf("operator %s is not defined on %s", op, a.Type())
+		}
+	case kind == reflect.String:
+		x, y := a.String(), b.String()
+		switch op {
+		case "<", "<=", ">", ">=":
+			return reflect.ValueOf(compare(op, x, y)), nil
+		case "+":
+			result.SetString(x + y)
+		default:
+			return reflect.Value{}, fmt.Errorf("operator %s is not defined on %s", op, a.Type())
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("operator %s is not defined on %s", op, a.Type())
+	}
+	return result, nil
+}
+
+// shift applies a shift operator; the count may be of any integer type, but not negative.
+func shift(op string, a, b reflect.Value) (reflect.Value, error) {
+	var count uint64
+	switch kind := b.Kind(); {
+	case reflect.Int <= kind && kind <= reflect.Int64:
+		if b.Int() < 0 {
+			return reflect.Value{}, fmt.Errorf("negative shift amount")
+		}
+		count = uint64(b.Int())
+	case reflect.Uint <= kind && kind <= reflect.Uintptr:
+		count = b.Uint()
+	default:
+		return reflect.Value{}, fmt.Errorf("shift count is %s, not an integer", typeString(b))
+	}
+	result := reflect.New(a.Type()).Elem()
+	switch kind := a.Kind(); {
+	case reflect.Int <= kind && kind <= reflect.Int64:
+		if op == "<<" {
+			result.SetInt(a.Int() << count)
+		} else {
+			result.SetInt(a.Int() >> count)
+		}
+	case reflect.Uint <= kind && kind <= reflect.Uintptr:
+		if op == "<<" {
+			result.SetUint(a.Uint() << count)
+		} else {
+			result.SetUint(a.Uint() >> count)
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("operator %s is not defined on %s", op, a.Type())
+	}
+	return result, nil
+}
+
+func compare[T int64 | uint64 | float64 | string](op string, x, y T) bool {
+	switch op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	}
+	return x >= y
+}
+
+// equal compares two values with ==, converting the second to the type of the first if they differ.
+func equal(a, b reflect.Value) (bool, error) {
+	if isNil(a) || isNil(b) {
+		return isNil(a) && isNil(b), nil
+	}
+	if a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	if b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	if a.Type() != b.Type() {
+		converted, err := convert(b, a.Type())
+		if err != nil {
+			return false, fmt.Errorf("mismatched types %s and %s for operator ==", a.Type(), b.Type())
+		}
+		b = converted
+	}
+	if !a.Type().Comparable() {
+		return false, fmt.Errorf("%s values cannot be compared", a.Type())
+	}
+	return a.Interface() == b.Interface(), nil
+}
+
+func typeString(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return v.Type().String()
+}
+
+// exprString formats an expression as Go source.
+func exprString(expr ast.Expr) string {
+	return types.ExprString(expr)
+}
@@ -0,0 +1,92 @@
+package interp_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Advik-B/Axon/interp"
+	"github.com/Advik-B/Axon/parser"
+	"github.com/Advik-B/Axon/transpiler"
+)
+
+// TestExamplesMatchTranspiled runs every example graph both ways, interpreted and as the Go
+// program it transpiles to, and checks that the two print the same. Examples using nodes the
+// interpreter does not cover are skipped.
+func TestExamplesMatchTranspiled(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds every example with the Go toolchain")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the Go toolchain is not in PATH")
+	}
+	files, err := filepath.Glob(filepath.Join("..", "examples", "*.ax"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no examples found: %v", err)
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".ax")
+		t.Run(name, func(t *testing.T) {
+			graph, err := parser.LoadGraphFromFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+
+			var interpreted bytes.Buffer
+			in, err := interp.New(graph, interp.Options{Stdout: &interpreted, Args: []string{name}})
+			if err == nil {
+				err = in.Run(ctx)
+			}
+			if err != nil && strings.Contains(err.Error(), "cannot be interpreted") {
+				t.Skip(err)
+			}
+			interpErr := err
+
+			result, err := transpiler.Compile(ctx, graph, transpiler.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Diagnostics.HasErrors() {
+				t.Fatalf("example does not transpile: %v", result.Diagnostics)
+			}
+			dir := t.TempDir()
+			for _, f := range result.Files {
+				if err := os.WriteFile(filepath.Join(dir, f.Name), f.Content, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if out, err := goCommand(goTool, dir, "mod", "init", "axon.test/"+name).CombinedOutput(); err != nil {
+				t.Fatalf("go mod init: %v\n%s", err, out)
+			}
+			var compiled, stderr bytes.Buffer
+			cmd := goCommand(goTool, dir, "run", ".")
+			cmd.Stdout, cmd.Stderr = &compiled, &stderr
+			runErr := cmd.Run()
+			if _, ok := runErr.(*exec.ExitError); runErr != nil && !ok {
+				t.Fatalf("go run: %v", runErr)
+			}
+
+			if interpreted.String() != compiled.String() {
+				t.Errorf("interpreted output:\n%s\ndiffers from the transpiled program's:\n%s%s", interpreted.String(), compiled.String(), stderr.String())
+			}
+			if (interpErr != nil) != (runErr != nil) {
+				t.Errorf("interpreter error %v, but the transpiled program exited with %v\n%s", interpErr, runErr, stderr.String())
+			}
+		})
+	}
+}
+
+// goCommand runs the go tool in dir, with a module of its own whatever the environment says.
+func goCommand(goTool, dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command(goTool, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOWORK=off")
+	return cmd
+}
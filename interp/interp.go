@@ -0,0 +1,603 @@
+// Package interp runs Axon graphs directly, without generating Go code or needing the Go toolchain.
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+	"github.com/Advik-B/Axon/transpiler"
+)
+
+// maxDepth bounds the nesting of calls to functions the graph defines, so runaway recursion
+// fails with an error instead of exhausting the stack.
+const maxDepth = 10000
+
+// Options controls how a graph is run. The zero value runs it with the standard library registry,
+// writing to os.Stdout.
+type Options struct {
+	// Stdout receives what the graph prints. It defaults to os.Stdout.
+	Stdout io.Writer
+	// Args are the program arguments the graph sees in os.Args, starting with the program name.
+	Args []string
+	// Registry resolves the impl_references and qualified names the graph uses. It defaults to
+	// Stdlib(Stdout, Args).
+	Registry Registry
+	// AllowFileWrites adds FileWrites to the default registry. Leave it off to run graphs that are
+	// not trusted.
+	AllowFileWrites bool
+	// Hook, if set, is called before each node on an execution flow runs, starting with the START
+	// or FUNC_DEF node the flow begins at. The run waits for it to return; an error stops the run.
+	Hook func(*Step) error
+}
+
+// Interpreter runs the flows of one graph. Global constants and variables are set up once, when
+// the interpreter is created, and variables keep their values between calls.
+type Interpreter struct {
+	graph    *axon.Graph
+	registry Registry
+	// Maps the aliases the graph imports packages under to the packages' default names.
+	aliases map[string]string
+	nodes   map[string]*axon.Node
+	// Maps "nodeID\x00pin" to the execution edge leaving that pin.
+	execEdges map[string]*axon.ExecEdge
+	// Maps "nodeID.port" to the data edge arriving at that input.
+	dataEdges map[string]*axon.DataEdge
+	main      *transpiler.Flow
+	// Map FUNC_DEF labels to their flows, for plain functions and for methods.
+	funcs   map[string]*transpiler.Flow
+	methods map[string]*transpiler.Flow
+	// Nodes that run as part of a flow, or are set up as globals; any other node is computed
+	// from its inputs wherever its value is needed.
+	scheduled map[string]bool
+	// Values of global constants by "nodeID.port", and global variables by node ID.
+	globals    map[string]reflect.Value
+	globalVars map[string]reflect.Value
+	types      map[string]reflect.Type
+	evaluating map[string]bool
+	hook       func(*Step) error
+	// The frames of the flows being run, outermost first.
+	stack []*frame
+}
+
+// Error is a failure while running a graph, such as a panic in a called function or a division
+// by zero. It names the node that was running.
+type Error struct {
+	NodeID string
+	Label  string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("node '%s' (%s): %v", e.Label, e.NodeID, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// nodeError attributes an error to the node that was running, unless it already names one.
+func nodeError(node *axon.Node, err error) error {
+	var e *Error
+	if err == nil || errors.As(err, &e) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &Error{NodeID: node.Id, Label: node.Label, Err: err}
+}
+
+// frame holds the state of one call of a flow.
+type frame struct {
+	flow *transpiler.Flow
+	// Values produced so far by the flow's nodes, keyed "nodeID.port".
+	values map[string]reflect.Value
+	// Local variables by VAR_DECLARE node ID.
+	vars map[string]reflect.Value
+}
+
+// outcome is how a block of a flow finished, when it did not simply run to its end.
+type outcome struct {
+	// returned is set when a RETURN node ended the function, with its results.
+	returned bool
+	results  []reflect.Value
+	// jump is an execution edge back into an enclosing LOOP, to continue or break it.
+	jump *axon.ExecEdge
+}
+
+// New prepares a graph to be run. The graph's flows are validated as they are for transpiling;
+// if any is invalid, the error is the transpiler.Diagnostics listing the problems.
+func New(graph *axon.Graph, opts Options) (*Interpreter, error) {
+	flows, globals, diags := transpiler.FindFlows(graph)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Registry == nil {
+		opts.Registry = Stdlib(opts.Stdout, opts.Args)
+		if opts.AllowFileWrites {
+			maps.Copy(opts.Registry, FileWrites())
+		}
+	}
+
+	in := &Interpreter{
+		graph:      graph,
+		registry:   opts.Registry,
+		aliases:    make(map[string]string),
+		nodes:      make(map[string]*axon.Node),
+		execEdges:  make(map[string]*axon.ExecEdge),
+		dataEdges:  make(map[string]*axon.DataEdge),
+		funcs:      make(map[string]*transpiler.Flow),
+		methods:    make(map[string]*transpiler.Flow),
+		scheduled:  make(map[string]bool),
+		globals:    make(map[string]reflect.Value),
+		globalVars: make(map[string]reflect.Value),
+		types:      make(map[string]reflect.Type),
+		evaluating: make(map[string]bool),
+		hook:       opts.Hook,
+	}
+	for _, entry := range graph.Imports {
+		if spec := transpiler.ParseImport(entry); spec.Alias != "" {
+			in.aliases[spec.Alias] = transpiler.ImportName(spec.Path)
+		}
+	}
+	for _, node := range graph.Nodes {
+		in.nodes[node.Id] = node
+	}
+	for _, edge := range graph.ExecEdges {
+		key := edge.FromNodeId + "\x00" + edge.SourcePin()
+		if _, ok := in.execEdges[key]; !ok {
+			in.execEdges[key] = edge
+		}
+	}
+	for _, edge := range graph.DataEdges {
+		in.dataEdges[edge.ToNodeId+"."+edge.ToPort] = edge
+	}
+	if starts := flows[axon.NodeType_START]; len(starts) > 0 {
+		in.main = starts[0]
+	}
+	for _, f := range flows[axon.NodeType_FUNC_DEF] {
+		if axon.FindPort(f.Entry.Inputs, "receiver") != nil {
+			in.methods[f.Entry.Label] = f
+		} else {
+			in.funcs[f.Entry.Label] = f
+		}
+	}
+	for _, flows := range flows {
+		for _, f := range flows {
+			for _, node := range f.Nodes {
+				in.scheduled[node.Id] = true
+			}
+		}
+	}
+	for _, node := range globals {
+		in.scheduled[node.Id] = true
+	}
+
+	// Constants first, as variables may be initialised from them.
+	global := &frame{values: in.globals, vars: in.globalVars}
+	for _, node := range globals {
+		if node.Type == axon.NodeType_CONSTANT {
+			if err := in.runConstant(global, node); err != nil {
+				return nil, nodeError(node, err)
+			}
+		}
+	}
+	for _, node := range globals {
+		if node.Type == axon.NodeType_VAR_DECLARE {
+			if err := in.runVarDeclare(context.Background(), global, node); err != nil {
+				return nil, nodeError(node, err)
+			}
+		}
+	}
+	return in, nil
+}
+
+// Run runs the graph's main flow, from its START node, until it ends or ctx is cancelled.
+func (in *Interpreter) Run(ctx context.Context) error {
+	if in.main == nil {
+		return fmt.Errorf("the graph has no START node to run")
+	}
+	f := &frame{flow: in.main, values: make(map[string]reflect.Value), vars: make(map[string]reflect.Value)}
+	in.stack = append(in.stack, f)
+	defer func() { in.stack = in.stack[:len(in.stack)-1] }()
+	if err := in.pause(f, in.main.Entry); err != nil {
+		return err
+	}
+	_, err := in.runBlock(ctx, f, in.nextEdge(in.main.Entry.Id, axon.DefaultExecOutput), nil)
+	return err
+}
+
+// Call runs the function a FUNC_DEF node with the given label defines, and returns its results.
+func (in *Interpreter) Call(ctx context.Context, name string, args ...any) ([]any, error) {
+	f, ok := in.funcs[name]
+	if !ok {
+		return nil, fmt.Errorf("the graph defines no function '%s'", name)
+	}
+	values := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		values[i] = reflect.ValueOf(arg)
+	}
+	results, err := in.callFlow(ctx, f, reflect.Value{}, values)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]any, len(results))
+	for i, result := range results {
+		out[i] = interfaceOf(result)
+	}
+	return out, nil
+}
+
+// callFlow runs a function flow with the given receiver, if it is a method, and arguments.
+func (in *Interpreter) callFlow(ctx context.Context, f *transpiler.Flow, receiver reflect.Value, args []reflect.Value) ([]reflect.Value, error) {
+	entry := f.Entry
+	if len(args) != len(entry.Outputs) {
+		return nil, fmt.Errorf("function '%s' takes %d arguments, but got %d", entry.Label, len(entry.Outputs), len(args))
+	}
+	if len(in.stack) > maxDepth {
+		return nil, fmt.Errorf("function '%s' is nested more than %d calls deep", entry.Label, maxDepth)
+	}
+	fr := &frame{flow: f, values: make(map[string]reflect.Value), vars: make(map[string]reflect.Value)}
+	in.stack = append(in.stack, fr)
+	defer func() { in.stack = in.stack[:len(in.stack)-1] }()
+
+	if receiver.IsValid() {
+		fr.values[entry.Id+".receiver"] = receiver
+	}
+	for i, port := range entry.Outputs {
+		arg, err := in.coerce(args[i], port.TypeName)
+		if err != nil {
+			return nil, fmt.Errorf("argument '%s' of function '%s': %w", port.Name, entry.Label, err)
+		}
+		fr.values[entry.Id+"."+port.Name] = arg
+	}
+	if err := in.pause(fr, entry); err != nil {
+		return nil, err
+	}
+
+	out, err := in.runBlock(ctx, fr, in.nextEdge(entry.Id, axon.DefaultExecOutput), nil)
+	if err != nil {
+		return nil, err
+	}
+	if out.returned {
+		return out.results, nil
+	}
+	// A flow without a RETURN on this path, as in a function with no results.
+	var results []reflect.Value
+	for _, port := range returnPorts(f) {
+		zero, err := in.zero(port.TypeName)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, zero)
+	}
+	return results, nil
+}
+
+// runBlock follows execution edges from edge until the path ends, a RETURN ends the function,
+// or an edge leads back into one of the enclosing loops.
+func (in *Interpreter) runBlock(ctx context.Context, f *frame, edge *axon.ExecEdge, loops []string) (outcome, error) {
+	for edge != nil {
+		if slices.Contains(loops, edge.ToNodeId) {
+			return outcome{jump: edge}, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return outcome{}, err
+		}
+		node := in.nodes[edge.ToNodeId]
+		if err := in.pause(f, node); err != nil {
+			return outcome{}, err
+		}
+		next, out, err := in.step(ctx, f, node, loops)
+		if err != nil {
+			return outcome{}, nodeError(node, err)
+		}
+		if out.returned || out.jump != nil {
+			return out, nil
+		}
+		edge = next
+	}
+	return outcome{}, nil
+}
+
+// pause calls the hook, if any, before a node of a flow runs.
+func (in *Interpreter) pause(f *frame, node *axon.Node) error {
+	if in.hook == nil {
+		return nil
+	}
+	return in.hook(&Step{Node: node, Depth: len(in.stack) - 1, in: in, frame: f})
+}
+
+// step runs one node of a flow and returns the execution edge to follow next.
+func (in *Interpreter) step(ctx context.Context, f *frame, node *axon.Node, loops []string) (*axon.ExecEdge, outcome, error) {
+	// New validates the whole graph, but the ports read below are checked again so that a node
+	// without them is an error rather than a panic.
+	if err := node.CheckPorts(); err != nil {
+		return nil, outcome{}, err
+	}
+	next := in.nextEdge(node.Id, axon.DefaultExecOutput)
+	switch node.Type {
+	case axon.NodeType_START, axon.NodeType_FUNC_DEF, axon.NodeType_IGNORE:
+	case axon.NodeType_END:
+		return nil, outcome{}, nil
+	case axon.NodeType_RETURN:
+		results, err := in.runReturn(ctx, f, node)
+		return nil, outcome{returned: true, results: results}, err
+	case axon.NodeType_CONSTANT:
+		return next, outcome{}, in.runConstant(f, node)
+	case axon.NodeType_OPERATOR:
+		result, err := in.evalOperator(ctx, f, node)
+		if err != nil {
+			return nil, outcome{}, err
+		}
+		f.values[node.Id+"."+node.Outputs[0].Name] = result
+	case axon.NodeType_FUNCTION:
+		results, err := in.callNode(ctx, f, node)
+		if err != nil {
+			return nil, outcome{}, err
+		}
+		for i, port := range node.Outputs {
+			f.values[node.Id+"."+port.Name] = results[i]
+		}
+	case axon.NodeType_VAR_DECLARE:
+		return next, outcome{}, in.runVarDeclare(ctx, f, node)
+	case axon.NodeType_VAR_SET:
+		return next, outcome{}, in.runVarSet(ctx, f, node)
+	case axon.NodeType_BRANCH:
+		cond, err := in.input(ctx, f, node, node.Inputs[0])
+		if err != nil {
+			return nil, outcome{}, err
+		}
+		if cond.Kind() != reflect.Bool {
+			return nil, outcome{}, fmt.Errorf("branch condition is %s, not bool", cond.Type())
+		}
+		if cond.Bool() {
+			return in.nextEdge(node.Id, "true"), outcome{}, nil
+		}
+		return in.nextEdge(node.Id, "false"), outcome{}, nil
+	case axon.NodeType_SWITCH:
+		pin, err := in.runSwitch(ctx, f, node)
+		return in.nextEdge(node.Id, pin), outcome{}, err
+	case axon.NodeType_ERROR_CHECK:
+		return in.runErrorCheck(ctx, f, node)
+	case axon.NodeType_LOOP:
+		out, err := in.runLoop(ctx, f, node, loops)
+		if err != nil || out.returned || out.jump != nil {
+			return nil, out, err
+		}
+		return in.nextEdge(node.Id, "completed"), outcome{}, nil
+	default:
+		return nil, outcome{}, fmt.Errorf("%s nodes cannot be interpreted; build the graph with 'axon build' instead", node.Type)
+	}
+	return next, outcome{}, nil
+}
+
+// nextEdge returns the execution edge leaving a node's pin, or nil if the pin is not connected.
+func (in *Interpreter) nextEdge(nodeID, pin string) *axon.ExecEdge {
+	return in.execEdges[nodeID+"\x00"+pin]
+}
+
+// input returns the value flowing into one of a node's inputs, converted to the input's type.
+// Values of nodes that run in a flow are the ones they last produced; any other node, like a pure
+// OPERATOR off the flow, is computed again from its own inputs.
+func (in *Interpreter) input(ctx context.Context, f *frame, node *axon.Node, port *axon.Port) (reflect.Value, error) {
+	edge, ok := in.dataEdges[node.Id+"."+port.Name]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("input '%s' is not connected", port.Name)
+	}
+	source, ok := in.nodes[edge.FromNodeId]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("input '%s' is connected to unknown node '%s'", port.Name, edge.FromNodeId)
+	}
+
+	key := edge.FromNodeId + "." + edge.FromPort
+	value, ok := f.values[key]
+	if !ok {
+		value, ok = in.globals[key]
+	}
+	switch {
+	case ok:
+	case source.Type == axon.NodeType_VAR_GET:
+		variable, err := in.variable(f, source)
+		if err != nil {
+			return reflect.Value{}, nodeError(source, err)
+		}
+		value = variable
+	case !in.scheduled[source.Id] && (source.Type == axon.NodeType_OPERATOR || source.Type == axon.NodeType_FUNCTION):
+		if in.evaluating[source.Id] {
+			return reflect.Value{}, nodeError(source, fmt.Errorf("node depends on its own output"))
+		}
+		in.evaluating[source.Id] = true
+		var err error
+		value, err = in.evalPure(ctx, f, source, edge.FromPort)
+		delete(in.evaluating, source.Id)
+		if err != nil {
+			return reflect.Value{}, nodeError(source, err)
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("input '%s' reads output '%s' of node '%s', which has not run yet", port.Name, edge.FromPort, source.Label)
+	}
+	converted, err := in.coerce(value, port.TypeName)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("input '%s': %w", port.Name, err)
+	}
+	return converted, nil
+}
+
+// evalPure computes one output of a node that is off the execution flow.
+func (in *Interpreter) evalPure(ctx context.Context, f *frame, node *axon.Node, port string) (reflect.Value, error) {
+	if node.Type == axon.NodeType_OPERATOR {
+		return in.evalOperator(ctx, f, node)
+	}
+	results, err := in.callNode(ctx, f, node)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	for i, output := range node.Outputs {
+		if output.Name == port {
+			return results[i], nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("node has no output '%s'", port)
+}
+
+func (in *Interpreter) runConstant(f *frame, node *axon.Node) error {
+	if len(node.Outputs) == 0 {
+		return fmt.Errorf("constant has no output")
+	}
+	value, ok := node.Config["value"]
+	if !ok {
+		return fmt.Errorf("constant has no 'value' in config")
+	}
+	result, err := in.evalExpr(value, node.Outputs[0].TypeName)
+	if err != nil {
+		return err
+	}
+	f.values[node.Id+"."+node.Outputs[0].Name] = result
+	return nil
+}
+
+func (in *Interpreter) runReturn(ctx context.Context, f *frame, node *axon.Node) ([]reflect.Value, error) {
+	var results []reflect.Value
+	for _, port := range node.Inputs {
+		var result reflect.Value
+		var err error
+		if _, ok := in.dataEdges[node.Id+"."+port.Name]; ok {
+			result, err = in.input(ctx, f, node, port)
+		} else {
+			// Results that are not wired up are returned as their zero value.
+			result, err = in.zero(port.TypeName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// callNode calls the function or method a FUNCTION node's impl_reference names, and returns one
+// value per output of the node.
+func (in *Interpreter) callNode(ctx context.Context, f *frame, node *axon.Node) ([]reflect.Value, error) {
+	if node.ImplReference == "" {
+		return nil, fmt.Errorf("function call is missing 'impl_reference'")
+	}
+	var receiver reflect.Value
+	var args []reflect.Value
+	for _, port := range node.Inputs {
+		arg, err := in.input(ctx, f, node, port)
+		if err != nil {
+			return nil, err
+		}
+		if port.Name == "receiver" {
+			receiver = arg
+			continue
+		}
+		args = append(args, arg)
+	}
+
+	var results []reflect.Value
+	var err error
+	switch {
+	case receiver.IsValid() && in.methods[node.ImplReference] != nil:
+		results, err = in.callFlow(ctx, in.methods[node.ImplReference], receiver, args)
+	case receiver.IsValid():
+		method := methodByName(receiver, node.ImplReference)
+		if !method.IsValid() {
+			return nil, fmt.Errorf("%s has no method %s", receiver.Type(), node.ImplReference)
+		}
+		results, err = callGo(method, args)
+	case in.funcs[node.ImplReference] != nil:
+		results, err = in.callFlow(ctx, in.funcs[node.ImplReference], reflect.Value{}, args)
+	default:
+		fn, ok := in.lookup(node.ImplReference)
+		if !ok {
+			return nil, fmt.Errorf("%s is not available to the interpreter", node.ImplReference)
+		}
+		if reflect.TypeOf(fn).Kind() != reflect.Func {
+			return nil, fmt.Errorf("%s is not a function", node.ImplReference)
+		}
+		results, err = callGo(reflect.ValueOf(fn), args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(node.Outputs) == 0 {
+		return nil, nil // The results, if any, are discarded, like those of a call statement.
+	}
+	if len(results) != len(node.Outputs) {
+		return nil, fmt.Errorf("%s returns %d results, but the node has %d outputs", node.ImplReference, len(results), len(node.Outputs))
+	}
+	for i, port := range node.Outputs {
+		if results[i], err = in.coerce(results[i], port.TypeName); err != nil {
+			return nil, fmt.Errorf("output '%s': %w", port.Name, err)
+		}
+	}
+	return results, nil
+}
+
+// lookup resolves a name in the registry, as "str.ToUpper" for a graph that imports strings as str.
+func (in *Interpreter) lookup(name string) (any, bool) {
+	if pkg, symbol, ok := strings.Cut(name, "."); ok {
+		if actual, ok := in.aliases[pkg]; ok {
+			name = actual + "." + symbol
+		}
+	}
+	v, ok := in.registry[name]
+	return v, ok
+}
+
+// methodByName finds a method of a value, including those declared on a pointer to its type.
+func methodByName(v reflect.Value, name string) reflect.Value {
+	if method := v.MethodByName(name); method.IsValid() {
+		return method
+	}
+	if v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		return ptr.MethodByName(name)
+	}
+	return reflect.Value{}
+}
+
+// callGo calls a Go function, converting each argument to its parameter's type. A panic in the
+// function is returned as an error.
+func callGo(fn reflect.Value, args []reflect.Value) (results []reflect.Value, err error) {
+	t := fn.Type()
+	if t.IsVariadic() && len(args) < t.NumIn()-1 || !t.IsVariadic() && len(args) != t.NumIn() {
+		return nil, fmt.Errorf("function takes %d arguments, but got %d", t.NumIn(), len(args))
+	}
+	for i, arg := range args {
+		param := t.In(min(i, t.NumIn()-1))
+		if t.IsVariadic() && i >= t.NumIn()-1 {
+			param = param.Elem()
+		}
+		if args[i], err = convert(arg, param); err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn.Call(args), nil
+}
+
+// returnPorts returns the results of a function flow, as declared by its RETURN node.
+func returnPorts(f *transpiler.Flow) []*axon.Port {
+	for _, node := range f.Nodes {
+		if node.Type == axon.NodeType_RETURN {
+			return node.Inputs
+		}
+	}
+	return nil
+}
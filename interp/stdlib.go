@@ -0,0 +1,202 @@
+package interp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Registry maps the qualified names a graph refers to, like "strings.ToUpper" or "math.Pi", to
+// what they denote: a function, a value, or, for a type like "time.Duration", its reflect.Type.
+// Names are qualified by the package's default import name; the interpreter resolves aliases the
+// graph imports packages under. Builtins like "len" are unqualified.
+type Registry map[string]any
+
+// Stdlib returns a registry of commonly used standard library functions, values and types. The fmt
+// printing functions write to stdout, and os.Args holds args.
+//
+// A graph run with it can print, read files and environment variables, and compute, but it cannot
+// write files, run programs or use the network, so graphs from elsewhere can be tried with it. Add
+// FileWrites to let a graph write files.
+func Stdlib(stdout io.Writer, args []string) Registry {
+	return Registry{
+		// Builtins.
+		"len": func(v any) int { return reflect.ValueOf(v).Len() },
+		"cap": func(v any) int { return reflect.ValueOf(v).Cap() },
+
+		// fmt
+		"fmt.Print":    func(a ...any) (int, error) { return fmt.Fprint(stdout, a...) },
+		"fmt.Println":  func(a ...any) (int, error) { return fmt.Fprintln(stdout, a...) },
+		"fmt.Printf":   func(format string, a ...any) (int, error) { return fmt.Fprintf(stdout, format, a...) },
+		"fmt.Sprint":   fmt.Sprint,
+		"fmt.Sprintln": fmt.Sprintln,
+		"fmt.Sprintf":  fmt.Sprintf,
+		"fmt.Errorf":   fmt.Errorf,
+		"fmt.Fprint":   fmt.Fprint,
+		"fmt.Fprintln": fmt.Fprintln,
+		"fmt.Fprintf":  fmt.Fprintf,
+		"fmt.Sscan":    fmt.Sscan,
+
+		// errors
+		"errors.New":    errors.New,
+		"errors.Is":     errors.Is,
+		"errors.Unwrap": errors.Unwrap,
+		"errors.Join":   errors.Join,
+
+		// strings
+		"strings.Contains":    strings.Contains,
+		"strings.ContainsAny": strings.ContainsAny,
+		"strings.Count":       strings.Count,
+		"strings.EqualFold":   strings.EqualFold,
+		"strings.Fields":      strings.Fields,
+		"strings.HasPrefix":   strings.HasPrefix,
+		"strings.HasSuffix":   strings.HasSuffix,
+		"strings.Index":       strings.Index,
+		"strings.Join":        strings.Join,
+		"strings.LastIndex":   strings.LastIndex,
+		"strings.Repeat":      strings.Repeat,
+		"strings.Replace":     strings.Replace,
+		"strings.ReplaceAll":  strings.ReplaceAll,
+		"strings.Split":       strings.Split,
+		"strings.SplitN":      strings.SplitN,
+		"strings.ToLower":     strings.ToLower,
+		"strings.ToUpper":     strings.ToUpper,
+		"strings.Trim":        strings.Trim,
+		"strings.TrimLeft":    strings.TrimLeft,
+		"strings.TrimPrefix":  strings.TrimPrefix,
+		"strings.TrimRight":   strings.TrimRight,
+		"strings.TrimSpace":   strings.TrimSpace,
+		"strings.TrimSuffix":  strings.TrimSuffix,
+		"strings.NewReader":   strings.NewReader,
+		"strings.Builder":     reflect.TypeFor[strings.Builder](),
+		"strings.Reader":      reflect.TypeFor[strings.Reader](),
+
+		// bytes
+		"bytes.Contains":    bytes.Contains,
+		"bytes.Equal":       bytes.Equal,
+		"bytes.NewBuffer":   bytes.NewBuffer,
+		"bytes.NewReader":   bytes.NewReader,
+		"bytes.ToLower":     bytes.ToLower,
+		"bytes.ToUpper":     bytes.ToUpper,
+		"bytes.TrimSpace":   bytes.TrimSpace,
+		"bytes.Buffer":      reflect.TypeFor[bytes.Buffer](),
+		"bytes.MinRead":     bytes.MinRead,
+		"bytes.ErrTooLarge": bytes.ErrTooLarge,
+
+		// strconv
+		"strconv.Atoi":        strconv.Atoi,
+		"strconv.Itoa":        strconv.Itoa,
+		"strconv.FormatBool":  strconv.FormatBool,
+		"strconv.FormatFloat": strconv.FormatFloat,
+		"strconv.FormatInt":   strconv.FormatInt,
+		"strconv.ParseBool":   strconv.ParseBool,
+		"strconv.ParseFloat":  strconv.ParseFloat,
+		"strconv.ParseInt":    strconv.ParseInt,
+		"strconv.Quote":       strconv.Quote,
+		"strconv.Unquote":     strconv.Unquote,
+
+		// math
+		"math.Abs":        math.Abs,
+		"math.Ceil":       math.Ceil,
+		"math.Cos":        math.Cos,
+		"math.Exp":        math.Exp,
+		"math.Floor":      math.Floor,
+		"math.Hypot":      math.Hypot,
+		"math.Inf":        math.Inf,
+		"math.IsInf":      math.IsInf,
+		"math.IsNaN":      math.IsNaN,
+		"math.Log":        math.Log,
+		"math.Log10":      math.Log10,
+		"math.Log2":       math.Log2,
+		"math.Max":        math.Max,
+		"math.Min":        math.Min,
+		"math.Mod":        math.Mod,
+		"math.NaN":        math.NaN,
+		"math.Pow":        math.Pow,
+		"math.Round":      math.Round,
+		"math.Sin":        math.Sin,
+		"math.Sqrt":       math.Sqrt,
+		"math.Tan":        math.Tan,
+		"math.Trunc":      math.Trunc,
+		"math.E":          math.E,
+		"math.Pi":         math.Pi,
+		"math.Sqrt2":      math.Sqrt2,
+		"math.MaxInt":     math.MaxInt,
+		"math.MinInt":     math.MinInt,
+		"math.MaxInt32":   math.MaxInt32,
+		"math.MinInt32":   math.MinInt32,
+		"math.MaxInt64":   math.MaxInt64,
+		"math.MinInt64":   math.MinInt64,
+		"math.MaxFloat64": math.MaxFloat64,
+		"rand.Intn":       rand.Intn,
+		"rand.Float64":    rand.Float64,
+		"rand.Perm":       rand.Perm,
+		"rand.Shuffle":    rand.Shuffle,
+
+		// unicode and unicode/utf8
+		"unicode.IsDigit":        unicode.IsDigit,
+		"unicode.IsLetter":       unicode.IsLetter,
+		"unicode.IsLower":        unicode.IsLower,
+		"unicode.IsSpace":        unicode.IsSpace,
+		"unicode.IsUpper":        unicode.IsUpper,
+		"unicode.ToLower":        unicode.ToLower,
+		"unicode.ToUpper":        unicode.ToUpper,
+		"utf8.RuneCountInString": utf8.RuneCountInString,
+		"utf8.RuneLen":           utf8.RuneLen,
+		"utf8.ValidString":       utf8.ValidString,
+
+		// sort
+		"sort.Float64s": sort.Float64s,
+		"sort.Ints":     sort.Ints,
+		"sort.Strings":  sort.Strings,
+
+		// time
+		"time.Now":           time.Now,
+		"time.Since":         time.Since,
+		"time.Sleep":         time.Sleep,
+		"time.ParseDuration": time.ParseDuration,
+		"time.Nanosecond":    time.Nanosecond,
+		"time.Microsecond":   time.Microsecond,
+		"time.Millisecond":   time.Millisecond,
+		"time.Second":        time.Second,
+		"time.Minute":        time.Minute,
+		"time.Hour":          time.Hour,
+		"time.Duration":      reflect.TypeFor[time.Duration](),
+		"time.Time":          reflect.TypeFor[time.Time](),
+
+		// io and os
+		"io.ReadAll":     io.ReadAll,
+		"io.EOF":         io.EOF,
+		"io.Reader":      reflect.TypeFor[io.Reader](),
+		"io.Writer":      reflect.TypeFor[io.Writer](),
+		"os.Args":        args,
+		"os.Getenv":      os.Getenv,
+		"os.LookupEnv":   os.LookupEnv,
+		"os.Open":        os.Open,
+		"os.ReadFile":    os.ReadFile,
+		"os.ErrNotExist": os.ErrNotExist,
+		"os.File":        reflect.TypeFor[os.File](),
+		"os.FileMode":    reflect.TypeFor[os.FileMode](),
+		"os.Stdout":      stdout,
+	}
+}
+
+// FileWrites returns the functions that let a graph write files, which Stdlib leaves out.
+func FileWrites() Registry {
+	return Registry{
+		"os.WriteFile": os.WriteFile,
+		"os.Create":    os.Create,
+		"os.MkdirAll":  os.MkdirAll,
+	}
+}
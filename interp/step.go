@@ -0,0 +1,88 @@
+package interp
+
+import (
+	"reflect"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// Step describes the node about to run, for an Options.Hook. It is only valid until the hook
+// returns.
+type Step struct {
+	Node *axon.Node
+	// Depth is the number of calls to the graph's own functions the node runs inside: 0 in the
+	// main flow, 1 in a function it calls, and so on.
+	Depth int
+	in    *Interpreter
+	frame *frame
+}
+
+// EdgeValue is the value flowing along a data edge.
+type EdgeValue struct {
+	Edge  *axon.DataEdge
+	Value any
+}
+
+// Flow returns the START or FUNC_DEF node of the flow the node belongs to.
+func (s *Step) Flow() *axon.Node {
+	return s.frame.flow.Entry
+}
+
+// Stack returns the START or FUNC_DEF nodes of the flows being run, outermost first, so the last
+// is the node's own flow.
+func (s *Step) Stack() []*axon.Node {
+	var entries []*axon.Node
+	for _, f := range s.in.stack {
+		entries = append(entries, f.flow.Entry)
+	}
+	return entries
+}
+
+// Values returns every data edge that carries a value in the running flow, in the graph's order:
+// outputs of the nodes that have run, global constants, and the variables VAR_GET nodes read.
+// Edges from nodes that have not run yet, and from pure nodes computed only when needed, are
+// left out.
+func (s *Step) Values() []EdgeValue {
+	var values []EdgeValue
+	for _, edge := range s.in.graph.DataEdges {
+		if value, ok := s.value(edge); ok {
+			values = append(values, EdgeValue{Edge: edge, Value: value})
+		}
+	}
+	return values
+}
+
+// Inputs returns the values flowing into the node, for those of its inputs that have one.
+func (s *Step) Inputs() []EdgeValue {
+	var values []EdgeValue
+	for _, value := range s.Values() {
+		if value.Edge.ToNodeId == s.Node.Id {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (s *Step) value(edge *axon.DataEdge) (any, bool) {
+	key := edge.FromNodeId + "." + edge.FromPort
+	value, ok := s.frame.values[key]
+	if !ok {
+		value, ok = s.in.globals[key]
+	}
+	if source := s.in.nodes[edge.FromNodeId]; !ok && source != nil && source.Type == axon.NodeType_VAR_GET {
+		variable, err := s.in.variable(s.frame, source)
+		value, ok = variable, err == nil
+	}
+	if !ok {
+		return nil, false
+	}
+	return interfaceOf(value), true
+}
+
+// interfaceOf returns the Go value a reflect.Value holds, or nil for none.
+func interfaceOf(v reflect.Value) any {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
@@ -0,0 +1,103 @@
+package interp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Advik-B/Axon/interp"
+	"github.com/Advik-B/Axon/pkg/axon"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// stepGraph calls a function of its own that prints a greeting, then prints a word in upper case.
+const stepGraph = `{
+  "id": "steps", "name": "steps", "imports": ["fmt", "strings"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "call", "type": "FUNCTION", "label": "call", "impl_reference": "greet"},
+    {"id": "word", "type": "CONSTANT", "label": "word", "outputs": [{"name": "out", "type_name": "string"}], "config": {"value": "\"bye\""}},
+    {"id": "upper", "type": "FUNCTION", "label": "upper", "impl_reference": "strings.ToUpper", "inputs": [{"name": "s", "type_name": "string"}], "outputs": [{"name": "out", "type_name": "string"}]},
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "string"}]},
+    {"id": "end", "type": "END", "label": "End"},
+    {"id": "greet", "type": "FUNC_DEF", "label": "greet"},
+    {"id": "hello", "type": "FUNCTION", "label": "hello", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "string"}]},
+    {"id": "greeting", "type": "CONSTANT", "label": "greeting", "outputs": [{"name": "out", "type_name": "string"}], "config": {"value": "\"hello\""}},
+    {"id": "ret", "type": "RETURN", "label": "Return"}
+  ],
+  "data_edges": [
+    {"from_node_id": "word", "from_port": "out", "to_node_id": "upper", "to_port": "s"},
+    {"from_node_id": "upper", "from_port": "out", "to_node_id": "print", "to_port": "a"},
+    {"from_node_id": "greeting", "from_port": "out", "to_node_id": "hello", "to_port": "a"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "call"},
+    {"from_node_id": "call", "to_node_id": "upper"},
+    {"from_node_id": "upper", "to_node_id": "print"},
+    {"from_node_id": "print", "to_node_id": "end"},
+    {"from_node_id": "greet", "to_node_id": "hello"},
+    {"from_node_id": "hello", "to_node_id": "ret"}
+  ]
+}`
+
+func TestHook(t *testing.T) {
+	var graph axon.Graph
+	if err := protojson.Unmarshal([]byte(stepGraph), &graph); err != nil {
+		t.Fatal(err)
+	}
+
+	var steps []string
+	var printed []any
+	hook := func(s *interp.Step) error {
+		steps = append(steps, fmt.Sprintf("%s@%d", s.Node.Id, s.Depth))
+		if s.Node.Id == "hello" && s.Flow().Id != "greet" {
+			t.Errorf("hello runs in the flow of %s", s.Flow().Id)
+		}
+		if s.Node.Id == "print" {
+			for _, v := range s.Inputs() {
+				printed = append(printed, v.Value)
+			}
+		}
+		return nil
+	}
+	var out bytes.Buffer
+	in, err := interp.New(&graph, interp.Options{Stdout: &out, Hook: hook})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start@0", "call@0", "greet@1", "hello@1", "ret@1", "upper@0", "print@0", "end@0"}
+	if !slices.Equal(steps, want) {
+		t.Errorf("got steps %v, want %v", steps, want)
+	}
+	if !slices.Equal(printed, []any{"BYE"}) {
+		t.Errorf("print received %v, want [BYE]", printed)
+	}
+	if out.String() != "hello\nBYE\n" {
+		t.Errorf("graph printed %q", out.String())
+	}
+
+	// An error from the hook stops the run before the node.
+	stop := errors.New("stop")
+	out.Reset()
+	in, err = interp.New(&graph, interp.Options{Stdout: &out, Hook: func(s *interp.Step) error {
+		if s.Node.Id == "print" {
+			return stop
+		}
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Run(context.Background()); !errors.Is(err, stop) {
+		t.Errorf("run stopped by its hook returned %v", err)
+	}
+	if out.String() != "hello\n" {
+		t.Errorf("graph stopped before print printed %q", out.String())
+	}
+}
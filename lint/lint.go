@@ -0,0 +1,124 @@
+// Package lint finds style problems and likely mistakes in Axon graphs that still transpile.
+// Each check is a Rule with a stable ID; its findings are warnings, reported as diagnostics
+// with the rule ID as their code.
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+	"github.com/Advik-B/Axon/transpiler"
+)
+
+// IgnoreConfigKey is the node config entry that suppresses lint rules. Its value is a comma-separated
+// list of rule IDs, or "all". On a node it suppresses that node's findings; on the START node it
+// suppresses the rules for the whole graph.
+const IgnoreConfigKey = "lint:ignore"
+
+// Rule is a single lint check.
+type Rule struct {
+	ID          string
+	Description string
+	Check       func(pass *Pass)
+}
+
+var rules []*Rule
+
+// Register adds a rule to the set Run applies. Rule IDs must be unique.
+func Register(rule *Rule) {
+	if Lookup(rule.ID) != nil {
+		panic(fmt.Sprintf("lint: rule %s registered twice", rule.ID))
+	}
+	rules = append(rules, rule)
+}
+
+// Rules returns every registered rule, sorted by ID.
+func Rules() []*Rule {
+	sorted := slices.Clone(rules)
+	slices.SortFunc(sorted, func(a, b *Rule) int { return strings.Compare(a.ID, b.ID) })
+	return sorted
+}
+
+// Lookup returns the rule with the given ID, or nil if there is none.
+func Lookup(id string) *Rule {
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Options controls which rules Run applies. The zero value applies them all.
+type Options struct {
+	// Disabled lists the IDs of rules to skip.
+	Disabled []string
+}
+
+// Pass is what a rule sees while it checks one graph.
+type Pass struct {
+	Graph   *axon.Graph
+	NodeMap map[string]*axon.Node
+
+	rule  *Rule
+	diags transpiler.Diagnostics
+}
+
+// Reportf records a finding of the current rule. node may be nil for findings that concern the
+// graph as a whole, and port is "" unless the finding is about one of the node's ports.
+func (p *Pass) Reportf(node *axon.Node, port string, format string, args ...any) {
+	d := &transpiler.Diagnostic{
+		Severity: transpiler.SeverityWarning,
+		Code:     p.rule.ID,
+		Port:     port,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if node != nil {
+		d.NodeID = node.Id
+	}
+	p.diags = append(p.diags, d)
+}
+
+// Run applies the enabled rules to a graph and returns their findings, minus the suppressed ones.
+func Run(graph *axon.Graph, opts Options) transpiler.Diagnostics {
+	pass := &Pass{Graph: graph, NodeMap: make(map[string]*axon.Node)}
+	graphIgnores := map[string]bool{}
+	for _, node := range graph.Nodes {
+		pass.NodeMap[node.Id] = node
+		if node.Type == axon.NodeType_START {
+			graphIgnores = ignoredRules(node)
+		}
+	}
+
+	for _, rule := range Rules() {
+		if slices.Contains(opts.Disabled, rule.ID) || graphIgnores[rule.ID] || graphIgnores["all"] {
+			continue
+		}
+		pass.rule = rule
+		rule.Check(pass)
+	}
+
+	var diags transpiler.Diagnostics
+	for _, d := range pass.diags {
+		if node, ok := pass.NodeMap[d.NodeID]; ok {
+			if ignores := ignoredRules(node); ignores[d.Code] || ignores["all"] {
+				continue
+			}
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// ignoredRules returns the rule IDs a node's config suppresses.
+func ignoredRules(node *axon.Node) map[string]bool {
+	ignores := make(map[string]bool)
+	for _, id := range strings.Split(node.Config[IgnoreConfigKey], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ignores[id] = true
+		}
+	}
+	return ignores
+}
@@ -0,0 +1,312 @@
+package lint
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+	"github.com/Advik-B/Axon/transpiler"
+)
+
+// maxExecChain is the longest run of straight-line nodes the long-exec-chain rule accepts.
+const maxExecChain = 25
+
+func init() {
+	Register(&Rule{
+		ID:          "orphan-comment",
+		Description: "Comments that no node is attached to, and nodes that refer to comments that do not exist.",
+		Check:       checkOrphanComments,
+	})
+	Register(&Rule{
+		ID:          "unused-import",
+		Description: "Imports that no node refers to.",
+		Check:       checkUnusedImports,
+	})
+	Register(&Rule{
+		ID:          "invalid-identifier",
+		Description: "Labels that become Go names but are not valid Go identifiers, so the generated code derives another name.",
+		Check:       checkIdentifiers,
+	})
+	Register(&Rule{
+		ID:          "duplicate-label",
+		Description: "Labels that become Go names and are used by more than one node, so the generated code adds node IDs to tell them apart.",
+		Check:       checkDuplicateLabels,
+	})
+	Register(&Rule{
+		ID:          "deprecated-api",
+		Description: "FUNCTION nodes that call standard library functions marked as deprecated.",
+		Check:       checkDeprecated,
+	})
+	Register(&Rule{
+		ID:          "long-exec-chain",
+		Description: "Runs of more than 25 straight-line nodes, which are easier to follow split into functions.",
+		Check:       checkExecChains,
+	})
+	Register(&Rule{
+		ID:          "unused-struct",
+		Description: "STRUCT_DEF nodes whose type no port, operator or config refers to.",
+		Check:       checkUnusedStructs,
+	})
+}
+
+func checkOrphanComments(pass *Pass) {
+	comments := make(map[string]bool)
+	for _, comment := range pass.Graph.Comments {
+		comments[comment.Id] = true
+	}
+	attached := make(map[string]bool)
+	for _, node := range pass.Graph.Nodes {
+		for _, id := range node.CommentIds {
+			attached[id] = true
+			if !comments[id] {
+				pass.Reportf(node, "", "node '%s' refers to comment '%s', which does not exist", node.Label, id)
+			}
+		}
+	}
+	for _, comment := range pass.Graph.Comments {
+		if !attached[comment.Id] {
+			pass.Reportf(nil, "", "comment '%s' is not attached to any node", comment.Id)
+		}
+	}
+}
+
+func checkUnusedImports(pass *Pass) {
+	// Imports the generated code needs without any node naming them.
+	implied := make(map[string]bool)
+	var text []string
+	for _, node := range pass.Graph.Nodes {
+		switch node.Type {
+		case axon.NodeType_WAIT:
+			implied["sync"], implied["errgroup"] = true, true
+		case axon.NodeType_ERROR_CHECK:
+			if node.Config["wrap"] != "" {
+				implied["fmt"] = true
+			}
+		}
+		text = append(text, nodeText(node)...)
+	}
+	all := strings.Join(text, "\n")
+	for _, imp := range pass.Graph.Imports {
+		spec := transpiler.ParseImport(imp)
+		name := spec.Name()
+		if name == "_" || name == "." || implied[name] || regexp.MustCompile(`\b`+regexp.QuoteMeta(name)+`\.`).MatchString(all) {
+			continue
+		}
+		pass.Reportf(nil, "", "package \"%s\" is imported but no node uses it", spec.Path)
+	}
+}
+
+// nodeText returns the strings of a node that may name Go packages or types.
+func nodeText(node *axon.Node) []string {
+	text := []string{node.ImplReference}
+	for _, port := range node.Inputs {
+		text = append(text, port.TypeName)
+	}
+	for _, port := range node.Outputs {
+		text = append(text, port.TypeName)
+	}
+	for _, value := range node.Config {
+		text = append(text, value)
+	}
+	return text
+}
+
+// namesIdentifier reports whether a node's label becomes the name of something in the generated code.
+func namesIdentifier(node *axon.Node) bool {
+	switch node.Type {
+	case axon.NodeType_CONSTANT, axon.NodeType_VAR_DECLARE, axon.NodeType_OPERATOR, axon.NodeType_STRUCT_DEF,
+		axon.NodeType_FUNC_DEF, axon.NodeType_CHAN_MAKE, axon.NodeType_CHAN_RECEIVE:
+		return true
+	case axon.NodeType_FUNCTION:
+		return len(node.Outputs) > 0
+	}
+	return false
+}
+
+// identifierOf returns the name a node's label gives it in the generated code.
+func identifierOf(node *axon.Node) string {
+	if node.Type == axon.NodeType_VAR_DECLARE && node.Config["name"] != "" {
+		return node.Config["name"]
+	}
+	return node.Label
+}
+
+func checkIdentifiers(pass *Pass) {
+	for _, node := range pass.Graph.Nodes {
+		if name := identifierOf(node); namesIdentifier(node) && !token.IsIdentifier(name) {
+			pass.Reportf(node, "", "%s node '%s' is named in the generated code, but '%s' is not a valid Go identifier", node.Type, node.Label, name)
+		}
+	}
+}
+
+func checkDuplicateLabels(pass *Pass) {
+	// Values are named within the function that declares them, so labels only clash within one.
+	// Nodes outside any flow, and the functions and types themselves, share the file's namespace.
+	scopeOf := make(map[string]string)
+	flows, _, _ := transpiler.FindFlows(pass.Graph)
+	for _, scopes := range flows {
+		for _, f := range scopes {
+			for _, node := range f.Nodes {
+				scopeOf[node.Id] = f.Entry.Id
+			}
+		}
+	}
+	type scopedName struct{ scope, name string }
+	firstWith := make(map[scopedName]*axon.Node)
+	for _, node := range pass.Graph.Nodes {
+		if !namesIdentifier(node) {
+			continue
+		}
+		key := scopedName{scopeOf[node.Id], identifierOf(node)}
+		if node.Type == axon.NodeType_FUNC_DEF || node.Type == axon.NodeType_STRUCT_DEF {
+			key.scope = ""
+		}
+		if first, ok := firstWith[key]; ok {
+			pass.Reportf(node, "", "node %s has the same name, '%s', as node %s", node.Id, key.name, first.Id)
+			continue
+		}
+		firstWith[key] = node
+	}
+}
+
+func checkDeprecated(pass *Pass) {
+	imports := make(map[string]string)
+	for _, imp := range pass.Graph.Imports {
+		spec := transpiler.ParseImport(imp)
+		imports[spec.Name()] = spec.Path
+	}
+	for _, node := range pass.Graph.Nodes {
+		if node.Type != axon.NodeType_FUNCTION || axon.FindPort(node.Inputs, "receiver") != nil {
+			continue
+		}
+		pkgName, name, ok := strings.Cut(node.ImplReference, ".")
+		if !ok || imports[pkgName] == "" {
+			continue
+		}
+		if note, ok := stdlibDeprecations(imports[pkgName])[name]; ok {
+			pass.Reportf(node, "", "%s is deprecated: %s", node.ImplReference, note)
+		}
+	}
+}
+
+// deprecations is shared between runs, as finding a package's deprecations means parsing its source.
+var deprecations struct {
+	sync.Mutex
+	byPackage map[string]map[string]string
+}
+
+// stdlibDeprecations maps the deprecated functions of a standard library package to their
+// deprecation notes, read from the package's doc comments in GOROOT.
+func stdlibDeprecations(importPath string) map[string]string {
+	deprecations.Lock()
+	defer deprecations.Unlock()
+	if notes, ok := deprecations.byPackage[importPath]; ok {
+		return notes
+	}
+	if deprecations.byPackage == nil {
+		deprecations.byPackage = make(map[string]map[string]string)
+	}
+	notes := make(map[string]string)
+	deprecations.byPackage[importPath] = notes
+
+	pkg, err := build.Default.Import(importPath, "", 0)
+	if err != nil || !pkg.Goroot {
+		return notes
+	}
+	fset := token.NewFileSet()
+	for _, file := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, file), nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Doc == nil || !fn.Name.IsExported() {
+				continue
+			}
+			if note, ok := deprecationNote(fn.Doc.Text()); ok {
+				notes[fn.Name.Name] = note
+			}
+		}
+	}
+	return notes
+}
+
+// deprecationNote extracts the paragraph starting with "Deprecated: " from a doc comment.
+func deprecationNote(doc string) (string, bool) {
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		if note, ok := strings.CutPrefix(paragraph, "Deprecated: "); ok {
+			return strings.Join(strings.Fields(note), " "), true
+		}
+	}
+	return "", false
+}
+
+func checkExecChains(pass *Pass) {
+	outgoing := make(map[string][]*axon.ExecEdge)
+	incoming := make(map[string]int)
+	for _, edge := range pass.Graph.ExecEdges {
+		outgoing[edge.FromNodeId] = append(outgoing[edge.FromNodeId], edge)
+		incoming[edge.ToNodeId]++
+	}
+	// continues reports whether the chain through a node carries on to the next one.
+	continues := func(id string) (string, bool) {
+		node := pass.NodeMap[id]
+		if node == nil || len(outgoing[id]) != 1 || len(node.ExecOutputPins()) > 1 {
+			return "", false
+		}
+		next := outgoing[id][0].ToNodeId
+		return next, incoming[next] == 1
+	}
+
+	// Only start counting where a chain begins, not part way along one.
+	continued := make(map[string]bool)
+	for id := range outgoing {
+		if next, ok := continues(id); ok {
+			continued[next] = true
+		}
+	}
+
+	for _, node := range pass.Graph.Nodes {
+		if continued[node.Id] {
+			continue
+		}
+		length, seen := 1, map[string]bool{node.Id: true}
+		for id := node.Id; ; length++ {
+			next, ok := continues(id)
+			if !ok || seen[next] {
+				break
+			}
+			seen[next] = true
+			id = next
+		}
+		if length > maxExecChain {
+			pass.Reportf(node, "", "the execution chain starting at node '%s' runs %d nodes in a row; consider moving part of it into a FUNC_DEF", node.Label, length)
+		}
+	}
+}
+
+func checkUnusedStructs(pass *Pass) {
+	for _, def := range pass.Graph.Nodes {
+		if def.Type != axon.NodeType_STRUCT_DEF {
+			continue
+		}
+		used := regexp.MustCompile(`\b` + regexp.QuoteMeta(def.Label) + `\b`)
+		isUsed := false
+		for _, node := range pass.Graph.Nodes {
+			if node != def && used.MatchString(strings.Join(nodeText(node), "\n")) {
+				isUsed = true
+				break
+			}
+		}
+		if !isUsed {
+			pass.Reportf(def, "", "struct '%s' is defined but never used", def.Label)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// mainFlow returns a graph whose main flow runs the nodes given, in order, from START to END.
+func mainFlow(nodes ...*axon.Node) *axon.Graph {
+	graph := &axon.Graph{Nodes: []*axon.Node{{Id: "start", Type: axon.NodeType_START, Label: "Start"}}}
+	graph.Nodes = append(graph.Nodes, nodes...)
+	graph.Nodes = append(graph.Nodes, &axon.Node{Id: "end", Type: axon.NodeType_END, Label: "End"})
+	for i := 1; i < len(graph.Nodes); i++ {
+		graph.ExecEdges = append(graph.ExecEdges, &axon.ExecEdge{FromNodeId: graph.Nodes[i-1].Id, ToNodeId: graph.Nodes[i].Id})
+	}
+	return graph
+}
+
+// variable returns a VAR_DECLARE node named label.
+func variable(id, label string) *axon.Node {
+	return &axon.Node{Id: id, Type: axon.NodeType_VAR_DECLARE, Label: label, Config: map[string]string{"type": "int"}}
+}
+
+// call returns a FUNCTION node calling ref with no arguments.
+func call(id, ref string) *axon.Node {
+	return &axon.Node{Id: id, Type: axon.NodeType_FUNCTION, Label: id, ImplReference: ref}
+}
+
+// withFunc adds a FUNC_DEF labelled label to graph, whose body runs the nodes given and returns.
+func withFunc(graph *axon.Graph, label string, nodes ...*axon.Node) *axon.Graph {
+	def := &axon.Node{Id: label, Type: axon.NodeType_FUNC_DEF, Label: label}
+	ret := &axon.Node{Id: label + "_ret", Type: axon.NodeType_RETURN, Label: "Return"}
+	body := append(append([]*axon.Node{def}, nodes...), ret)
+	graph.Nodes = append(graph.Nodes, body...)
+	for i := 1; i < len(body); i++ {
+		graph.ExecEdges = append(graph.ExecEdges, &axon.ExecEdge{FromNodeId: body[i-1].Id, ToNodeId: body[i].Id})
+	}
+	return graph
+}
+
+func TestRules(t *testing.T) {
+	var chain []*axon.Node
+	for i := range maxExecChain + 1 {
+		chain = append(chain, call(fmt.Sprintf("p%d", i), "println"))
+	}
+
+	tests := []struct {
+		name  string
+		graph *axon.Graph
+		// want lists the IDs of the rules that must report, in order of their findings.
+		want []string
+	}{
+		{
+			name:  "clean graph",
+			graph: withFunc(mainFlow(variable("n", "n"), call("greet", "Greet")), "Greet", variable("g", "g")),
+		},
+		{
+			name: "orphan comments",
+			graph: func() *axon.Graph {
+				g := mainFlow(&axon.Node{Id: "v", Type: axon.NodeType_VAR_DECLARE, Label: "v", CommentIds: []string{"gone"}})
+				g.Comments = []*axon.Comment{{Id: "loose", Content: "nobody reads this"}}
+				return g
+			}(),
+			want: []string{"orphan-comment", "orphan-comment"},
+		},
+		{
+			name: "unused import",
+			graph: func() *axon.Graph {
+				g := mainFlow(call("print", "fmt.Println"))
+				g.Imports = []string{"fmt", "strings"}
+				return g
+			}(),
+			want: []string{"unused-import"},
+		},
+		{
+			name:  "invalid identifier",
+			graph: mainFlow(variable("v", "my value")),
+			want:  []string{"invalid-identifier"},
+		},
+		{
+			name:  "same label in one function",
+			graph: mainFlow(variable("a", "count"), variable("b", "count")),
+			want:  []string{"duplicate-label"},
+		},
+		{
+			name:  "same label in different functions",
+			graph: withFunc(withFunc(mainFlow(variable("a", "count")), "first", variable("b", "count")), "second", variable("c", "count")),
+		},
+		{
+			name:  "functions with the same label",
+			graph: withFunc(withFunc(mainFlow(), "twice"), "twice"),
+			want:  []string{"duplicate-label"},
+		},
+		{
+			name: "deprecated function",
+			graph: func() *axon.Graph {
+				g := mainFlow(call("title", "strings.Title"))
+				g.Imports = []string{"strings"}
+				return g
+			}(),
+			want: []string{"deprecated-api"},
+		},
+		{
+			name:  "long execution chain",
+			graph: mainFlow(chain...),
+			want:  []string{"long-exec-chain"},
+		},
+		{
+			name:  "chain at the limit",
+			graph: mainFlow(chain[:maxExecChain-2]...),
+		},
+		{
+			name: "unused struct",
+			graph: func() *axon.Graph {
+				g := mainFlow(&axon.Node{Id: "v", Type: axon.NodeType_VAR_DECLARE, Label: "v", Config: map[string]string{"type": "Used"}})
+				g.Nodes = append(g.Nodes,
+					&axon.Node{Id: "used", Type: axon.NodeType_STRUCT_DEF, Label: "Used"},
+					&axon.Node{Id: "unused", Type: axon.NodeType_STRUCT_DEF, Label: "Unused"})
+				return g
+			}(),
+			want: []string{"unused-struct"},
+		},
+		{
+			name:  "finding suppressed on its node",
+			graph: mainFlow(&axon.Node{Id: "v", Type: axon.NodeType_VAR_DECLARE, Label: "my value", Config: map[string]string{IgnoreConfigKey: "invalid-identifier"}}),
+		},
+		{
+			name: "rule suppressed for the graph",
+			graph: func() *axon.Graph {
+				g := mainFlow(variable("v", "my value"))
+				g.Nodes[0].Config = map[string]string{IgnoreConfigKey: "all"}
+				return g
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range Run(tt.graph, Options{}) {
+				got = append(got, d.Code)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got findings %v, want %v", Run(tt.graph, Options{}), tt.want)
+			}
+		})
+	}
+}
+
+func TestDisabledRules(t *testing.T) {
+	graph := mainFlow(variable("v", "my value"))
+	if diags := Run(graph, Options{Disabled: []string{"invalid-identifier"}}); len(diags) != 0 {
+		t.Errorf("disabled rule still reported: %v", diags)
+	}
+}
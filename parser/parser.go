@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/Advik-B/Axon/debug"
 	"github.com/Advik-B/Axon/pkg/axon"
 	"github.com/ulikunitz/xz"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -69,9 +70,12 @@ func LoadGraphFromFile(filePath string) (*Graph, error) {
 			return nil, fmt.Errorf("failed to parse .axb (binary) file: %w", err)
 		}
 	case ".axd":
-		if err := yaml.Unmarshal(bytes, &graph); err != nil {
+		// The debug format wraps each node for commenting, so read it back through the same structure.
+		var debugGraph debug.DebugGraph
+		if err := yaml.Unmarshal(bytes, &debugGraph); err != nil {
 			return nil, fmt.Errorf("failed to parse .axd (YAML) file: %w", err)
 		}
+		return debugGraph.ToGraph(), nil
 	}
 
 	return &graph, nil
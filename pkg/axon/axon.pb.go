@@ -38,21 +38,61 @@ const (
 	NodeType_FUNCTION NodeType = 7 // A call to a function or method.
 	NodeType_OPERATOR NodeType = 8 // Performs a binary operation (e.g., "+", "==").
 	NodeType_IGNORE   NodeType = 9 // Explicitly discards an input value (like `_` in Go).
+	// Control Flow Nodes
+	NodeType_BRANCH NodeType = 10 // Routes execution to its "true" or "false" output based on a bool input.
+	NodeType_LOOP   NodeType = 11 // Repeats its "body" output, then continues from "completed". Config "mode" is count, while or range.
+	NodeType_SWITCH NodeType = 12 // Routes execution to the output of the matching "case:<output>" config entry, or "default".
+	// Variable Nodes
+	NodeType_VAR_DECLARE NodeType = 13 // Declares a mutable variable (global when outside any execution flow).
+	NodeType_VAR_SET     NodeType = 14 // Assigns a new value to the variable named by config "variable" (a VAR_DECLARE node ID).
+	NodeType_VAR_GET     NodeType = 15 // Reads the current value of the variable named by config "variable". Needs no execution edges.
+	// Error Handling Nodes
+	NodeType_ERROR_CHECK NodeType = 16 // Routes execution to "failed" when its error input is non-nil, and to "ok" otherwise.
+	// Concurrency Nodes
+	NodeType_GO           NodeType = 17 // Runs its "body" flow, or the call in impl_reference, in a new goroutine. Config "group" names a WAIT node.
+	NodeType_CHAN_MAKE    NodeType = 18 // Makes a channel of its output's type, buffered by config "size".
+	NodeType_CHAN_SEND    NodeType = 19 // Sends its "value" input on its "channel" input.
+	NodeType_CHAN_RECEIVE NodeType = 20 // Receives a value, and optionally whether the channel is still open, from its channel input.
+	NodeType_CHAN_CLOSE   NodeType = 21 // Closes its channel input.
+	NodeType_SELECT       NodeType = 22 // Waits on the "send"/"receive" operations of its "case:<output>" config entries.
+	NodeType_WAIT         NodeType = 23 // Waits for every goroutine in its group. Config "kind" is waitgroup or errgroup.
+	// Cleanup Nodes
+	NodeType_DEFER   NodeType = 24 // Defers the call in impl_reference, or its "body" flow, until the function returns.
+	NodeType_RECOVER NodeType = 25 // Recovers from a panic later in the function and runs its "handler" flow with the value.
+	// Import Nodes
+	NodeType_PLACEHOLDER NodeType = 26 // Stands in for Go code 'axon import' could not convert, kept in config "source". It must be replaced before the graph can be built.
 )
 
 // Enum value maps for NodeType.
 var (
 	NodeType_name = map[int32]string{
-		0: "NODE_UNKNOWN",
-		1: "START",
-		2: "END",
-		3: "RETURN",
-		4: "FUNC_DEF",
-		5: "STRUCT_DEF",
-		6: "CONSTANT",
-		7: "FUNCTION",
-		8: "OPERATOR",
-		9: "IGNORE",
+		0:  "NODE_UNKNOWN",
+		1:  "START",
+		2:  "END",
+		3:  "RETURN",
+		4:  "FUNC_DEF",
+		5:  "STRUCT_DEF",
+		6:  "CONSTANT",
+		7:  "FUNCTION",
+		8:  "OPERATOR",
+		9:  "IGNORE",
+		10: "BRANCH",
+		11: "LOOP",
+		12: "SWITCH",
+		13: "VAR_DECLARE",
+		14: "VAR_SET",
+		15: "VAR_GET",
+		16: "ERROR_CHECK",
+		17: "GO",
+		18: "CHAN_MAKE",
+		19: "CHAN_SEND",
+		20: "CHAN_RECEIVE",
+		21: "CHAN_CLOSE",
+		22: "SELECT",
+		23: "WAIT",
+		24: "DEFER",
+		25: "RECOVER",
+		26: "PLACEHOLDER",
 	}
 	NodeType_value = map[string]int32{
 		"NODE_UNKNOWN": 0,
@@ -65,6 +105,23 @@ var (
 		"FUNCTION":     7,
 		"OPERATOR":     8,
 		"IGNORE":       9,
+		"BRANCH":       10,
+		"LOOP":         11,
+		"SWITCH":       12,
+		"VAR_DECLARE":  13,
+		"VAR_SET":      14,
+		"VAR_GET":      15,
+		"ERROR_CHECK":  16,
+		"GO":           17,
+		"CHAN_MAKE":    18,
+		"CHAN_SEND":    19,
+		"CHAN_RECEIVE": 20,
+		"CHAN_CLOSE":   21,
+		"SELECT":       22,
+		"WAIT":         23,
+		"DEFER":        24,
+		"RECOVER":      25,
+		"PLACEHOLDER":  26,
 	}
 )
 
@@ -285,7 +342,11 @@ type Node struct {
 	// Optional visual information.
 	VisualInfo *VisualInfo `protobuf:"bytes,8,opt,name=visual_info,json=visualInfo,proto3,oneof" json:"visual_info,omitempty"`
 	// List of comment IDs attached to this node.
-	CommentIds    []string `protobuf:"bytes,9,rep,name=comment_ids,json=commentIds,proto3" json:"comment_ids,omitempty"`
+	CommentIds []string `protobuf:"bytes,9,rep,name=comment_ids,json=commentIds,proto3" json:"comment_ids,omitempty"`
+	// Named execution pins. When empty, the node uses the defaults for its type
+	// (a single "exec_in" and "exec_out", or "true"/"false" for a BRANCH).
+	ExecInputs    []string `protobuf:"bytes,10,rep,name=exec_inputs,json=execInputs,proto3" json:"exec_inputs,omitempty"`
+	ExecOutputs   []string `protobuf:"bytes,11,rep,name=exec_outputs,json=execOutputs,proto3" json:"exec_outputs,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -383,6 +444,20 @@ func (x *Node) GetCommentIds() []string {
 	return nil
 }
 
+func (x *Node) GetExecInputs() []string {
+	if x != nil {
+		return x.ExecInputs
+	}
+	return nil
+}
+
+func (x *Node) GetExecOutputs() []string {
+	if x != nil {
+		return x.ExecOutputs
+	}
+	return nil
+}
+
 // DataEdge represents a data dependency between two nodes.
 type DataEdge struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -457,6 +532,8 @@ type ExecEdge struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	FromNodeId    string                 `protobuf:"bytes,1,opt,name=from_node_id,json=fromNodeId,proto3" json:"from_node_id,omitempty"`
 	ToNodeId      string                 `protobuf:"bytes,2,opt,name=to_node_id,json=toNodeId,proto3" json:"to_node_id,omitempty"`
+	FromPort      string                 `protobuf:"bytes,3,opt,name=from_port,json=fromPort,proto3" json:"from_port,omitempty"` // Named execution output on the source node (e.g. "true"). Empty means the default output.
+	ToPort        string                 `protobuf:"bytes,4,opt,name=to_port,json=toPort,proto3" json:"to_port,omitempty"`       // Named execution input on the target node. Empty means the default input.
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -505,12 +582,28 @@ func (x *ExecEdge) GetToNodeId() string {
 	return ""
 }
 
+func (x *ExecEdge) GetFromPort() string {
+	if x != nil {
+		return x.FromPort
+	}
+	return ""
+}
+
+func (x *ExecEdge) GetToPort() string {
+	if x != nil {
+		return x.ToPort
+	}
+	return ""
+}
+
 // A Graph is a complete Axon visual program.
 type Graph struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	Name  string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	// Explicit list of Go packages to import.
+	// Go packages to import, by path, optionally preceded by a name as in
+	// "yaml gopkg.in/yaml.v3". Standard library packages the nodes use are
+	// imported without being listed.
 	Imports       []string    `protobuf:"bytes,3,rep,name=imports,proto3" json:"imports,omitempty"`
 	Nodes         []*Node     `protobuf:"bytes,4,rep,name=nodes,proto3" json:"nodes,omitempty"`
 	DataEdges     []*DataEdge `protobuf:"bytes,5,rep,name=data_edges,json=dataEdges,proto3" json:"data_edges,omitempty"`
@@ -615,7 +708,7 @@ const file_pkg_axon_axon_proto_rawDesc = "" +
 	"\ttype_name\x18\x02 \x01(\tR\btypeName\"3\n" +
 	"\aComment\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
-	"\acontent\x18\x02 \x01(\tR\acontent\"\x95\x03\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"\xd9\x03\n" +
 	"\x04Node\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\"\n" +
 	"\x04type\x18\x02 \x01(\x0e2\x0e.axon.NodeTypeR\x04type\x12\x14\n" +
@@ -629,7 +722,11 @@ const file_pkg_axon_axon_proto_rawDesc = "" +
 	"\vvisual_info\x18\b \x01(\v2\x10.axon.VisualInfoH\x00R\n" +
 	"visualInfo\x88\x01\x01\x12\x1f\n" +
 	"\vcomment_ids\x18\t \x03(\tR\n" +
-	"commentIds\x1a9\n" +
+	"commentIds\x12\x1f\n" +
+	"\vexec_inputs\x18\n" +
+	" \x03(\tR\n" +
+	"execInputs\x12!\n" +
+	"\fexec_outputs\x18\v \x03(\tR\vexecOutputs\x1a9\n" +
 	"\vConfigEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x0e\n" +
@@ -640,12 +737,14 @@ const file_pkg_axon_axon_proto_rawDesc = "" +
 	"\tfrom_port\x18\x02 \x01(\tR\bfromPort\x12\x1c\n" +
 	"\n" +
 	"to_node_id\x18\x03 \x01(\tR\btoNodeId\x12\x17\n" +
-	"\ato_port\x18\x04 \x01(\tR\x06toPort\"J\n" +
+	"\ato_port\x18\x04 \x01(\tR\x06toPort\"\x80\x01\n" +
 	"\bExecEdge\x12 \n" +
 	"\ffrom_node_id\x18\x01 \x01(\tR\n" +
 	"fromNodeId\x12\x1c\n" +
 	"\n" +
-	"to_node_id\x18\x02 \x01(\tR\btoNodeId\"\xf0\x01\n" +
+	"to_node_id\x18\x02 \x01(\tR\btoNodeId\x12\x1b\n" +
+	"\tfrom_port\x18\x03 \x01(\tR\bfromPort\x12\x17\n" +
+	"\ato_port\x18\x04 \x01(\tR\x06toPort\"\xf0\x01\n" +
 	"\x05Graph\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
@@ -656,7 +755,7 @@ const file_pkg_axon_axon_proto_rawDesc = "" +
 	"data_edges\x18\x05 \x03(\v2\x0e.axon.DataEdgeR\tdataEdges\x12-\n" +
 	"\n" +
 	"exec_edges\x18\x06 \x03(\v2\x0e.axon.ExecEdgeR\texecEdges\x12)\n" +
-	"\bcomments\x18\a \x03(\v2\r.axon.CommentR\bcomments*\x90\x01\n" +
+	"\bcomments\x18\a \x03(\v2\r.axon.CommentR\bcomments*\xf5\x02\n" +
 	"\bNodeType\x12\x10\n" +
 	"\fNODE_UNKNOWN\x10\x00\x12\t\n" +
 	"\x05START\x10\x01\x12\a\n" +
@@ -670,7 +769,29 @@ const file_pkg_axon_axon_proto_rawDesc = "" +
 	"\bFUNCTION\x10\a\x12\f\n" +
 	"\bOPERATOR\x10\b\x12\n" +
 	"\n" +
-	"\x06IGNORE\x10\tB\"Z github.com/Advik-B/Axon/pkg/axonb\x06proto3"
+	"\x06IGNORE\x10\t\x12\n" +
+	"\n" +
+	"\x06BRANCH\x10\n" +
+	"\x12\b\n" +
+	"\x04LOOP\x10\v\x12\n" +
+	"\n" +
+	"\x06SWITCH\x10\f\x12\x0f\n" +
+	"\vVAR_DECLARE\x10\r\x12\v\n" +
+	"\aVAR_SET\x10\x0e\x12\v\n" +
+	"\aVAR_GET\x10\x0f\x12\x0f\n" +
+	"\vERROR_CHECK\x10\x10\x12\x06\n" +
+	"\x02GO\x10\x11\x12\r\n" +
+	"\tCHAN_MAKE\x10\x12\x12\r\n" +
+	"\tCHAN_SEND\x10\x13\x12\x10\n" +
+	"\fCHAN_RECEIVE\x10\x14\x12\x0e\n" +
+	"\n" +
+	"CHAN_CLOSE\x10\x15\x12\n" +
+	"\n" +
+	"\x06SELECT\x10\x16\x12\b\n" +
+	"\x04WAIT\x10\x17\x12\t\n" +
+	"\x05DEFER\x10\x18\x12\v\n" +
+	"\aRECOVER\x10\x19\x12\x0f\n" +
+	"\vPLACEHOLDER\x10\x1aB\"Z github.com/Advik-B/Axon/pkg/axonb\x06proto3"
 
 var (
 	file_pkg_axon_axon_proto_rawDescOnce sync.Once
@@ -0,0 +1,157 @@
+package axon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Names of the execution pins a node has when it does not declare its own.
+const (
+	DefaultExecInput  = "exec_in"
+	DefaultExecOutput = "exec_out"
+)
+
+// SwitchCasePrefix marks the config entries of a SWITCH or SELECT node that define its cases.
+// The rest of the key names the case's execution output, and the value describes the case.
+const SwitchCasePrefix = "case:"
+
+// ExecInputPins returns the names of the node's execution input pins.
+// Declared pins take precedence over the defaults for the node's type.
+func (n *Node) ExecInputPins() []string {
+	if len(n.GetExecInputs()) > 0 {
+		return n.GetExecInputs()
+	}
+	switch n.GetType() {
+	case NodeType_START:
+		return nil
+	case NodeType_LOOP:
+		// A loop's body jumps back in through "continue" or "break".
+		return []string{DefaultExecInput, "continue", "break"}
+	default:
+		return []string{DefaultExecInput}
+	}
+}
+
+// ExecOutputPins returns the names of the node's execution output pins.
+// Declared pins take precedence over the defaults for the node's type.
+func (n *Node) ExecOutputPins() []string {
+	if len(n.GetExecOutputs()) > 0 {
+		return n.GetExecOutputs()
+	}
+	switch n.GetType() {
+	case NodeType_END, NodeType_RETURN:
+		return nil
+	case NodeType_BRANCH:
+		return []string{"true", "false"}
+	case NodeType_LOOP:
+		return []string{"body", "completed"}
+	case NodeType_SWITCH:
+		return append(n.SwitchCases(), "default")
+	case NodeType_ERROR_CHECK:
+		return []string{"ok", "failed"}
+	case NodeType_GO, NodeType_DEFER:
+		if n.GetImplReference() != "" {
+			return []string{DefaultExecOutput}
+		}
+		return []string{"body", DefaultExecOutput}
+	case NodeType_RECOVER:
+		return []string{"handler", DefaultExecOutput}
+	case NodeType_SELECT:
+		// A select only gets a default case when it must not block.
+		if n.GetConfig()["nonblocking"] == "true" {
+			return append(n.SwitchCases(), "default")
+		}
+		return n.SwitchCases()
+	default:
+		return []string{DefaultExecOutput}
+	}
+}
+
+// SwitchCases returns the names of the case outputs defined in a SWITCH or SELECT node's config, sorted by name.
+func (n *Node) SwitchCases() []string {
+	var cases []string
+	for key := range n.GetConfig() {
+		if name, ok := strings.CutPrefix(key, SwitchCasePrefix); ok {
+			cases = append(cases, name)
+		}
+	}
+	sort.Strings(cases)
+	return cases
+}
+
+// HasExecInputPin reports whether the node has an execution input with the given name.
+func (n *Node) HasExecInputPin(name string) bool {
+	for _, pin := range n.ExecInputPins() {
+		if pin == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasExecOutputPin reports whether the node has an execution output with the given name.
+func (n *Node) HasExecOutputPin(name string) bool {
+	for _, pin := range n.ExecOutputPins() {
+		if pin == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SourcePin returns the execution output the edge leaves from, resolving an empty name to the default.
+func (e *ExecEdge) SourcePin() string {
+	if e.GetFromPort() == "" {
+		return DefaultExecOutput
+	}
+	return e.GetFromPort()
+}
+
+// TargetPin returns the execution input the edge arrives at, resolving an empty name to the default.
+func (e *ExecEdge) TargetPin() string {
+	if e.GetToPort() == "" {
+		return DefaultExecInput
+	}
+	return e.GetToPort()
+}
+
+// FindPort returns the port with the given name, or nil if there is none.
+func FindPort(ports []*Port, name string) *Port {
+	for _, port := range ports {
+		if port.GetName() == name {
+			return port
+		}
+	}
+	return nil
+}
+
+// minPorts are the fewest data inputs and outputs a node of each type needs to do anything; the
+// transpiler and interpreter read these ports without looking further.
+var minPorts = map[NodeType]struct{ inputs, outputs int }{
+	NodeType_CONSTANT:     {0, 1},
+	NodeType_OPERATOR:     {1, 1},
+	NodeType_BRANCH:       {1, 0},
+	NodeType_ERROR_CHECK:  {1, 0},
+	NodeType_VAR_SET:      {1, 0},
+	NodeType_CHAN_MAKE:    {0, 1},
+	NodeType_CHAN_SEND:    {2, 0},
+	NodeType_CHAN_RECEIVE: {1, 0},
+	NodeType_CHAN_CLOSE:   {1, 0},
+}
+
+// CheckPorts reports a node that lacks the data ports its type cannot work without, like a
+// CONSTANT with no output or a BRANCH with no condition.
+func (n *Node) CheckPorts() error {
+	min, ok := minPorts[n.GetType()]
+	if !ok {
+		return nil
+	}
+	if len(n.GetOutputs()) < min.outputs {
+		return fmt.Errorf("%s node '%s' has no output", n.GetType(), n.GetLabel())
+	}
+	if len(n.GetInputs()) < min.inputs {
+		return fmt.Errorf("%s node '%s' needs at least %d input(s), but has %d", n.GetType(), n.GetLabel(), min.inputs, len(n.GetInputs()))
+	}
+	return nil
+}
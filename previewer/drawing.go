@@ -38,23 +38,57 @@ var (
 		"default": color.RGBA{R: 139, G: 92, B: 246, A: 255},
 	}
 	nodeColors = map[axon.NodeType]color.Color{
-		axon.NodeType_START:      color.RGBA{R: 16, G: 185, B: 129, A: 255},
-		axon.NodeType_END:        color.RGBA{R: 239, G: 68, B: 68, A: 255},
-		axon.NodeType_RETURN:     color.RGBA{R: 217, G: 70, B: 239, A: 255},
-		axon.NodeType_CONSTANT:   color.RGBA{R: 59, G: 130, B: 246, A: 255},
-		axon.NodeType_FUNCTION:   color.RGBA{R: 99, G: 102, B: 241, A: 255},
-		axon.NodeType_OPERATOR:   color.RGBA{R: 249, G: 115, B: 22, A: 255},
-		axon.NodeType_IGNORE:     color.RGBA{R: 236, G: 72, B: 153, A: 255},
-		axon.NodeType_STRUCT_DEF: color.RGBA{R: 14, G: 165, B: 233, A: 255},
-		axon.NodeType_FUNC_DEF:   color.RGBA{R: 34, G: 197, B: 94, A: 255},
+		axon.NodeType_START:        color.RGBA{R: 16, G: 185, B: 129, A: 255},
+		axon.NodeType_END:          color.RGBA{R: 239, G: 68, B: 68, A: 255},
+		axon.NodeType_RETURN:       color.RGBA{R: 217, G: 70, B: 239, A: 255},
+		axon.NodeType_CONSTANT:     color.RGBA{R: 59, G: 130, B: 246, A: 255},
+		axon.NodeType_FUNCTION:     color.RGBA{R: 99, G: 102, B: 241, A: 255},
+		axon.NodeType_OPERATOR:     color.RGBA{R: 249, G: 115, B: 22, A: 255},
+		axon.NodeType_IGNORE:       color.RGBA{R: 236, G: 72, B: 153, A: 255},
+		axon.NodeType_STRUCT_DEF:   color.RGBA{R: 14, G: 165, B: 233, A: 255},
+		axon.NodeType_FUNC_DEF:     color.RGBA{R: 34, G: 197, B: 94, A: 255},
+		axon.NodeType_BRANCH:       color.RGBA{R: 234, G: 179, B: 8, A: 255},
+		axon.NodeType_LOOP:         color.RGBA{R: 20, G: 184, B: 166, A: 255},
+		axon.NodeType_SWITCH:       color.RGBA{R: 202, G: 138, B: 4, A: 255},
+		axon.NodeType_VAR_DECLARE:  color.RGBA{R: 168, G: 85, B: 247, A: 255},
+		axon.NodeType_VAR_SET:      color.RGBA{R: 147, G: 51, B: 234, A: 255},
+		axon.NodeType_VAR_GET:      color.RGBA{R: 192, G: 132, B: 252, A: 255},
+		axon.NodeType_ERROR_CHECK:  color.RGBA{R: 220, G: 38, B: 38, A: 255},
+		axon.NodeType_GO:           color.RGBA{R: 6, G: 182, B: 212, A: 255},
+		axon.NodeType_CHAN_MAKE:    color.RGBA{R: 59, G: 130, B: 246, A: 255},
+		axon.NodeType_CHAN_SEND:    color.RGBA{R: 37, G: 99, B: 235, A: 255},
+		axon.NodeType_CHAN_RECEIVE: color.RGBA{R: 29, G: 78, B: 216, A: 255},
+		axon.NodeType_CHAN_CLOSE:   color.RGBA{R: 30, G: 64, B: 175, A: 255},
+		axon.NodeType_SELECT:       color.RGBA{R: 8, G: 145, B: 178, A: 255},
+		axon.NodeType_WAIT:         color.RGBA{R: 14, G: 116, B: 144, A: 255},
+		axon.NodeType_DEFER:        color.RGBA{R: 100, G: 116, B: 139, A: 255},
+		axon.NodeType_RECOVER:      color.RGBA{R: 190, G: 18, B: 60, A: 255},
+		axon.NodeType_PLACEHOLDER:  color.RGBA{R: 113, G: 113, B: 122, A: 255},
 	}
 	defaultNodeColor = color.RGBA{R: 45, G: 48, B: 51, A: 255}
 	nodeTypeTitles   = map[axon.NodeType]string{
-		axon.NodeType_FUNCTION:   "FUNCTION CALL",
-		axon.NodeType_CONSTANT:   "CONSTANT",
-		axon.NodeType_OPERATOR:   "OPERATOR",
-		axon.NodeType_STRUCT_DEF: "STRUCT DEFINITION",
-		axon.NodeType_FUNC_DEF:   "FUNCTION DEFINITION",
+		axon.NodeType_FUNCTION:     "FUNCTION CALL",
+		axon.NodeType_CONSTANT:     "CONSTANT",
+		axon.NodeType_OPERATOR:     "OPERATOR",
+		axon.NodeType_STRUCT_DEF:   "STRUCT DEFINITION",
+		axon.NodeType_FUNC_DEF:     "FUNCTION DEFINITION",
+		axon.NodeType_BRANCH:       "BRANCH",
+		axon.NodeType_LOOP:         "LOOP",
+		axon.NodeType_SWITCH:       "SWITCH",
+		axon.NodeType_VAR_DECLARE:  "VARIABLE",
+		axon.NodeType_VAR_SET:      "SET VARIABLE",
+		axon.NodeType_VAR_GET:      "GET VARIABLE",
+		axon.NodeType_ERROR_CHECK:  "ERROR CHECK",
+		axon.NodeType_GO:           "GOROUTINE",
+		axon.NodeType_CHAN_MAKE:    "MAKE CHANNEL",
+		axon.NodeType_CHAN_SEND:    "SEND",
+		axon.NodeType_CHAN_RECEIVE: "RECEIVE",
+		axon.NodeType_CHAN_CLOSE:   "CLOSE CHANNEL",
+		axon.NodeType_SELECT:       "SELECT",
+		axon.NodeType_WAIT:         "WAIT",
+		axon.NodeType_DEFER:        "DEFER",
+		axon.NodeType_RECOVER:      "RECOVER",
+		axon.NodeType_PLACEHOLDER:  "PLACEHOLDER",
 	}
 )
 
@@ -117,41 +151,42 @@ func drawNode(screen *ebiten.Image, node *LayoutNode, face, smallFace text.Face,
 
 // CHANGE: This function now accepts a text.Face from the v2 package
 func drawPorts(screen *ebiten.Image, node *LayoutNode, face text.Face, op *ebiten.DrawImageOptions) {
-	if node.Type != axon.NodeType_START {
-		if p, ok := node.InputPorts["exec_in"]; ok {
+	// Exec pins are drawn in their declared order; only non-default pins get a label.
+	for _, name := range node.ExecInputPins() {
+		if p, ok := node.ExecInputPorts[name]; ok {
 			drawExecPin(screen, p, false, colorExec, op)
-		}
-		for name, p := range node.InputPorts {
-			if name == "exec_in" {
-				continue
+			if name != axon.DefaultExecInput {
+				drawPortLabel(screen, p, name, false, face, op)
 			}
-			var portType string
-			for _, portDef := range node.Inputs {
-				if portDef.Name == name {
-					portType = portDef.TypeName
-					break
-				}
+		}
+	}
+	for name, p := range node.InputPorts {
+		var portType string
+		for _, portDef := range node.Inputs {
+			if portDef.Name == name {
+				portType = portDef.TypeName
+				break
 			}
-			drawDataPin(screen, p, portType, name, false, face, op)
 		}
+		drawDataPin(screen, p, portType, name, false, face, op)
 	}
-	if node.Type != axon.NodeType_END && node.Type != axon.NodeType_RETURN {
-		if p, ok := node.OutputPorts["exec_out"]; ok {
+	for _, name := range node.ExecOutputPins() {
+		if p, ok := node.ExecOutputPorts[name]; ok {
 			drawExecPin(screen, p, true, colorExec, op)
-		}
-		for name, p := range node.OutputPorts {
-			if name == "exec_out" {
-				continue
+			if name != axon.DefaultExecOutput {
+				drawPortLabel(screen, p, name, true, face, op)
 			}
-			var portType string
-			for _, portDef := range node.Outputs {
-				if portDef.Name == name {
-					portType = portDef.TypeName
-					break
-				}
+		}
+	}
+	for name, p := range node.OutputPorts {
+		var portType string
+		for _, portDef := range node.Outputs {
+			if portDef.Name == name {
+				portType = portDef.TypeName
+				break
 			}
-			drawDataPin(screen, p, portType, name, true, face, op)
 		}
+		drawDataPin(screen, p, portType, name, true, face, op)
 	}
 }
 
@@ -187,6 +222,14 @@ func drawDataPin(screen *ebiten.Image, p image.Point, typeName, label string, is
 	vector.DrawFilledCircle(screen, float32(tx), float32(ty), (portRadius+1)*zoom, color.Black, false)
 	vector.DrawFilledCircle(screen, float32(tx), float32(ty), portRadius*zoom, clr, false)
 
+	drawPortLabel(screen, p, label, isOutput, face, op)
+}
+
+// drawPortLabel draws a pin's name just inside the node, next to the pin.
+func drawPortLabel(screen *ebiten.Image, p image.Point, label string, isOutput bool, face text.Face, op *ebiten.DrawImageOptions) {
+	zoom := float32(op.GeoM.Element(0, 0))
+	tx, ty := op.GeoM.Apply(float64(p.X), float64(p.Y))
+
 	labelOp := &text.DrawOptions{}
 	labelOp.ColorScale.Reset()
 	labelOp.ColorScale.ScaleWithColor(colorPortLabel)
@@ -204,14 +247,17 @@ func drawDataPin(screen *ebiten.Image, p image.Point, typeName, label string, is
 	text.Draw(screen, label, face, labelOp)
 }
 
-func drawBezierCurve(screen *ebiten.Image, p0, p3 image.Point, clr color.Color, op *ebiten.DrawImageOptions) {
+// bezierControls returns the control points of the curve drawn between two ports.
+func bezierControls(p0, p3 image.Point) (p1, p2 image.Point) {
 	dx, dy := p3.X-p0.X, p3.Y-p0.Y
-	var p1, p2 image.Point
 	if math.Abs(float64(dx)) > math.Abs(float64(dy)) {
-		p1, p2 = image.Pt(p0.X+dx/2, p0.Y), image.Pt(p3.X-dx/2, p3.Y)
-	} else {
-		p1, p2 = image.Pt(p0.X, p0.Y+dy/2), image.Pt(p3.X, p3.Y-dy/2)
+		return image.Pt(p0.X+dx/2, p0.Y), image.Pt(p3.X-dx/2, p3.Y)
 	}
+	return image.Pt(p0.X, p0.Y+dy/2), image.Pt(p3.X, p3.Y-dy/2)
+}
+
+func drawBezierCurve(screen *ebiten.Image, p0, p3 image.Point, clr color.Color, op *ebiten.DrawImageOptions) {
+	p1, p2 := bezierControls(p0, p3)
 
 	var path vector.Path
 	v0x, v0y := op.GeoM.Apply(float64(p0.X), float64(p0.Y))
@@ -232,6 +278,40 @@ func drawBezierCurve(screen *ebiten.Image, p0, p3 image.Point, clr color.Color,
 	screen.DrawTriangles(verts, indices, getWhitePixel(), &ebiten.DrawTrianglesOptions{})
 }
 
+// drawEdgeLabel draws a value in a box at the middle of the curve between two ports.
+func drawEdgeLabel(screen *ebiten.Image, p0, p3 image.Point, label string, clr color.Color, face text.Face, op *ebiten.DrawImageOptions) {
+	p1, p2 := bezierControls(p0, p3)
+	// The point halfway along a cubic Bezier curve.
+	mx := float64(p0.X+3*p1.X+3*p2.X+p3.X) / 8
+	my := float64(p0.Y+3*p1.Y+3*p2.Y+p3.Y) / 8
+	tx, ty := op.GeoM.Apply(mx, my)
+
+	advance, _ := text.Measure(label, face, 0)
+	metrics := face.Metrics()
+	w, h := float32(advance)+12, float32(metrics.HAscent+metrics.HDescent)+6
+	x, y := float32(tx)-w/2, float32(ty)-h/2
+	drawFilledRoundRect(screen, x, y, w, h, 4, colorEdgeLabel)
+	strokeRoundRect(screen, x, y, w, h, 4, 1, clr)
+
+	labelOp := &text.DrawOptions{}
+	labelOp.GeoM.Translate(float64(x+6), float64(y+3))
+	labelOp.ColorScale.ScaleWithColor(colorText)
+	text.Draw(screen, label, face, labelOp)
+}
+
+// drawNodeHighlight outlines a node with a glow that grows and fades as pulse goes from 0 to 1.
+func drawNodeHighlight(screen *ebiten.Image, node *LayoutNode, pulse float64, op *ebiten.DrawImageOptions) {
+	tx, ty := op.GeoM.Apply(float64(node.Rect.Min.X), float64(node.Rect.Min.Y))
+	zoom := float32(op.GeoM.Element(0, 0))
+	w, h := float32(node.Rect.Dx())*zoom, float32(node.Rect.Dy())*zoom
+	grow := (3 + 6*float32(pulse)) * zoom
+
+	glow := colorActive
+	glow.A = uint8(220 - 160*pulse)
+	strokeRoundRect(screen, float32(tx)-grow, float32(ty)-grow, w+2*grow, h+2*grow, (nodeCornerRadius+grow/zoom)*zoom, 3*zoom, glow)
+	strokeRoundRect(screen, float32(tx), float32(ty), w, h, nodeCornerRadius*zoom, 2*zoom, colorActive)
+}
+
 func colorVerts(v []ebiten.Vertex, clr color.Color) {
 	r, g, b, a := clr.RGBA()
 	cr, cg, cb, ca := float32(r)/0xffff, float32(g)/0xffff, float32(b)/0xffff, float32(a)/0xffff
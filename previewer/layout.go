@@ -31,9 +31,11 @@ const (
 // LayoutNode stores a node's visual info, which is updated by the physics simulation.
 type LayoutNode struct {
 	*axon.Node
-	Rect        image.Rectangle
-	InputPorts  map[string]image.Point
-	OutputPorts map[string]image.Point
+	Rect            image.Rectangle
+	InputPorts      map[string]image.Point
+	OutputPorts     map[string]image.Point
+	ExecInputPorts  map[string]image.Point
+	ExecOutputPorts map[string]image.Point
 }
 
 // updateRect recalculates a node's visual rectangle and port positions based on its physics position and orientation.
@@ -41,14 +43,10 @@ func (n *PhysicsNode) updateRect(orientation LayoutOrientation) {
 	x, y := int(math.Round(n.Position.X)), int(math.Round(n.Position.Y))
 
 	// Dynamically calculate node height based on the number of ports
-	numInputRows := len(n.Inputs)
-	numOutputRows := len(n.Outputs)
-	if n.Type != axon.NodeType_END {
-		numOutputRows++
-	} // Account for exec_out
-	if n.Type != axon.NodeType_START {
-		numInputRows++
-	} // Account for exec_in
+	execInputs := n.ExecInputPins()
+	execOutputs := n.ExecOutputPins()
+	numInputRows := len(n.Inputs) + len(execInputs)
+	numOutputRows := len(n.Outputs) + len(execOutputs)
 
 	bodyRowCount := math.Max(float64(numInputRows), float64(numOutputRows))
 	dynamicHeight := int(nodeHeaderHeight) + nodePaddingTop + nodePaddingBottom + (int(bodyRowCount) * portRowHeight)
@@ -58,30 +56,37 @@ func (n *PhysicsNode) updateRect(orientation LayoutOrientation) {
 
 	// Dynamically update port positions
 	if orientation == Horizontal {
-		// Exec ports are at the top of the body
-		n.InputPorts["exec_in"] = image.Pt(x, y+int(nodeHeaderHeight)+nodePaddingTop)
-		n.OutputPorts["exec_out"] = image.Pt(x+nodeWidth, y+int(nodeHeaderHeight)+nodePaddingTop)
-		// Data ports follow
+		// Exec pins are at the top of the body, data ports follow
+		bodyTop := y + int(nodeHeaderHeight) + nodePaddingTop
+		for i, name := range execInputs {
+			n.ExecInputPorts[name] = image.Pt(x, bodyTop+i*portRowHeight)
+		}
+		for i, name := range execOutputs {
+			n.ExecOutputPorts[name] = image.Pt(x+nodeWidth, bodyTop+i*portRowHeight)
+		}
 		for i, p := range n.Inputs {
-			portY := y + int(nodeHeaderHeight) + nodePaddingTop + (i+1)*portRowHeight
+			portY := bodyTop + (len(execInputs)+i)*portRowHeight
 			n.InputPorts[p.Name] = image.Pt(x, portY)
 		}
 		for i, p := range n.Outputs {
-			portY := y + int(nodeHeaderHeight) + nodePaddingTop + (i+1)*portRowHeight
+			portY := bodyTop + (len(execOutputs)+i)*portRowHeight
 			n.OutputPorts[p.Name] = image.Pt(x+nodeWidth, portY)
 		}
 	} else { // Vertical
-		n.InputPorts["exec_in"] = image.Pt(x+nodeWidth/2, y)
-		n.OutputPorts["exec_out"] = image.Pt(x+nodeWidth/2, y+finalHeight)
-		numInputs := len(n.Inputs)
-		startX_in := x + nodeWidth/2 - (numInputs-1)*portRowHeight/2
+		// Exec pins and data ports share one row along each edge, centred on the node
+		startX_in := x + nodeWidth/2 - (numInputRows-1)*portRowHeight/2
+		for i, name := range execInputs {
+			n.ExecInputPorts[name] = image.Pt(startX_in+i*portRowHeight, y)
+		}
 		for i, p := range n.Inputs {
-			n.InputPorts[p.Name] = image.Pt(startX_in+i*portRowHeight, y)
+			n.InputPorts[p.Name] = image.Pt(startX_in+(len(execInputs)+i)*portRowHeight, y)
+		}
+		startX_out := x + nodeWidth/2 - (numOutputRows-1)*portRowHeight/2
+		for i, name := range execOutputs {
+			n.ExecOutputPorts[name] = image.Pt(startX_out+i*portRowHeight, y+finalHeight)
 		}
-		numOutputs := len(n.Outputs)
-		startX_out := x + nodeWidth/2 - (numOutputs-1)*portRowHeight/2
 		for i, p := range n.Outputs {
-			n.OutputPorts[p.Name] = image.Pt(startX_out+i*portRowHeight, y+finalHeight)
+			n.OutputPorts[p.Name] = image.Pt(startX_out+(len(execOutputs)+i)*portRowHeight, y+finalHeight)
 		}
 	}
 }
@@ -53,6 +53,9 @@ type Previewer struct {
 	codeScrollY       float64
 	codeContentHeight float64
 	transpiledCode    string
+
+	// Replay of a recorded run; nil unless SetTrace was given one.
+	replay *replay
 }
 
 func NewPreviewer(graph *axon.Graph) (*Previewer, error) {
@@ -101,6 +104,9 @@ func NewPreviewer(graph *axon.Graph) (*Previewer, error) {
 
 func (p *Previewer) Update() error {
 	simulatePhysics(p.physicsNodes, p.graph.DataEdges, p.graph.ExecEdges, p.draggedNode, p.currentOrientation)
+	if p.updateReplay() {
+		return nil // The mouse is scrubbing the timeline.
+	}
 	p.handleZoom()
 	p.handleInput()
 	return nil
@@ -129,13 +135,29 @@ func (p *Previewer) Draw(screen *ebiten.Image) {
 	op.GeoM.Scale(p.camZoom, p.camZoom)
 	op.GeoM.Translate(float64(sw)/2, float64(sh)/2)
 
+	var previousID, activeID string
+	if p.replay != nil {
+		previousID, activeID = p.replay.activeNode()
+	}
+	// Values recorded on data edges are drawn over the nodes.
+	type edgeLabel struct {
+		p0, p3 image.Point
+		value  string
+		clr    color.Color
+	}
+	var labels []edgeLabel
+
 	for _, edge := range p.graph.ExecEdges {
 		fromNode, ok1 := p.physicsNodes[edge.FromNodeId]
 		toNode, ok2 := p.physicsNodes[edge.ToNodeId]
 		if ok1 && ok2 {
-			p0 := fromNode.OutputPorts["exec_out"]
-			p3 := toNode.InputPorts["exec_in"]
-			drawBezierCurve(screen, p0, p3, colorExec, op)
+			p0 := fromNode.ExecOutputPorts[edge.SourcePin()]
+			p3 := toNode.ExecInputPorts[edge.TargetPin()]
+			var clr color.Color = colorExec
+			if edge.FromNodeId == previousID && edge.ToNodeId == activeID {
+				clr = colorActive // The step the replay just took.
+			}
+			drawBezierCurve(screen, p0, p3, clr, op)
 		}
 	}
 	for _, edge := range p.graph.DataEdges {
@@ -156,16 +178,30 @@ func (p *Previewer) Draw(screen *ebiten.Image) {
 				clr = dataTypeColors["default"]
 			}
 			drawBezierCurve(screen, p0, p3, clr, op)
+			if p.replay != nil {
+				if value, ok := p.replay.value(edge.FromNodeId, edge.FromPort); ok {
+					labels = append(labels, edgeLabel{p0, p3, value, clr})
+				}
+			}
 		}
 	}
 
 	for _, node := range p.physicsNodes {
 		drawNode(screen, node.LayoutNode, p.titleFace, p.smallFace, op)
 	}
+	if node, ok := p.physicsNodes[activeID]; ok {
+		drawNodeHighlight(screen, node.LayoutNode, p.replay.pulse(), op)
+	}
+	for _, label := range labels {
+		drawEdgeLabel(screen, label.p0, label.p3, label.value, label.clr, p.smallFace, op)
+	}
 
 	if p.showCodePanel {
 		p.drawCodePanel(screen)
 	}
+	if p.replay != nil {
+		p.drawTimeline(screen)
+	}
 }
 
 // chromaToRGBA converts a chroma.Colour to a standard color.RGBA
@@ -174,9 +210,10 @@ func chromaToRGBA(c chroma.Colour) color.RGBA {
 }
 
 func (p *Previewer) updateCodePanel() error {
-	code, err := transpiler.Transpile(p.graph)
+	code, err := transpiler.Transpile(p.graph, transpiler.Options{})
 	if err != nil {
-		p.transpiledCode = fmt.Sprintf("// Transpilation Error:\n// %v", err)
+		// Every problem found is on a line of its own.
+		p.transpiledCode = "// Transpilation Error:\n// " + strings.ReplaceAll(err.Error(), "\n", "\n// ")
 	} else {
 		p.transpiledCode = code
 	}
@@ -422,9 +459,11 @@ func initializePhysicsNodes(graph *axon.Graph) map[string]*PhysicsNode {
 			y := startY + i*(nodeHeight+vSpacing)
 			pn := &PhysicsNode{
 				LayoutNode: &LayoutNode{
-					Node:        node,
-					InputPorts:  make(map[string]image.Point),
-					OutputPorts: make(map[string]image.Point),
+					Node:            node,
+					InputPorts:      make(map[string]image.Point),
+					OutputPorts:     make(map[string]image.Point),
+					ExecInputPorts:  make(map[string]image.Point),
+					ExecOutputPorts: make(map[string]image.Point),
 				},
 				Position:       Vec2{X: float64(x), Y: float64(y)},
 				TargetPosition: Vec2{X: float64(x), Y: float64(y)},
@@ -0,0 +1,217 @@
+package previewer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/Advik-B/Axon/transpiler"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	text "github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// Ticks (at 60 per second) each event is shown for while the replay plays.
+	replayStepTicks = 30
+	timelineHeight  = 44
+	timelineMargin  = 16
+	// Longest value shown on an edge, in characters.
+	maxEdgeLabel = 24
+)
+
+var (
+	colorActive      = color.RGBA{R: 250, G: 204, B: 21, A: 255}
+	colorTimeline    = color.RGBA{R: 20, G: 21, B: 22, A: 240}
+	colorTimelineBar = color.RGBA{R: 55, G: 58, B: 62, A: 255}
+	colorEdgeLabel   = color.RGBA{R: 10, G: 10, B: 10, A: 220}
+)
+
+// replay steps through a run recorded by a traced build, one event at a time.
+type replay struct {
+	events []transpiler.TraceEvent
+	// current is the index of the event shown.
+	current int
+	playing bool
+	// ticks counts the updates the current event has been shown for while playing; frame counts
+	// every update, for the pulse.
+	ticks, frame int
+	scrubbing    bool
+	// values holds the latest value recorded for each "nodeID.port" up to shown, the event it
+	// was last brought up to date with.
+	values map[string]string
+	shown  int
+}
+
+// SetTrace makes the previewer replay a recorded run: the node of each event in turn pulses, the
+// edges are labelled with the values recorded so far, and a timeline at the bottom of the window
+// can be scrubbed. The replay starts playing from the first event.
+func (p *Previewer) SetTrace(events []transpiler.TraceEvent) {
+	if len(events) == 0 {
+		p.replay = nil
+		return
+	}
+	p.replay = &replay{events: events, playing: true, shown: -1}
+}
+
+// seek shows the event at index i, clamped to the trace.
+func (r *replay) seek(i int) {
+	r.current = max(0, min(i, len(r.events)-1))
+	r.ticks = 0
+}
+
+// value returns the latest value recorded for a node's output, as JSON shortened for display.
+func (r *replay) value(nodeID, port string) (string, bool) {
+	if r.shown > r.current {
+		r.values, r.shown = nil, -1
+	}
+	if r.values == nil {
+		r.values = make(map[string]string)
+	}
+	for ; r.shown < r.current; r.shown++ {
+		event := r.events[r.shown+1]
+		for name, raw := range event.Outputs {
+			label := string(raw)
+			if runes := []rune(label); len(runes) > maxEdgeLabel {
+				label = string(runes[:maxEdgeLabel-1]) + "…"
+			}
+			r.values[event.Node+"."+name] = label
+		}
+	}
+	v, ok := r.values[nodeID+"."+port]
+	return v, ok
+}
+
+// updateReplay advances a playing replay and handles the replay's keys and the timeline. It
+// reports whether the mouse is scrubbing the timeline, so it does not also pan the view.
+func (p *Previewer) updateReplay() bool {
+	r := p.replay
+	if r == nil {
+		return false
+	}
+	r.frame++
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyP):
+		if !r.playing && r.current == len(r.events)-1 {
+			r.seek(0) // Play again from the start.
+		}
+		r.playing = !r.playing
+		r.ticks = 0
+	case inpututil.IsKeyJustPressed(ebiten.KeyRight):
+		r.playing = false
+		r.seek(r.current + 1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyLeft):
+		r.playing = false
+		r.seek(r.current - 1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyHome):
+		r.seek(0)
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnd):
+		r.seek(len(r.events) - 1)
+	}
+
+	mx, my := ebiten.CursorPosition()
+	bar := p.timelineRect()
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && image.Pt(mx, my).In(bar) {
+		r.scrubbing, r.playing = true, false
+	}
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		r.scrubbing = false
+	}
+	if r.scrubbing {
+		track := timelineTrack(bar)
+		fraction := float64(mx-track.Min.X) / float64(max(track.Dx(), 1))
+		r.seek(int(math.Round(fraction * float64(len(r.events)-1))))
+		return true
+	}
+
+	if r.playing {
+		r.ticks++
+		if r.ticks >= replayStepTicks {
+			r.seek(r.current + 1)
+			if r.current == len(r.events)-1 {
+				r.playing = false
+			}
+		}
+	}
+	return false
+}
+
+// timelineRect is where the timeline is drawn: along the bottom of the window, clear of the code
+// panel.
+func (p *Previewer) timelineRect() image.Rectangle {
+	right, bottom := p.lastWidth, p.lastHeight
+	if p.showCodePanel {
+		if p.currentOrientation == Horizontal {
+			right -= codePanelWidthLandscape
+		} else {
+			bottom -= codePanelHeightPortrait
+		}
+	}
+	return image.Rect(timelineMargin, bottom-timelineMargin-timelineHeight, right-timelineMargin, bottom-timelineMargin)
+}
+
+// timelineTrack is the part of the timeline that maps to the events, under its caption.
+func timelineTrack(bar image.Rectangle) image.Rectangle {
+	return image.Rect(bar.Min.X+12, bar.Max.Y-14, bar.Max.X-12, bar.Max.Y-8)
+}
+
+// drawTimeline draws the timeline with the current event's number, node and time since the
+// run started.
+func (p *Previewer) drawTimeline(screen *ebiten.Image) {
+	r := p.replay
+	bar := p.timelineRect()
+	if bar.Dx() <= 0 {
+		return
+	}
+	drawFilledRoundRect(screen, float32(bar.Min.X), float32(bar.Min.Y), float32(bar.Dx()), float32(bar.Dy()), 6, colorTimeline)
+
+	track := timelineTrack(bar)
+	vector.DrawFilledRect(screen, float32(track.Min.X), float32(track.Min.Y), float32(track.Dx()), float32(track.Dy()), colorTimelineBar, false)
+	fraction := 1.0
+	if len(r.events) > 1 {
+		fraction = float64(r.current) / float64(len(r.events)-1)
+	}
+	handleX := float32(track.Min.X) + float32(fraction)*float32(track.Dx())
+	vector.DrawFilledRect(screen, float32(track.Min.X), float32(track.Min.Y), handleX-float32(track.Min.X), float32(track.Dy()), colorActive, false)
+	vector.DrawFilledCircle(screen, handleX, float32(track.Min.Y+track.Dy()/2), 6, colorActive, false)
+
+	event := r.events[r.current]
+	node := event.Node
+	if n, ok := p.physicsNodes[event.Node]; ok && n.Label != "" {
+		node = n.Label
+	}
+	state := "PAUSED"
+	if r.playing {
+		state = "PLAYING"
+	}
+	caption := fmt.Sprintf("%s  event %d/%d  %s  +%s", state, r.current+1, len(r.events), node, event.Time.Sub(r.events[0].Time).Round(time.Microsecond))
+	captionOp := &text.DrawOptions{}
+	captionOp.GeoM.Translate(float64(bar.Min.X+12), float64(bar.Min.Y+18))
+	captionOp.ColorScale.ScaleWithColor(colorText)
+	text.Draw(screen, caption, p.smallFace, captionOp)
+
+	help := "P play/pause  Left/Right step  drag to scrub"
+	advance, _ := text.Measure(help, p.smallFace, 0)
+	helpOp := &text.DrawOptions{}
+	helpOp.GeoM.Translate(float64(bar.Max.X-12)-advance, float64(bar.Min.Y+18))
+	helpOp.ColorScale.ScaleWithColor(colorTextDim)
+	text.Draw(screen, help, p.smallFace, helpOp)
+}
+
+// activeNode returns the ID of the node the replay is at, and of the one before it, whose exec
+// edge into it is highlighted.
+func (r *replay) activeNode() (previous, current string) {
+	if r.current > 0 {
+		previous = r.events[r.current-1].Node
+	}
+	return previous, r.events[r.current].Node
+}
+
+// pulse is the phase of the active node's highlight, between 0 and 1.
+func (r *replay) pulse() float64 {
+	return 0.5 + 0.5*math.Sin(float64(r.frame)/8)
+}
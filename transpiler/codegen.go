@@ -2,6 +2,10 @@ package transpiler
 
 import (
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/Advik-B/Axon/pkg/axon"
@@ -23,11 +27,63 @@ func generateNodeCode(state *transpilationState, node *axon.Node) (string, error
 		return generateFunctionCall(state, node)
 	case axon.NodeType_RETURN:
 		return generateReturn(state, node)
+	case axon.NodeType_VAR_DECLARE:
+		return generateVarDeclare(state, node, false) // false for local scope
+	case axon.NodeType_VAR_SET:
+		return generateVarSet(state, node)
+	case axon.NodeType_VAR_GET:
+		return "", nil // Reads are resolved wherever their value is used
+	case axon.NodeType_CHAN_MAKE:
+		return generateChanMake(state, node)
+	case axon.NodeType_CHAN_SEND:
+		return generateChanSend(state, node)
+	case axon.NodeType_CHAN_RECEIVE:
+		return generateChanReceive(state, node)
+	case axon.NodeType_CHAN_CLOSE:
+		return generateChanClose(state, node)
+	case axon.NodeType_WAIT:
+		return generateWait(state, node)
 	default:
 		return fmt.Sprintf("\t// Node type '%s' not implemented for node '%s'\n", node.Type, node.Id), nil
 	}
 }
 
+// generatePureNode emits the code for a pure node off the execution flow into the prelude of the
+// statement being generated, computing any pure nodes it depends on first.
+func generatePureNode(state *transpilationState, node *axon.Node) error {
+	if state.prelude == nil {
+		return errorAt(CodePureNodeOutsideFunc, node, "", "pure node '%s' is used outside of a function body, so it must be wired into an execution flow", node.Label)
+	}
+	if state.prelude.active[node.Id] {
+		return errorAt(CodeDataCycle, node, "", "pure node '%s' depends on its own output", node.Label)
+	}
+	for _, edge := range state.graph.DataEdges {
+		if source, ok := state.nodeMap[edge.FromNodeId]; ok && edge.ToNodeId == node.Id && source.Type == axon.NodeType_VAR_GET {
+			return errorAt(CodePureNodeReadsVar, node, edge.ToPort, "pure node '%s' reads a variable, so it must be wired into the execution flow to fix when the read happens", node.Label)
+		}
+	}
+
+	state.prelude.active[node.Id] = true
+	code, err := generateNodeCode(state, node)
+	delete(state.prelude.active, node.Id)
+	if err != nil {
+		return asDiagnostic(err, CodeInvalidNode, node)
+	}
+	for _, outputPort := range node.Outputs {
+		key := fmt.Sprintf("%s.%s", node.Id, outputPort.Name)
+		if varName, ok := state.outputVarMap[key]; ok {
+			state.prelude.vars[key] = varName
+		}
+	}
+	if code != "" {
+		state.prelude.code.WriteString(nodeMarker(node, "\t"))
+	}
+	state.prelude.code.WriteString(generateCommentBlock(state, node))
+	state.prelude.code.WriteString(code)
+	state.prelude.code.WriteString(traceCall(state, node))
+	return nil
+}
+
 // generateStructDef generates a `type ... struct` block.
 func generateStructDef(state *transpilationState, node *axon.Node) (string, error) {
 	var sb strings.Builder
@@ -42,6 +98,10 @@ func generateStructDef(state *transpilationState, node *axon.Node) (string, erro
 
 // generateFuncDef generates a complete function or method definition.
 func generateFuncDef(state *transpilationState, entryNode *axon.Node, bodyNodes []*axon.Node) (string, error) {
+	// Parameters and locals are only visible inside the function.
+	saved := state.enterScope()
+	defer state.exitScope(saved)
+
 	var sb strings.Builder
 	sb.WriteString(generateCommentBlock(state, entryNode))
 
@@ -49,8 +109,7 @@ func generateFuncDef(state *transpilationState, entryNode *axon.Node, bodyNodes
 	// Check for a receiver (convention: an input port named 'receiver')
 	for _, port := range entryNode.Inputs {
 		if port.Name == "receiver" {
-			// A short, conventional receiver name like 'u' for 'User'
-			receiverName := strings.ToLower(string(port.TypeName[strings.LastIndex(port.TypeName, "*")+1]))
+			receiverName := state.nameOfOutput(entryNode, port)
 			receiver = append(receiver, fmt.Sprintf("(%s %s)", receiverName, port.TypeName))
 			state.outputVarMap[fmt.Sprintf("%s.receiver", entryNode.Id)] = receiverName
 			break
@@ -59,9 +118,10 @@ func generateFuncDef(state *transpilationState, entryNode *axon.Node, bodyNodes
 
 	// Function parameters are defined as output ports on the FUNC_DEF node
 	for _, port := range entryNode.Outputs {
-		params = append(params, fmt.Sprintf("%s %s", port.Name, port.TypeName))
+		name := state.nameOfOutput(entryNode, port)
+		params = append(params, fmt.Sprintf("%s %s", name, port.TypeName))
 		// Register the parameter as a known variable for the function body
-		state.outputVarMap[fmt.Sprintf("%s.%s", entryNode.Id, port.Name)] = port.Name
+		state.outputVarMap[fmt.Sprintf("%s.%s", entryNode.Id, port.Name)] = name
 	}
 
 	// Find the RETURN node to determine return types
@@ -74,6 +134,9 @@ func generateFuncDef(state *transpilationState, entryNode *axon.Node, bodyNodes
 		}
 	}
 
+	state.returnTypes = returnTypes
+	defer func() { state.returnTypes = nil }()
+
 	returnStr := ""
 	if len(returnTypes) > 0 {
 		if len(returnTypes) > 1 {
@@ -83,8 +146,8 @@ func generateFuncDef(state *transpilationState, entryNode *axon.Node, bodyNodes
 		}
 	}
 
-	sb.WriteString(fmt.Sprintf("func %s %s(%s) %s {\n", strings.Join(receiver, ""), entryNode.Label, strings.Join(params, ", "), returnStr))
-	bodyCode, err := generateFunctionBody(state, bodyNodes)
+	sb.WriteString(fmt.Sprintf("func %s %s(%s) %s {\n", strings.Join(receiver, ""), state.callee(entryNode.Label, len(receiver) > 0), strings.Join(params, ", "), returnStr))
+	bodyCode, err := generateFunctionBody(state, entryNode)
 	if err != nil {
 		return "", err
 	}
@@ -93,45 +156,117 @@ func generateFuncDef(state *transpilationState, entryNode *axon.Node, bodyNodes
 	return sb.String(), nil
 }
 
-// generateConstant generates code for a CONSTANT node.
+// generateConstant generates code for a CONSTANT node. A global slice or map literal, which Go
+// cannot declare as a constant, becomes a variable.
 func generateConstant(state *transpilationState, node *axon.Node, isGlobal bool) (string, error) {
 	val, ok := node.Config["value"]
 	if !ok {
 		return "", fmt.Errorf("constant node %s has no 'value' in config", node.Id)
 	}
-	varName := node.Label
+	varName := state.nameOfOutput(node, node.Outputs[0])
 	state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = varName
 
 	if isGlobal {
-		return fmt.Sprintf("const %s = %s\n\n", varName, val), nil
+		keyword := "const"
+		if expr, err := parser.ParseExpr(val); err == nil {
+			if _, ok := ast.Unparen(expr).(*ast.CompositeLit); ok {
+				keyword = "var"
+			}
+		}
+		return fmt.Sprintf("%s %s = %s\n\n", keyword, varName, val), nil
 	}
 	return fmt.Sprintf("\t%s := %s\n", varName, val), nil
 }
 
+// generateVarDeclare generates the declaration of a mutable variable and brings it into scope.
+// Locals use `x := v` when only an initial value is given, and `var x T` or `var x T = v` otherwise.
+func generateVarDeclare(state *transpilationState, node *axon.Node, isGlobal bool) (string, error) {
+	name := state.nameOf(node.Id)
+
+	typeName := node.Config["type"]
+	value := node.Config["value"]
+	if len(node.Inputs) > 0 {
+		inputVar, err := findSourceVar(state, node.Id, node.Inputs[0].Name)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve initial value for variable node %s: %w", node.Id, err)
+		}
+		value = inputVar
+	}
+	if typeName == "" && value == "" {
+		return "", fmt.Errorf("variable node %s needs a 'type' in config or an initial value", node.Id)
+	}
+	// Go rejects local variables that are never read.
+	if !isGlobal && !isVariableRead(state.graph, node.Id) {
+		return "", errorAt(CodeUnreadVariable, node, "", "variable '%s' (node %s) is never read by a VAR_GET node", name, node.Id)
+	}
+	state.variables[node.Id] = variable{name: name, depth: state.depth}
+
+	switch {
+	case isGlobal:
+		decl := "var " + name
+		if typeName != "" {
+			decl += " " + typeName
+		}
+		if value != "" {
+			decl += " = " + value
+		}
+		return decl + "\n\n", nil
+	case typeName == "":
+		return fmt.Sprintf("\t%s := %s\n", name, value), nil
+	case value == "":
+		return fmt.Sprintf("\tvar %s %s\n", name, typeName), nil
+	default:
+		return fmt.Sprintf("\tvar %s %s = %s\n", name, typeName, value), nil
+	}
+}
+
+// generateVarSet generates an assignment for a VAR_SET node: `x = v`, or `x += v` when
+// config "op" names an arithmetic or bitwise operator.
+func generateVarSet(state *transpilationState, node *axon.Node) (string, error) {
+	target, err := resolveVariable(state, node)
+	if err != nil {
+		return "", err
+	}
+	if len(node.Inputs) != 1 {
+		return "", fmt.Errorf("variable set node %s must have exactly one input, found %d", node.Id, len(node.Inputs))
+	}
+	value, err := findSourceVar(state, node.Id, node.Inputs[0].Name)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve value for variable set node %s: %w", node.Id, err)
+	}
+
+	op := node.Config["op"]
+	switch op {
+	case "", "+", "-", "*", "/", "%", "&", "|", "^", "<<", ">>", "&^":
+	default:
+		return "", fmt.Errorf("variable set node %s has unsupported op '%s'", node.Id, op)
+	}
+	return fmt.Sprintf("\t%s %s= %s\n", target, op, value), nil
+}
+
 func generateFunctionCall(state *transpilationState, node *axon.Node) (string, error) {
 	if node.ImplReference == "" {
 		return "", fmt.Errorf("function call node %s is missing 'impl_reference'", node.Id)
 	}
 
-	var args []string
-	for _, inputPort := range node.Inputs {
-		arg, err := findSourceVar(state, node.Id, inputPort.Name)
-		if err != nil {
-			return "", fmt.Errorf("could not resolve input '%s' for func call %s: %w", inputPort.Name, node.Id, err)
-		}
-		args = append(args, arg)
+	args, err := resolveArgs(state, node)
+	if err != nil {
+		return "", err
+	}
+	call := formatCall(state, node, args)
+	if state.foldable[node.Id] {
+		return foldOrDeclare(state, node, call, precPrimary), nil
 	}
-	argString := strings.Join(args, ", ")
 
 	var outputVars []string
-	for i, outputPort := range node.Outputs {
+	for _, outputPort := range node.Outputs {
 		if !isOutputUsed(state.graph, node.Id, outputPort.Name) {
-			return "", fmt.Errorf("output '%s' of function call '%s' is not used or explicitly ignored", outputPort.Name, node.Label)
-		}
-		varName := fmt.Sprintf("%s_out%d", node.Label, i)
-		if len(node.Outputs) == 1 {
-			varName = node.Label
+			if outputPort.TypeName == "error" {
+				return "", errorAt(CodeUnhandledError, node, outputPort.Name, "error output '%s' of function call '%s' is not handled; connect it to an ERROR_CHECK node or explicitly ignore it", outputPort.Name, node.Label)
+			}
+			return "", errorAt(CodeUnusedOutput, node, outputPort.Name, "output '%s' of function call '%s' is not used or explicitly ignored", outputPort.Name, node.Label)
 		}
+		varName := state.nameOfOutput(node, outputPort)
 
 		isIgnored := isPortConnectedToIgnore(state, node.Id, outputPort.Name)
 		if isIgnored {
@@ -144,9 +279,45 @@ func generateFunctionCall(state *transpilationState, node *axon.Node) (string, e
 
 	outputString := strings.Join(outputVars, ", ")
 	if len(outputVars) > 0 {
-		return fmt.Sprintf("\t%s := %s(%s)\n", outputString, node.ImplReference, argString), nil
+		// `:=` needs at least one new variable, so discarding every result is a plain assignment.
+		if strings.Trim(outputString, "_, ") == "" {
+			return fmt.Sprintf("\t%s = %s\n", outputString, call), nil
+		}
+		return fmt.Sprintf("\t%s := %s\n", outputString, call), nil
+	}
+	return fmt.Sprintf("\t%s\n", call), nil
+}
+
+// resolveArgs resolves the variables feeding each of a call node's inputs.
+func resolveArgs(state *transpilationState, node *axon.Node) ([]string, error) {
+	var args []string
+	for _, inputPort := range node.Inputs {
+		minPrec := 0
+		if inputPort.Name == "receiver" {
+			minPrec = precPrimary // It is followed by the method selector.
+		}
+		arg, err := findSourceOperand(state, node.Id, inputPort.Name, minPrec)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve input '%s' for func call %s: %w", inputPort.Name, node.Id, err)
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// formatCall formats the call to a node's impl_reference with one argument per input.
+// As with FUNC_DEF, an input named "receiver" is the value the method is called on.
+func formatCall(state *transpilationState, node *axon.Node, args []string) string {
+	fn := state.callee(node.ImplReference, false)
+	var rest []string
+	for i, inputPort := range node.Inputs {
+		if inputPort.Name == "receiver" {
+			fn = fmt.Sprintf("%s.%s", args[i], state.callee(node.ImplReference, true))
+			continue
+		}
+		rest = append(rest, args[i])
 	}
-	return fmt.Sprintf("\t%s(%s)\n", node.ImplReference, argString), nil
+	return fmt.Sprintf("%s(%s)", fn, strings.Join(rest, ", "))
 }
 
 // generateOperator generates code for a binary operation or a unary type cast.
@@ -165,12 +336,8 @@ func generateOperator(state *transpilationState, node *axon.Node) (string, error
 			return "", fmt.Errorf("could not resolve input for unary operator node %s: %w", node.Id, err)
 		}
 
-		varName := node.Label
-		outputPort := node.Outputs[0]
-		state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, outputPort.Name)] = varName
-
 		// Generate the cast: result := string(source)
-		return fmt.Sprintf("\t%s := %s(%s)\n", varName, op, inputVar), nil
+		return foldOrDeclare(state, node, fmt.Sprintf("%s(%s)", op, inputVar), precPrimary), nil
 	}
 
 	// --- BINARY OPERATOR LOGIC (Arithmetic, etc.) ---
@@ -186,25 +353,848 @@ func generateOperator(state *transpilationState, node *axon.Node) (string, error
 				}
 				fields = append(fields, arg)
 			}
-			varName := node.Label
-			state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = varName
-			return fmt.Sprintf("\t%s := %s{%s}\n", varName, op, strings.Join(fields, ", ")), nil
+			prec := precPrimary
+			if strings.HasPrefix(op, "&") {
+				prec = precUnary
+			}
+			return foldOrDeclare(state, node, fmt.Sprintf("%s{%s}", op, strings.Join(fields, ", ")), prec), nil
 		}
 
 		// Handle standard binary operators
-		inputA, errA := findSourceVar(state, node.Id, "a")
-		inputB, errB := findSourceVar(state, node.Id, "b")
+		// Folded operands are parenthesised as needed; Go's binary operators are left-associative.
+		prec, ok := binaryPrecedence[op]
+		if !ok {
+			prec = precUnary
+		}
+		inputA, errA := findSourceOperand(state, node.Id, "a", prec)
+		inputB, errB := findSourceOperand(state, node.Id, "b", prec+1)
 		if errA != nil || errB != nil {
 			return "", fmt.Errorf("could not resolve inputs for operator node %s", node.Id)
 		}
-		varName := node.Label
-		state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = varName
-		return fmt.Sprintf("\t%s := %s %s %s\n", varName, inputA, op, inputB), nil
+		return foldOrDeclare(state, node, fmt.Sprintf("%s %s %s", inputA, op, inputB), prec), nil
 	}
 
 	return "", fmt.Errorf("operator node '%s' has an unsupported number of inputs (%d)", node.Label, len(node.Inputs))
 }
 
+// generateBranch generates an `if cond { ... } else { ... }` statement for a BRANCH node.
+// Each arm runs until the branches rejoin; the edge into the join node is returned so the caller can continue from it.
+func generateBranch(state *transpilationState, node *axon.Node, ctx blockContext) (string, *axon.ExecEdge, error) {
+	if len(node.Inputs) != 1 {
+		return "", nil, fmt.Errorf("branch node %s must have exactly one bool input, found %d", node.Id, len(node.Inputs))
+	}
+	cond, err := findSourceVar(state, node.Id, node.Inputs[0].Name)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not resolve condition for branch node %s: %w", node.Id, err)
+	}
+
+	trueEdge := nextExecEdge(state, node.Id, "true")
+	falseEdge := nextExecEdge(state, node.Id, "false")
+	joinID := findJoinNode(state, []string{trueEdge.GetToNodeId(), falseEdge.GetToNodeId()})
+	armCtx := blockContext{stopID: joinID}
+	if joinID == "" {
+		// The arms never meet again, so each one runs until it terminates or leaves its loop.
+		armCtx = ctx
+	}
+
+	trueCode, err := generateNestedBlock(state, trueEdge, armCtx)
+	if err != nil {
+		return "", nil, err
+	}
+	falseCode, err := generateNestedBlock(state, falseEdge, armCtx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sb strings.Builder
+	if trueCode == "" && falseCode != "" {
+		negated, err := findSourceOperand(state, node.Id, node.Inputs[0].Name, precUnary)
+		if err != nil {
+			return "", nil, err
+		}
+		sb.WriteString(fmt.Sprintf("\tif !%s {\n%s\t}\n", negated, falseCode))
+	} else {
+		sb.WriteString(fmt.Sprintf("\tif %s {\n%s", cond, trueCode))
+		if falseCode != "" {
+			sb.WriteString(portMarker(node, "false", "\t"))
+			sb.WriteString(fmt.Sprintf("\t} else {\n%s", falseCode))
+		}
+		sb.WriteString(nodeMarker(node, "\t"))
+		sb.WriteString("\t}\n")
+	}
+
+	if joinID == "" {
+		return sb.String(), nil, nil
+	}
+	// Both arms flow on into the join node, so execution continues there after the if statement.
+	return sb.String(), &axon.ExecEdge{FromNodeId: node.Id, ToNodeId: joinID}, nil
+}
+
+// generateLoop generates a `for` statement for a LOOP node. The body is nested inside it, and the
+// edge leaving its "completed" output is returned so the caller can continue after the loop.
+func generateLoop(state *transpilationState, node *axon.Node) (string, *axon.ExecEdge, error) {
+	// The loop's outputs (index, key, value) only exist inside the body.
+	saved := state.enterScope()
+	header, err := generateLoopHeader(state, node)
+	if err != nil {
+		state.exitScope(saved)
+		return "", nil, err
+	}
+
+	state.loops = append(state.loops, node)
+	state.breakables = append(state.breakables, node)
+	body, err := generateBlock(state, nextExecEdge(state, node.Id, "body"), blockContext{loopTail: true})
+	body = traceCall(state, node) + body
+	state.loops = state.loops[:len(state.loops)-1]
+	state.breakables = state.breakables[:len(state.breakables)-1]
+	state.exitScope(saved)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sb strings.Builder
+	if state.labelledLoops[node.Id] {
+		sb.WriteString(fmt.Sprintf("%s:\n", state.nameOf(loopLabelKey(node))))
+	}
+	sb.WriteString(fmt.Sprintf("\t%s {\n%s\t}\n", header, indentBlock(body)))
+	return sb.String(), nextExecEdge(state, node.Id, "completed"), nil
+}
+
+// generateSwitch generates a `switch` statement for a SWITCH node, with one clause per case output
+// and a default clause when its output does more than flow straight on. Like a BRANCH, the edge
+// into the node where the clauses rejoin is returned so the caller can continue from it.
+func generateSwitch(state *transpilationState, node *axon.Node, ctx blockContext) (string, *axon.ExecEdge, error) {
+	// The bound value of a type switch only exists inside the clauses.
+	saved := state.enterScope()
+	defer state.exitScope(saved)
+
+	var header string
+	switch kind := node.Config["kind"]; kind {
+	case "", "value":
+		// switch x { case 1, 2: ... }, or a bare `switch` whose cases are bool expressions
+		switch len(node.Inputs) {
+		case 0:
+			header = "switch"
+		case 1:
+			tag, err := findSourceVar(state, node.Id, node.Inputs[0].Name)
+			if err != nil {
+				return "", nil, fmt.Errorf("could not resolve value for switch node %s: %w", node.Id, err)
+			}
+			header = fmt.Sprintf("switch %s", tag)
+		default:
+			return "", nil, fmt.Errorf("switch node %s can have at most one input, found %d", node.Id, len(node.Inputs))
+		}
+	case "type":
+		// switch v := x.(type) { case int: ... }
+		if len(node.Inputs) != 1 {
+			return "", nil, fmt.Errorf("type switch node %s must have exactly one input", node.Id)
+		}
+		subject, err := findSourceOperand(state, node.Id, node.Inputs[0].Name, precPrimary)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not resolve value for type switch node %s: %w", node.Id, err)
+		}
+		header = fmt.Sprintf("switch %s.(type)", subject)
+		if len(node.Outputs) > 0 && isOutputUsed(state.graph, node.Id, node.Outputs[0].Name) {
+			bound := state.nameOfOutput(node, node.Outputs[0])
+			state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = bound
+			header = fmt.Sprintf("switch %s := %s.(type)", bound, subject)
+		}
+	default:
+		return "", nil, fmt.Errorf("switch node %s has unknown kind '%s' (expected value or type)", node.Id, kind)
+	}
+
+	pins := node.ExecOutputPins()
+	if len(node.SwitchCases()) == 0 {
+		return "", nil, fmt.Errorf("switch node %s has no cases; add '%s<output>' config entries", node.Id, axon.SwitchCasePrefix)
+	}
+	var targets []string
+	for _, pin := range pins {
+		targets = append(targets, nextExecEdge(state, node.Id, pin).GetToNodeId())
+	}
+	joinID := findJoinNode(state, targets)
+	armCtx := blockContext{stopID: joinID}
+	if joinID == "" {
+		armCtx = ctx
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\t%s {\n", header))
+	state.breakables = append(state.breakables, node)
+	defer func() { state.breakables = state.breakables[:len(state.breakables)-1] }()
+	for _, pin := range pins {
+		code, err := generateNestedBlock(state, nextExecEdge(state, node.Id, pin), armCtx)
+		if err != nil {
+			return "", nil, err
+		}
+		if pin == "default" {
+			if code != "" {
+				sb.WriteString(portMarker(node, pin, "\t"))
+				sb.WriteString(fmt.Sprintf("\tdefault:\n%s", code))
+			}
+			continue
+		}
+		expr, ok := node.Config[axon.SwitchCasePrefix+pin]
+		if !ok {
+			return "", nil, fmt.Errorf("switch node %s has no '%s%s' config entry for its '%s' output", node.Id, axon.SwitchCasePrefix, pin, pin)
+		}
+		sb.WriteString(portMarker(node, pin, "\t"))
+		sb.WriteString(fmt.Sprintf("\tcase %s:\n%s", expr, code))
+	}
+	sb.WriteString(nodeMarker(node, "\t"))
+	sb.WriteString("\t}\n")
+
+	if joinID == "" {
+		return sb.String(), nil, nil
+	}
+	return sb.String(), &axon.ExecEdge{FromNodeId: node.Id, ToNodeId: joinID}, nil
+}
+
+// generateErrorCheck emits an `if err != nil` check that routes to the node's "failed" and "ok" outputs.
+// When the failed path never rejoins the ok path it becomes an early exit and the ok path carries on
+// after the if statement. Inside a function an unconnected "failed" output returns the error.
+func generateErrorCheck(state *transpilationState, node *axon.Node) (string, *axon.ExecEdge, error) {
+	if len(node.Inputs) != 1 || node.Inputs[0].TypeName != "error" {
+		return "", nil, fmt.Errorf("error check node %s must have exactly one input of type error", node.Id)
+	}
+	errVar, err := findSourceVar(state, node.Id, node.Inputs[0].Name)
+	if err != nil {
+		return "", nil, fmt.Errorf("could not resolve error for error check node %s: %w", node.Id, err)
+	}
+	wrapped := wrapError(node, errVar)
+
+	okEdge := nextExecEdge(state, node.Id, "ok")
+	failedEdge := nextExecEdge(state, node.Id, "failed")
+	joinID := findJoinNode(state, []string{okEdge.GetToNodeId(), failedEdge.GetToNodeId()})
+
+	var failedCode string
+	if failedEdge == nil {
+		failedCode, err = generateErrorReturn(state, node, wrapped)
+	} else {
+		failedCode, err = generateFailedBlock(state, node, failedEdge, errVar, wrapped, blockContext{stopID: joinID})
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if joinID == "" {
+		// The failed path leaves the block on its own, so the ok path simply follows the check.
+		return fmt.Sprintf("\tif %s != nil {\n%s\t}\n", errVar, failedCode), okEdge, nil
+	}
+
+	okCode, err := generateNestedBlock(state, okEdge, blockContext{stopID: joinID})
+	if err != nil {
+		return "", nil, err
+	}
+	var sb strings.Builder
+	switch {
+	case failedCode == "" && okCode == "":
+	case okCode == "":
+		sb.WriteString(fmt.Sprintf("\tif %s != nil {\n%s\t}\n", errVar, failedCode))
+	case failedCode == "":
+		sb.WriteString(fmt.Sprintf("\tif %s == nil {\n%s\t}\n", errVar, okCode))
+	default:
+		sb.WriteString(fmt.Sprintf("\tif %s != nil {\n%s", errVar, failedCode))
+		sb.WriteString(portMarker(node, "ok", "\t"))
+		sb.WriteString(fmt.Sprintf("\t} else {\n%s", okCode))
+		sb.WriteString(nodeMarker(node, "\t"))
+		sb.WriteString("\t}\n")
+	}
+	return sb.String(), &axon.ExecEdge{FromNodeId: node.Id, ToNodeId: joinID}, nil
+}
+
+// generateFailedBlock emits the "failed" path of an ERROR_CHECK. The node's output, if any, carries the
+// (possibly wrapped) error into that path only.
+func generateFailedBlock(state *transpilationState, node *axon.Node, edge *axon.ExecEdge, errVar, wrapped string, ctx blockContext) (string, error) {
+	saved := state.enterScope()
+	defer state.exitScope(saved)
+
+	var prelude string
+	if len(node.Outputs) > 0 && isOutputUsed(state.graph, node.Id, node.Outputs[0].Name) {
+		name := errVar
+		if wrapped != errVar {
+			name = state.nameOfOutput(node, node.Outputs[0])
+			prelude = fmt.Sprintf("\t%s := %s\n", name, wrapped)
+		}
+		state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = name
+	}
+	code, err := generateBlock(state, edge, ctx)
+	if err != nil {
+		return "", err
+	}
+	return indentBlock(portMarker(node, "failed", "\t") + prelude + code), nil
+}
+
+// generateErrorReturn emits the return statement for an ERROR_CHECK whose "failed" output is not
+// connected: every other result is its zero value and the last one is the error.
+func generateErrorReturn(state *transpilationState, node *axon.Node, wrapped string) (string, error) {
+	n := len(state.returnTypes)
+	if n == 0 || state.returnTypes[n-1] != "error" {
+		return "", errorAt(CodeUnreturnableError, node, "failed", "error check node '%s' has no 'failed' execution edge, but its function does not return an error as its last result", node.Label)
+	}
+	var results []string
+	for _, typeName := range state.returnTypes[:n-1] {
+		results = append(results, zeroValue(state, typeName))
+	}
+	results = append(results, wrapped)
+	return portMarker(node, "failed", "\t\t") + fmt.Sprintf("\t\treturn %s\n", strings.Join(results, ", ")), nil
+}
+
+// wrapError returns the expression for the error an ERROR_CHECK passes on. With a "wrap" config
+// entry the error is wrapped with that message, as in fmt.Errorf("reading config: %w", err).
+func wrapError(node *axon.Node, errVar string) string {
+	msg, ok := node.Config["wrap"]
+	if !ok || msg == "" {
+		return errVar
+	}
+	format := strings.ReplaceAll(msg, "%", "%%") + ": %w"
+	return fmt.Sprintf("fmt.Errorf(%s, %s)", strconv.Quote(format), errVar)
+}
+
+// zeroValue returns a Go expression for the zero value of the named type.
+func zeroValue(state *transpilationState, typeName string) string {
+	switch typeName {
+	case "string":
+		return `""`
+	case "bool":
+		return "false"
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"byte", "rune", "float32", "float64", "complex64", "complex128":
+		return "0"
+	case "error", "any":
+		return "nil"
+	}
+	for _, prefix := range []string{"*", "[]", "map[", "chan ", "<-chan ", "func(", "interface{"} {
+		if strings.HasPrefix(typeName, prefix) {
+			return "nil"
+		}
+	}
+	for _, node := range state.graph.Nodes {
+		if node.Type == axon.NodeType_STRUCT_DEF && node.Label == typeName {
+			return typeName + "{}"
+		}
+	}
+	// Works for any type, including named types from other packages.
+	return fmt.Sprintf("*new(%s)", typeName)
+}
+
+// generateGo emits a GO node: either `go f(args)` for its impl_reference, or a closure running its
+// "body" flow. The node's inputs are evaluated when the goroutine starts and reach the body through
+// the matching outputs, so later changes to the originals are not seen by the goroutine.
+func generateGo(state *transpilationState, node *axon.Node) (string, *axon.ExecEdge, error) {
+	group, err := goroutineGroup(state, node)
+	if err != nil {
+		return "", nil, err
+	}
+	args, err := resolveArgs(state, node)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var params, names []string
+	var body string
+	if node.ImplReference != "" {
+		if len(node.Outputs) > 0 {
+			return "", nil, fmt.Errorf("go node '%s' calls %s, so it cannot have outputs", node.Label, node.ImplReference)
+		}
+		if group == nil {
+			return fmt.Sprintf("\tgo %s\n", formatCall(state, node, args)), nextExecEdge(state, node.Id, axon.DefaultExecOutput), nil
+		}
+		for _, inputPort := range node.Inputs {
+			name := state.nameOf(fmt.Sprintf("%s.%s", node.Id, inputPort.Name))
+			params = append(params, fmt.Sprintf("%s %s", name, inputPort.TypeName))
+			names = append(names, name)
+		}
+		call := formatCall(state, node, names)
+		if groupKind(group) == "errgroup" {
+			call = "return " + call // The function must return an error for the group to collect.
+		}
+		body = fmt.Sprintf("\t%s\n", call)
+	} else {
+		var returnTypes []string
+		if group != nil && groupKind(group) == "errgroup" {
+			returnTypes = []string{"error"}
+		}
+		saved := state.enterScope()
+		params, names, err = bindClosureParams(state, node)
+		if err == nil {
+			body, err = generateClosureBody(state, nextExecEdge(state, node.Id, "body"), returnTypes)
+		}
+		state.exitScope(saved)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	var sb strings.Builder
+	switch {
+	case group == nil:
+		sb.WriteString(fmt.Sprintf("\tgo func(%s) {\n%s\t}(%s)\n", strings.Join(params, ", "), indentBlock(body), strings.Join(args, ", ")))
+	case groupKind(group) == "errgroup":
+		// Group.Go takes a func() error, so the inputs are copied into a block of their own instead.
+		start := fmt.Sprintf("\t%s.Go(func() error {\n%s\t})\n", state.nameOf(group.Id), indentBlock(body))
+		if len(names) == 0 {
+			sb.WriteString(start)
+		} else {
+			copies := fmt.Sprintf("\t%s := %s\n", strings.Join(names, ", "), strings.Join(args, ", "))
+			sb.WriteString(fmt.Sprintf("\t{\n%s\t}\n", indentBlock(copies+start)))
+		}
+	default:
+		wg := state.nameOf(group.Id)
+		body = fmt.Sprintf("\tdefer %s.Done()\n%s", wg, body)
+		sb.WriteString(fmt.Sprintf("\t%s.Add(1)\n", wg))
+		sb.WriteString(fmt.Sprintf("\tgo func(%s) {\n%s\t}(%s)\n", strings.Join(params, ", "), indentBlock(body), strings.Join(args, ", ")))
+	}
+	return sb.String(), nextExecEdge(state, node.Id, axon.DefaultExecOutput), nil
+}
+
+// generateDefer emits a DEFER node: either `defer f(args)` for its impl_reference, or a deferred
+// closure running its "body" flow. As in Go, the inputs are evaluated when the defer statement runs.
+func generateDefer(state *transpilationState, node *axon.Node) (string, *axon.ExecEdge, error) {
+	args, err := resolveArgs(state, node)
+	if err != nil {
+		return "", nil, err
+	}
+	next := nextExecEdge(state, node.Id, axon.DefaultExecOutput)
+	if node.ImplReference != "" {
+		if len(node.Outputs) > 0 {
+			return "", nil, fmt.Errorf("defer node '%s' calls %s, so it cannot have outputs", node.Label, node.ImplReference)
+		}
+		return fmt.Sprintf("\tdefer %s\n", formatCall(state, node, args)), next, nil
+	}
+
+	saved := state.enterScope()
+	params, _, err := bindClosureParams(state, node)
+	var body string
+	if err == nil {
+		body, err = generateClosureBody(state, nextExecEdge(state, node.Id, "body"), nil)
+	}
+	state.exitScope(saved)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("\tdefer func(%s) {\n%s\t}(%s)\n", strings.Join(params, ", "), indentBlock(body), strings.Join(args, ", ")), next, nil
+}
+
+// generateRecover emits a deferred closure that recovers from a panic anywhere after the RECOVER
+// node in its function. The "handler" flow runs with the recovered value on the node's output.
+func generateRecover(state *transpilationState, node *axon.Node) (string, *axon.ExecEdge, error) {
+	if len(node.Outputs) > 1 {
+		return "", nil, fmt.Errorf("recover node %s can have at most one output (the recovered value), found %d", node.Id, len(node.Outputs))
+	}
+	next := nextExecEdge(state, node.Id, axon.DefaultExecOutput)
+	handlerEdge := nextExecEdge(state, node.Id, "handler")
+	if handlerEdge == nil {
+		// Without a handler the panic is simply swallowed.
+		return "\tdefer func() {\n\t\trecover()\n\t}()\n", next, nil
+	}
+
+	saved := state.enterScope()
+	defer state.exitScope(saved)
+	check := "recover() != nil"
+	if len(node.Outputs) == 1 && isOutputUsed(state.graph, node.Id, node.Outputs[0].Name) && !isPortConnectedToIgnore(state, node.Id, node.Outputs[0].Name) {
+		name := state.nameOfOutput(node, node.Outputs[0])
+		state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = name
+		check = fmt.Sprintf("%s := recover(); %s != nil", name, name)
+	}
+	handler, err := generateClosureBody(state, handlerEdge, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("\tdefer func() {\n\t\tif %s {\n%s\t\t}\n\t}()\n", check, indentBlock(indentBlock(handler))), next, nil
+}
+
+// bindClosureParams turns the outputs of a GO or DEFER node into the parameters of the function
+// literal running its body, which receives the node's inputs as arguments.
+func bindClosureParams(state *transpilationState, node *axon.Node) (params, names []string, err error) {
+	if len(node.Outputs) != len(node.Inputs) {
+		return nil, nil, fmt.Errorf("%s node '%s' must have one output for each input it passes to its body", node.Type, node.Label)
+	}
+	for i, outputPort := range node.Outputs {
+		name := state.nameOfOutput(node, outputPort)
+		params = append(params, fmt.Sprintf("%s %s", name, node.Inputs[i].TypeName))
+		names = append(names, name)
+		state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, outputPort.Name)] = name
+	}
+	return params, names, nil
+}
+
+// generateClosureBody emits a flow that runs inside a function literal, such as the body of a GO
+// node. Loops around the literal are out of reach, and returns use the literal's own result types.
+func generateClosureBody(state *transpilationState, edge *axon.ExecEdge, returnTypes []string) (string, error) {
+	loops, breakables, outerReturnTypes := state.loops, state.breakables, state.returnTypes
+	state.loops, state.breakables, state.returnTypes = nil, nil, returnTypes
+	defer func() { state.loops, state.breakables, state.returnTypes = loops, breakables, outerReturnTypes }()
+
+	body, err := generateBlock(state, edge, blockContext{tail: true})
+	if err != nil {
+		return "", err
+	}
+	if len(returnTypes) > 0 && !endsWithReturn(body) {
+		body += generateBareReturn(state)
+	}
+	return body, nil
+}
+
+// generateBareReturn emits a return that leaves the current function without a RETURN node,
+// returning zero values for any results it has.
+func generateBareReturn(state *transpilationState) string {
+	if len(state.returnTypes) == 0 {
+		return "\treturn\n"
+	}
+	var results []string
+	for _, typeName := range state.returnTypes {
+		results = append(results, zeroValue(state, typeName))
+	}
+	return fmt.Sprintf("\treturn %s\n", strings.Join(results, ", "))
+}
+
+// endsWithReturn reports whether the last statement of a generated block is a return.
+func endsWithReturn(code string) bool {
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	return last == "return" || strings.HasPrefix(last, "return ")
+}
+
+// goroutineGroup returns the WAIT node a GO node's config "group" names, or nil if it has none.
+func goroutineGroup(state *transpilationState, node *axon.Node) (*axon.Node, error) {
+	id := node.Config["group"]
+	if id == "" {
+		return nil, nil
+	}
+	wait, ok := state.nodeMap[id]
+	if !ok || wait.Type != axon.NodeType_WAIT {
+		return nil, fmt.Errorf("go node '%s' names group '%s', which is not a WAIT node", node.Label, id)
+	}
+	if !slices.Contains(state.groups, wait) {
+		state.groups = append(state.groups, wait)
+	}
+	return wait, nil
+}
+
+// groupKind returns whether a WAIT node joins a sync.WaitGroup or an errgroup.Group.
+func groupKind(wait *axon.Node) string {
+	if kind := wait.Config["kind"]; kind != "" {
+		return kind
+	}
+	return "waitgroup"
+}
+
+// generateGroupDecl declares the group variable a WAIT node waits on. It goes at the top of the
+// function, so goroutines anywhere in the function can join it.
+func generateGroupDecl(state *transpilationState, wait *axon.Node) (string, error) {
+	switch kind := groupKind(wait); kind {
+	case "waitgroup":
+		return fmt.Sprintf("\tvar %s sync.WaitGroup\n", state.nameOf(wait.Id)), nil
+	case "errgroup":
+		return fmt.Sprintf("\tvar %s errgroup.Group\n", state.nameOf(wait.Id)), nil
+	default:
+		return "", fmt.Errorf("wait node %s has unknown kind '%s' (expected waitgroup or errgroup)", wait.Id, kind)
+	}
+}
+
+// generateWait emits the call that blocks until every goroutine in the WAIT node's group is done.
+// An errgroup's first error comes out of the node's single error output.
+func generateWait(state *transpilationState, node *axon.Node) (string, error) {
+	if !slices.Contains(state.groups, node) {
+		state.groups = append(state.groups, node)
+	}
+	state.waited[node.Id] = true
+	group := state.nameOf(node.Id)
+
+	if groupKind(node) != "errgroup" {
+		if len(node.Outputs) > 0 {
+			return "", fmt.Errorf("wait node '%s' waits on a sync.WaitGroup, so it cannot have outputs", node.Label)
+		}
+		return fmt.Sprintf("\t%s.Wait()\n", group), nil
+	}
+	if len(node.Outputs) != 1 || node.Outputs[0].TypeName != "error" {
+		return "", fmt.Errorf("wait node '%s' waits on an errgroup, so it must have exactly one output of type error", node.Label)
+	}
+	outputPort := node.Outputs[0]
+	if !isOutputUsed(state.graph, node.Id, outputPort.Name) {
+		return "", errorAt(CodeUnhandledError, node, outputPort.Name, "error output '%s' of wait node '%s' is not handled; connect it to an ERROR_CHECK node or explicitly ignore it", outputPort.Name, node.Label)
+	}
+	if isPortConnectedToIgnore(state, node.Id, outputPort.Name) {
+		return fmt.Sprintf("\t_ = %s.Wait()\n", group), nil
+	}
+	errVar := state.nameOfOutput(node, outputPort)
+	state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, outputPort.Name)] = errVar
+	return fmt.Sprintf("\t%s := %s.Wait()\n", errVar, group), nil
+}
+
+// generateChanMake emits `ch := make(chan T)`, buffered when config "size" is set.
+func generateChanMake(state *transpilationState, node *axon.Node) (string, error) {
+	if len(node.Outputs) != 1 || !strings.HasPrefix(node.Outputs[0].TypeName, "chan") {
+		return "", fmt.Errorf("channel node '%s' must have exactly one output of a chan type", node.Label)
+	}
+	varName := state.nameOfOutput(node, node.Outputs[0])
+	state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = varName
+	if size := node.Config["size"]; size != "" {
+		return fmt.Sprintf("\t%s := make(%s, %s)\n", varName, node.Outputs[0].TypeName, size), nil
+	}
+	return fmt.Sprintf("\t%s := make(%s)\n", varName, node.Outputs[0].TypeName), nil
+}
+
+// generateChanSend emits `ch <- v` from the node's "channel" and "value" inputs.
+func generateChanSend(state *transpilationState, node *axon.Node) (string, error) {
+	ch, err := findSourceVar(state, node.Id, "channel")
+	if err != nil {
+		return "", fmt.Errorf("could not resolve channel for send node %s: %w", node.Id, err)
+	}
+	value, err := findSourceVar(state, node.Id, "value")
+	if err != nil {
+		return "", fmt.Errorf("could not resolve value for send node %s: %w", node.Id, err)
+	}
+	return fmt.Sprintf("\t%s <- %s\n", ch, value), nil
+}
+
+// generateChanReceive emits `v := <-ch`, or `v, ok := <-ch` when the node has a second output.
+// Outputs nothing reads are discarded.
+func generateChanReceive(state *transpilationState, node *axon.Node) (string, error) {
+	if len(node.Inputs) != 1 {
+		return "", fmt.Errorf("receive node %s must have exactly one channel input, found %d", node.Id, len(node.Inputs))
+	}
+	if len(node.Outputs) > 2 {
+		return "", fmt.Errorf("receive node %s can have at most two outputs (value and ok), found %d", node.Id, len(node.Outputs))
+	}
+	ch, err := findSourceOperand(state, node.Id, node.Inputs[0].Name, precUnary)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve channel for receive node %s: %w", node.Id, err)
+	}
+
+	var outputVars []string
+	used := false
+	for _, outputPort := range node.Outputs {
+		if !isOutputUsed(state.graph, node.Id, outputPort.Name) || isPortConnectedToIgnore(state, node.Id, outputPort.Name) {
+			outputVars = append(outputVars, "_")
+			continue
+		}
+		varName := state.nameOfOutput(node, outputPort)
+		outputVars = append(outputVars, varName)
+		state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, outputPort.Name)] = varName
+		used = true
+	}
+	if !used {
+		return fmt.Sprintf("\t<-%s\n", ch), nil
+	}
+	return fmt.Sprintf("\t%s := <-%s\n", strings.Join(outputVars, ", "), ch), nil
+}
+
+// generateChanClose emits `close(ch)`.
+func generateChanClose(state *transpilationState, node *axon.Node) (string, error) {
+	if len(node.Inputs) != 1 {
+		return "", fmt.Errorf("close node %s must have exactly one channel input, found %d", node.Id, len(node.Inputs))
+	}
+	ch, err := findSourceVar(state, node.Id, node.Inputs[0].Name)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve channel for close node %s: %w", node.Id, err)
+	}
+	return fmt.Sprintf("\tclose(%s)\n", ch), nil
+}
+
+// generateSelect emits a select statement with one clause per "case:<output>" config entry.
+// An entry is either "receive <input>" or "send <input> <value input>", naming the node's inputs.
+// A receive case binds the value to the node's output named after the case, and whether the
+// channel is open to the output "<case>_ok", if they exist and are used.
+func generateSelect(state *transpilationState, node *axon.Node, ctx blockContext) (string, *axon.ExecEdge, error) {
+	pins := node.ExecOutputPins()
+	if len(node.SwitchCases()) == 0 {
+		return "", nil, fmt.Errorf("select node %s has no cases; add '%s<output>' config entries", node.Id, axon.SwitchCasePrefix)
+	}
+	var targets []string
+	for _, pin := range pins {
+		targets = append(targets, nextExecEdge(state, node.Id, pin).GetToNodeId())
+	}
+	joinID := findJoinNode(state, targets)
+	armCtx := blockContext{stopID: joinID}
+	if joinID == "" {
+		armCtx = ctx
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\tselect {\n")
+	state.breakables = append(state.breakables, node)
+	defer func() { state.breakables = state.breakables[:len(state.breakables)-1] }()
+	for _, pin := range pins {
+		// Values received by a case only exist inside its clause.
+		saved := state.enterScope()
+		clause := "default"
+		if pin != "default" {
+			var err error
+			if clause, err = generateSelectCase(state, node, pin); err != nil {
+				state.exitScope(saved)
+				return "", nil, err
+			}
+		}
+		code, err := generateNestedBlock(state, nextExecEdge(state, node.Id, pin), armCtx)
+		state.exitScope(saved)
+		if err != nil {
+			return "", nil, err
+		}
+		sb.WriteString(portMarker(node, pin, "\t"))
+		sb.WriteString(fmt.Sprintf("\t%s:\n%s", clause, code))
+	}
+	sb.WriteString(nodeMarker(node, "\t"))
+	sb.WriteString("\t}\n")
+
+	if joinID == "" {
+		return sb.String(), nil, nil
+	}
+	return sb.String(), &axon.ExecEdge{FromNodeId: node.Id, ToNodeId: joinID}, nil
+}
+
+// generateSelectCase returns the `case ...` line for one of a SELECT node's cases.
+func generateSelectCase(state *transpilationState, node *axon.Node, name string) (string, error) {
+	fields := strings.Fields(node.Config[axon.SwitchCasePrefix+name])
+	operand := func(port string, minPrec int) (string, error) {
+		v, err := findSourceOperand(state, node.Id, port, minPrec)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve input '%s' for case '%s' of select node %s: %w", port, name, node.Id, err)
+		}
+		return v, nil
+	}
+
+	switch {
+	case len(fields) == 2 && fields[0] == "receive":
+		ch, err := operand(fields[1], precUnary)
+		if err != nil {
+			return "", err
+		}
+		value, ok := "_", "_"
+		for _, outputPort := range node.Outputs {
+			if !isOutputUsed(state.graph, node.Id, outputPort.Name) || isPortConnectedToIgnore(state, node.Id, outputPort.Name) {
+				continue
+			}
+			varName := state.nameOfOutput(node, outputPort)
+			switch outputPort.Name {
+			case name:
+				value = varName
+			case name + "_ok":
+				ok = varName
+			default:
+				continue
+			}
+			state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, outputPort.Name)] = varName
+		}
+		switch {
+		case ok != "_":
+			return fmt.Sprintf("case %s, %s := <-%s", value, ok, ch), nil
+		case value != "_":
+			return fmt.Sprintf("case %s := <-%s", value, ch), nil
+		default:
+			return fmt.Sprintf("case <-%s", ch), nil
+		}
+	case len(fields) == 3 && fields[0] == "send":
+		ch, err := operand(fields[1], precUnary)
+		if err != nil {
+			return "", err
+		}
+		value, err := operand(fields[2], 0)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("case %s <- %s", ch, value), nil
+	default:
+		return "", fmt.Errorf("case '%s' of select node %s must be 'receive <input>' or 'send <input> <value input>'", name, node.Id)
+	}
+}
+
+// generateLoopHeader builds the `for` clause of a LOOP node and registers its outputs as variables.
+func generateLoopHeader(state *transpilationState, node *axon.Node) (string, error) {
+	mode := node.Config["mode"]
+	switch mode {
+	case "count":
+		// for i := 0; i < n; i++
+		if len(node.Inputs) != 1 {
+			return "", fmt.Errorf("counted loop node %s must have exactly one input (the count)", node.Id)
+		}
+		count, err := findSourceVar(state, node.Id, node.Inputs[0].Name)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve count for loop node %s: %w", node.Id, err)
+		}
+		index := "i"
+		if len(node.Outputs) > 0 {
+			index = state.nameOfOutput(node, node.Outputs[0])
+			state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = index
+		}
+		return fmt.Sprintf("for %s := 0; %s < %s; %s++", index, index, count, index), nil
+
+	case "while":
+		// for cond, or a bare `for` that runs until the body breaks out
+		if len(node.Inputs) == 0 {
+			return "for", nil
+		}
+		cond, err := findSourceVar(state, node.Id, node.Inputs[0].Name)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve condition for loop node %s: %w", node.Id, err)
+		}
+		return fmt.Sprintf("for %s", cond), nil
+
+	case "range":
+		// for k, v := range x. Channels yield a single value; everything else yields a key (or index) then a value.
+		if len(node.Inputs) != 1 {
+			return "", fmt.Errorf("range loop node %s must have exactly one input (the collection)", node.Id)
+		}
+		collection, err := findSourceVar(state, node.Id, node.Inputs[0].Name)
+		if err != nil {
+			return "", fmt.Errorf("could not resolve collection for loop node %s: %w", node.Id, err)
+		}
+		maxVars := 2
+		if typeName := strings.TrimSpace(node.Inputs[0].TypeName); strings.HasPrefix(typeName, "chan") || strings.HasPrefix(typeName, "<-chan") {
+			maxVars = 1
+		}
+		if len(node.Outputs) > maxVars {
+			return "", fmt.Errorf("range loop node %s over %s can have at most %d outputs", node.Id, node.Inputs[0].TypeName, maxVars)
+		}
+
+		var vars []string
+		for _, port := range node.Outputs {
+			if !isOutputUsed(state.graph, node.Id, port.Name) {
+				vars = append(vars, "_")
+				continue
+			}
+			varName := state.nameOfOutput(node, port)
+			vars = append(vars, varName)
+			state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, port.Name)] = varName
+		}
+		for len(vars) > 0 && vars[len(vars)-1] == "_" {
+			vars = vars[:len(vars)-1]
+		}
+		if len(vars) == 0 {
+			return fmt.Sprintf("for range %s", collection), nil
+		}
+		return fmt.Sprintf("for %s := range %s", strings.Join(vars, ", "), collection), nil
+
+	default:
+		return "", fmt.Errorf("loop node %s has unknown mode '%s' (expected count, while or range)", node.Id, mode)
+	}
+}
+
+// generateLoopJump generates the `continue` or `break` for an execution edge that leads back into an
+// enclosing LOOP. The loop's label is used whenever a plain jump would target a different statement
+// (an inner loop, or a switch for `break`); a plain continue at the very end of the body is implied.
+func generateLoopJump(state *transpilationState, loop *axon.Node, edge *axon.ExecEdge, ctx blockContext) string {
+	isBreak := edge.TargetPin() == "break"
+	innermost := state.loops[len(state.loops)-1]
+	if isBreak {
+		innermost = state.breakables[len(state.breakables)-1]
+	}
+
+	label := ""
+	if loop != innermost {
+		label = " " + state.nameOf(loopLabelKey(loop))
+		state.labelledLoops[loop.Id] = true
+	}
+	if isBreak {
+		return fmt.Sprintf("\tbreak%s\n", label)
+	}
+	if label == "" && ctx.loopTail {
+		return ""
+	}
+	return fmt.Sprintf("\tcontinue%s\n", label)
+}
+
 // generateReturn generates a return statement.
 func generateReturn(state *transpilationState, node *axon.Node) (string, error) {
 	if len(node.Inputs) == 0 {
@@ -212,6 +1202,11 @@ func generateReturn(state *transpilationState, node *axon.Node) (string, error)
 	}
 	var returnVars []string
 	for _, inputPort := range node.Inputs {
+		if !isInputConnected(state.graph, node.Id, inputPort.Name) {
+			// Results that are not wired up are returned as their zero value.
+			returnVars = append(returnVars, zeroValue(state, inputPort.TypeName))
+			continue
+		}
 		varName, err := findSourceVar(state, node.Id, inputPort.Name)
 		if err != nil {
 			return "", fmt.Errorf("could not find source for RETURN node input '%s': %w", inputPort.Name, err)
@@ -240,4 +1235,4 @@ func generateCommentBlock(state *transpilationState, node *axon.Node) string {
 		}
 	}
 	return sb.String()
-}
\ No newline at end of file
+}
@@ -0,0 +1,300 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// generate compiles a graph that must compile cleanly, and returns its main.go, which must also
+// type-check as Go.
+func generate(t *testing.T, src string, opts Options) string {
+	t.Helper()
+	result := compile(t, loadGraph(t, src), opts)
+	if result.Diagnostics.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %v", result.Diagnostics)
+	}
+	code := string(result.Files[0].Content)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, code)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("main", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("generated code does not type-check: %v\n%s", err, code)
+	}
+	return code
+}
+
+// expectCode fails the test unless code contains each of the snippets given.
+func expectCode(t *testing.T, code string, snippets ...string) {
+	t.Helper()
+	for _, s := range snippets {
+		if !strings.Contains(code, s) {
+			t.Errorf("generated code has no %q:\n%s", s, code)
+		}
+	}
+}
+
+// loopLabelGraph has two outer loops labelled Outer, each left from an inner loop, and a loop
+// labelled with a keyword that is continued from the loop inside it.
+const loopLabelGraph = `{
+  "id": "labels", "name": "labels", "imports": ["fmt"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "a", "type": "LOOP", "label": "Outer", "config": {"mode": "while"}},
+    {"id": "b", "type": "LOOP", "label": "Inner", "config": {"mode": "while"}},
+    {"id": "pa", "type": "FUNCTION", "label": "pa", "impl_reference": "fmt.Println"},
+    {"id": "c", "type": "LOOP", "label": "Outer", "config": {"mode": "while"}},
+    {"id": "d", "type": "LOOP", "label": "for", "config": {"mode": "while"}},
+    {"id": "e", "type": "LOOP", "label": "", "config": {"mode": "while"}},
+    {"id": "pb", "type": "FUNCTION", "label": "pb", "impl_reference": "fmt.Println"},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "a"},
+    {"from_node_id": "a", "from_port": "body", "to_node_id": "b"},
+    {"from_node_id": "b", "from_port": "body", "to_node_id": "pa"},
+    {"from_node_id": "pa", "to_node_id": "a", "to_port": "break"},
+    {"from_node_id": "b", "from_port": "completed", "to_node_id": "a", "to_port": "continue"},
+    {"from_node_id": "a", "from_port": "completed", "to_node_id": "c"},
+    {"from_node_id": "c", "from_port": "body", "to_node_id": "d"},
+    {"from_node_id": "d", "from_port": "body", "to_node_id": "e"},
+    {"from_node_id": "e", "from_port": "body", "to_node_id": "pb"},
+    {"from_node_id": "pb", "to_node_id": "d", "to_port": "continue"},
+    {"from_node_id": "e", "from_port": "completed", "to_node_id": "c", "to_port": "break"},
+    {"from_node_id": "d", "from_port": "completed", "to_node_id": "c", "to_port": "continue"},
+    {"from_node_id": "c", "from_port": "completed", "to_node_id": "end"}
+  ]
+}`
+
+func TestLoopLabels(t *testing.T) {
+	code := generate(t, loopLabelGraph, Options{})
+	expectCode(t, code, "Outer:\n", "break Outer\n", "OuterC:\n", "break OuterC\n", "for_:\n", "continue for_\n")
+}
+
+// branchGraph prints a message when there are arguments, and carries on to the end either way.
+const branchGraph = `{
+  "id": "branch", "name": "branch", "imports": ["fmt", "os"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "args", "type": "FUNCTION", "label": "args", "impl_reference": "len", "inputs": [{"name": "v", "type_name": "[]string"}], "outputs": [{"name": "out", "type_name": "int"}]},
+    {"id": "argv", "type": "VAR_DECLARE", "label": "argv", "config": {"type": "[]string", "value": "os.Args"}},
+    {"id": "argvGet", "type": "VAR_GET", "label": "argv", "outputs": [{"name": "out", "type_name": "[]string"}], "config": {"variable": "argv"}},
+    {"id": "one", "type": "CONSTANT", "label": "one", "outputs": [{"name": "out", "type_name": "int"}], "config": {"value": "1"}},
+    {"id": "more", "type": "OPERATOR", "label": "more", "inputs": [{"name": "a", "type_name": "int"}, {"name": "b", "type_name": "int"}], "outputs": [{"name": "out", "type_name": "bool"}], "config": {"op": ">"}},
+    {"id": "check", "type": "BRANCH", "label": "has args", "inputs": [{"name": "condition", "type_name": "bool"}]},
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "int"}]},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "data_edges": [
+    {"from_node_id": "argvGet", "from_port": "out", "to_node_id": "args", "to_port": "v"},
+    {"from_node_id": "args", "from_port": "out", "to_node_id": "more", "to_port": "a"},
+    {"from_node_id": "one", "from_port": "out", "to_node_id": "more", "to_port": "b"},
+    {"from_node_id": "more", "from_port": "out", "to_node_id": "check", "to_port": "condition"},
+    {"from_node_id": "args", "from_port": "out", "to_node_id": "print", "to_port": "a"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "argv"},
+    {"from_node_id": "argv", "to_node_id": "args"},
+    {"from_node_id": "args", "to_node_id": "check"},
+    {"from_node_id": "check", "from_port": "true", "to_node_id": "print"},
+    {"from_node_id": "check", "from_port": "false", "to_node_id": "end"},
+    {"from_node_id": "print", "to_node_id": "end"}
+  ]
+}`
+
+func TestBranchWithoutElse(t *testing.T) {
+	code := generate(t, branchGraph, Options{})
+	expectCode(t, code, "\tif args > one {\n\t\tfmt.Println(args)\n\t}\n}\n")
+	if strings.Contains(code, "else") {
+		t.Errorf("a false output leading straight on gave an else:\n%s", code)
+	}
+}
+
+// rangeGraph prints each word of a slice, with the index unused, until one is empty.
+const rangeGraph = `{
+  "id": "range", "name": "range", "imports": ["fmt"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "words", "type": "CONSTANT", "label": "words", "outputs": [{"name": "out", "type_name": "[]string"}], "config": {"value": "[]string{\"a\", \"\", \"b\"}"}},
+    {"id": "each", "type": "LOOP", "label": "each word", "config": {"mode": "range"}, "inputs": [{"name": "items", "type_name": "[]string"}], "outputs": [{"name": "i", "type_name": "int"}, {"name": "word", "type_name": "string"}]},
+    {"id": "empty", "type": "CONSTANT", "label": "empty", "outputs": [{"name": "out", "type_name": "string"}], "config": {"value": "\"\""}},
+    {"id": "isEmpty", "type": "OPERATOR", "label": "isEmpty", "inputs": [{"name": "a", "type_name": "string"}, {"name": "b", "type_name": "string"}], "outputs": [{"name": "out", "type_name": "bool"}], "config": {"op": "=="}},
+    {"id": "check", "type": "BRANCH", "label": "check", "inputs": [{"name": "condition", "type_name": "bool"}]},
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "string"}]},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "data_edges": [
+    {"from_node_id": "words", "from_port": "out", "to_node_id": "each", "to_port": "items"},
+    {"from_node_id": "each", "from_port": "word", "to_node_id": "isEmpty", "to_port": "a"},
+    {"from_node_id": "empty", "from_port": "out", "to_node_id": "isEmpty", "to_port": "b"},
+    {"from_node_id": "isEmpty", "from_port": "out", "to_node_id": "check", "to_port": "condition"},
+    {"from_node_id": "each", "from_port": "word", "to_node_id": "print", "to_port": "a"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "each"},
+    {"from_node_id": "each", "from_port": "body", "to_node_id": "check"},
+    {"from_node_id": "check", "from_port": "true", "to_node_id": "each", "to_port": "break"},
+    {"from_node_id": "check", "from_port": "false", "to_node_id": "print"},
+    {"from_node_id": "print", "to_node_id": "each", "to_port": "continue"},
+    {"from_node_id": "each", "from_port": "completed", "to_node_id": "end"}
+  ]
+}`
+
+func TestRangeLoop(t *testing.T) {
+	code := generate(t, rangeGraph, Options{})
+	expectCode(t, code, "\tfor _, word := range words {\n", "\t\t\tbreak\n")
+	if strings.Contains(code, "continue") {
+		t.Errorf("continuing at the end of the body gave a continue statement:\n%s", code)
+	}
+}
+
+// selectGraph receives from one channel or sends on another, whichever is ready first, or does
+// neither if both would block.
+const selectGraph = `{
+  "id": "select", "name": "select", "imports": ["fmt"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "in", "type": "CHAN_MAKE", "label": "in", "outputs": [{"name": "out", "type_name": "chan int"}], "config": {"size": "1"}},
+    {"id": "out", "type": "CHAN_MAKE", "label": "out", "outputs": [{"name": "out", "type_name": "chan int"}]},
+    {"id": "seven", "type": "CONSTANT", "label": "seven", "outputs": [{"name": "out", "type_name": "int"}], "config": {"value": "7"}},
+    {"id": "send", "type": "CHAN_SEND", "label": "send", "inputs": [{"name": "channel", "type_name": "chan int"}, {"name": "value", "type_name": "int"}]},
+    {"id": "sel", "type": "SELECT", "label": "wait",
+     "inputs": [{"name": "in", "type_name": "chan int"}, {"name": "out", "type_name": "chan int"}, {"name": "value", "type_name": "int"}],
+     "outputs": [{"name": "got", "type_name": "int"}, {"name": "got_ok", "type_name": "bool"}],
+     "config": {"case:got": "receive in", "case:put": "send out value", "nonblocking": "true"}},
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "int"}, {"name": "b", "type_name": "bool"}]},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "data_edges": [
+    {"from_node_id": "in", "from_port": "out", "to_node_id": "send", "to_port": "channel"},
+    {"from_node_id": "seven", "from_port": "out", "to_node_id": "send", "to_port": "value"},
+    {"from_node_id": "in", "from_port": "out", "to_node_id": "sel", "to_port": "in"},
+    {"from_node_id": "out", "from_port": "out", "to_node_id": "sel", "to_port": "out"},
+    {"from_node_id": "seven", "from_port": "out", "to_node_id": "sel", "to_port": "value"},
+    {"from_node_id": "sel", "from_port": "got", "to_node_id": "print", "to_port": "a"},
+    {"from_node_id": "sel", "from_port": "got_ok", "to_node_id": "print", "to_port": "b"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "in"},
+    {"from_node_id": "in", "to_node_id": "out"},
+    {"from_node_id": "out", "to_node_id": "send"},
+    {"from_node_id": "send", "to_node_id": "sel"},
+    {"from_node_id": "sel", "from_port": "got", "to_node_id": "print"},
+    {"from_node_id": "sel", "from_port": "put", "to_node_id": "end"},
+    {"from_node_id": "sel", "from_port": "default", "to_node_id": "end"},
+    {"from_node_id": "print", "to_node_id": "end"}
+  ]
+}`
+
+func TestSelect(t *testing.T) {
+	code := generate(t, selectGraph, Options{})
+	expectCode(t, code,
+		"\tin <- seven\n\tselect {\n",
+		"\tcase got, got_ok := <-in:\n\t\tfmt.Println(got, got_ok)\n",
+		"\tcase out <- seven:\n",
+		"\tdefault:\n\t}\n")
+}
+
+// pureGraph computes a value from a function's parameter with operators off the execution flow,
+// and from values of main, where one result is used twice.
+const pureGraph = `{
+  "id": "pure", "name": "pure", "imports": ["fmt"],
+  "nodes": [
+    {"id": "double", "type": "FUNC_DEF", "label": "double", "outputs": [{"name": "n", "type_name": "int"}]},
+    {"id": "two", "type": "CONSTANT", "label": "two", "outputs": [{"name": "out", "type_name": "int"}], "config": {"value": "2"}},
+    {"id": "times", "type": "OPERATOR", "label": "doubled", "inputs": [{"name": "a", "type_name": "int"}, {"name": "b", "type_name": "int"}], "outputs": [{"name": "out", "type_name": "int"}], "config": {"op": "*"}},
+    {"id": "ret", "type": "RETURN", "label": "Return", "inputs": [{"name": "result", "type_name": "int"}]},
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "call", "type": "FUNCTION", "label": "four", "impl_reference": "double", "inputs": [{"name": "n", "type_name": "int"}], "outputs": [{"name": "result", "type_name": "int"}]},
+    {"id": "square", "type": "OPERATOR", "label": "square", "inputs": [{"name": "a", "type_name": "int"}, {"name": "b", "type_name": "int"}], "outputs": [{"name": "out", "type_name": "int"}], "config": {"op": "*"}},
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "int"}, {"name": "b", "type_name": "int"}]},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "data_edges": [
+    {"from_node_id": "double", "from_port": "n", "to_node_id": "times", "to_port": "a"},
+    {"from_node_id": "two", "from_port": "out", "to_node_id": "times", "to_port": "b"},
+    {"from_node_id": "times", "from_port": "out", "to_node_id": "ret", "to_port": "result"},
+    {"from_node_id": "two", "from_port": "out", "to_node_id": "call", "to_port": "n"},
+    {"from_node_id": "call", "from_port": "result", "to_node_id": "square", "to_port": "a"},
+    {"from_node_id": "call", "from_port": "result", "to_node_id": "square", "to_port": "b"},
+    {"from_node_id": "square", "from_port": "out", "to_node_id": "print", "to_port": "a"},
+    {"from_node_id": "square", "from_port": "out", "to_node_id": "print", "to_port": "b"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "double", "to_node_id": "ret"},
+    {"from_node_id": "start", "to_node_id": "call"},
+    {"from_node_id": "call", "to_node_id": "print"},
+    {"from_node_id": "print", "to_node_id": "end"}
+  ]
+}`
+
+func TestPureNodes(t *testing.T) {
+	code := generate(t, pureGraph, Options{})
+	expectCode(t, code, "\treturn n * two\n", "\tfour := double(two)\n\tsquare := four * four\n\tfmt.Println(square, square)\n")
+}
+
+// operatorNode returns an OPERATOR node applying op to inputs a and b, or to in if it is unary.
+func operatorNode(id, op, typeName string, unary bool) string {
+	inputs := `{"name": "a", "type_name": "int"}, {"name": "b", "type_name": "int"}`
+	if unary {
+		inputs = `{"name": "in", "type_name": "int"}`
+	}
+	return fmt.Sprintf(`{"id": %q, "type": "OPERATOR", "label": %q, "inputs": [%s], "outputs": [{"name": "out", "type_name": %q}], "config": {"op": %q}}`, id, id, inputs, typeName, op)
+}
+
+// foldingGraph prints operators nested in ways that need parentheses, and ways that do not.
+var foldingGraph = `{
+  "id": "fold", "name": "fold", "imports": ["fmt"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "a", "type": "CONSTANT", "label": "a", "outputs": [{"name": "out", "type_name": "int"}], "config": {"value": "8"}},
+    {"id": "b", "type": "CONSTANT", "label": "b", "outputs": [{"name": "out", "type_name": "int"}], "config": {"value": "4"}},
+    {"id": "c", "type": "CONSTANT", "label": "c", "outputs": [{"name": "out", "type_name": "int"}], "config": {"value": "2"}},
+    ` + strings.Join([]string{
+	operatorNode("sum", "+", "int", false), operatorNode("product", "*", "int", false),
+	operatorNode("inner", "-", "int", false), operatorNode("outer", "-", "int", false),
+	operatorNode("times", "*", "int", false), operatorNode("plus", "+", "int", false),
+	operatorNode("sum2", "+", "int", false), operatorNode("negated", "-", "int", true),
+}, ",\n    ") + `,
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "int"}, {"name": "b", "type_name": "int"}, {"name": "c", "type_name": "int"}, {"name": "d", "type_name": "int"}]},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "data_edges": [
+    {"from_node_id": "a", "from_port": "out", "to_node_id": "sum", "to_port": "a"},
+    {"from_node_id": "b", "from_port": "out", "to_node_id": "sum", "to_port": "b"},
+    {"from_node_id": "sum", "from_port": "out", "to_node_id": "product", "to_port": "a"},
+    {"from_node_id": "c", "from_port": "out", "to_node_id": "product", "to_port": "b"},
+    {"from_node_id": "b", "from_port": "out", "to_node_id": "inner", "to_port": "a"},
+    {"from_node_id": "c", "from_port": "out", "to_node_id": "inner", "to_port": "b"},
+    {"from_node_id": "a", "from_port": "out", "to_node_id": "outer", "to_port": "a"},
+    {"from_node_id": "inner", "from_port": "out", "to_node_id": "outer", "to_port": "b"},
+    {"from_node_id": "a", "from_port": "out", "to_node_id": "times", "to_port": "a"},
+    {"from_node_id": "b", "from_port": "out", "to_node_id": "times", "to_port": "b"},
+    {"from_node_id": "times", "from_port": "out", "to_node_id": "plus", "to_port": "a"},
+    {"from_node_id": "c", "from_port": "out", "to_node_id": "plus", "to_port": "b"},
+    {"from_node_id": "a", "from_port": "out", "to_node_id": "sum2", "to_port": "a"},
+    {"from_node_id": "b", "from_port": "out", "to_node_id": "sum2", "to_port": "b"},
+    {"from_node_id": "sum2", "from_port": "out", "to_node_id": "negated", "to_port": "in"},
+    {"from_node_id": "product", "from_port": "out", "to_node_id": "print", "to_port": "a"},
+    {"from_node_id": "outer", "from_port": "out", "to_node_id": "print", "to_port": "b"},
+    {"from_node_id": "plus", "from_port": "out", "to_node_id": "print", "to_port": "c"},
+    {"from_node_id": "negated", "from_port": "out", "to_node_id": "print", "to_port": "d"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "print"},
+    {"from_node_id": "print", "to_node_id": "end"}
+  ]
+}`
+
+func TestFoldingPrecedence(t *testing.T) {
+	code := generate(t, foldingGraph, Options{})
+	expectCode(t, code, "\tfmt.Println((a+b)*c, a-(b-c), a*b+c, -(a + b))\n")
+}
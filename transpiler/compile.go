@@ -0,0 +1,102 @@
+package transpiler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// Result is the outcome of compiling a graph.
+type Result struct {
+	// Files are the generated Go source files. There are none if the graph has errors.
+	Files []File
+	// Diagnostics lists every problem found in the graph.
+	Diagnostics Diagnostics
+	// SourceMap relates the lines of the generated file to the nodes they came from. It is nil
+	// if the graph has errors.
+	SourceMap *SourceMap
+	// Timing records how long each phase of the compilation took.
+	Timing Timing
+}
+
+// File is a generated Go source file.
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Timing breaks down the time spent compiling a graph.
+type Timing struct {
+	// Validate covers checking the structure of the execution flows.
+	Validate time.Duration
+	// TypeCheck covers resolving imports, and checking data edges and function signatures
+	// against Go's type system.
+	TypeCheck time.Duration
+	// Generate covers writing the Go code and its source map.
+	Generate time.Duration
+	// Total is the time from the start of the compilation to the end.
+	Total time.Duration
+}
+
+// Compile converts an Axon graph into Go source code. Problems in the graph do not make it fail:
+// they are listed in the result's Diagnostics, and no files are generated if any is an error. The
+// error is only for compilations that could not run, because the options are invalid or ctx was
+// cancelled.
+func Compile(ctx context.Context, graph *axon.Graph, opts Options) (*Result, error) {
+	start := time.Now()
+	result := &Result{Diagnostics: Diagnostics{}}
+	// finish records the end of a phase and reports whether the compilation should stop.
+	phaseStart := start
+	finish := func(phase *time.Duration) error {
+		now := time.Now()
+		*phase, result.Timing.Total = now.Sub(phaseStart), now.Sub(start)
+		phaseStart = now
+		return ctx.Err()
+	}
+
+	state, err := newState(graph, opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	fileName := opts.FileName
+	if fileName == "" {
+		fileName = state.packageName + ".go"
+	}
+
+	// Code can only be generated for well-formed flows, so structural problems stop here.
+	entryPoints, globals, diags := findExecutionScopes(graph)
+	result.Diagnostics = append(result.Diagnostics, diags...)
+	result.Diagnostics = append(result.Diagnostics, checkFuncNames(graph, opts.FuncNaming)...)
+	if err := finish(&result.Timing.Validate); err != nil {
+		return nil, err
+	}
+	if result.Diagnostics.HasErrors() {
+		result.Diagnostics = result.Diagnostics.unique()
+		return result, nil
+	}
+
+	state.imports, diags = resolveImports(graph)
+	result.Diagnostics = append(result.Diagnostics, diags...)
+	result.Diagnostics = append(result.Diagnostics, checkTypes(graph, state.nodeMap, state.imports)...)
+	if err := finish(&result.Timing.TypeCheck); err != nil {
+		return nil, err
+	}
+
+	state.names = assignNames(graph, entryPoints, globals, state.imports, state.funcNames)
+	code, diags := generateFile(state, opts, entryPoints, globals)
+	result.Diagnostics = append(result.Diagnostics, diags...).unique()
+	if !result.Diagnostics.HasErrors() {
+		content, sourceMap := extractSourceMap(code, fileName, opts)
+		result.Files = []File{{Name: fileName, Content: []byte(content)}}
+		if opts.Trace != "" {
+			result.Files = append(result.Files, File{Name: traceFileName, Content: traceRuntime(state.packageName, opts.Trace)})
+		}
+		result.SourceMap = sourceMap
+	}
+	if err := finish(&result.Timing.Generate); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,46 @@
+package transpiler
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	result := compile(t, loadGraph(t, branchGraph), Options{})
+	if len(result.Files) != 1 || result.Files[0].Name != "main.go" {
+		t.Fatalf("got files %v, want main.go", result.Files)
+	}
+	if result.SourceMap == nil || result.SourceMap.File != "main.go" {
+		t.Errorf("got source map %+v for main.go", result.SourceMap)
+	}
+	timing := result.Timing
+	if timing.Total <= 0 || timing.Validate+timing.TypeCheck+timing.Generate > timing.Total {
+		t.Errorf("phases take %v, %v and %v out of %v", timing.Validate, timing.TypeCheck, timing.Generate, timing.Total)
+	}
+
+	lib := compile(t, loadGraph(t, pureGraph), Options{PackageName: "shapes", NoMain: true})
+	if len(lib.Files) != 1 || lib.Files[0].Name != "shapes.go" {
+		t.Errorf("got files %v for package shapes, want shapes.go", lib.Files)
+	}
+}
+
+func TestCompileProblems(t *testing.T) {
+	// Problems in the graph are diagnostics, not errors.
+	result := compile(t, loadGraph(t, brokenGraph), Options{})
+	if !result.Diagnostics.HasErrors() {
+		t.Fatal("broken graph compiled without errors")
+	}
+	if len(result.Files) != 0 || result.SourceMap != nil {
+		t.Errorf("broken graph gave files %v and source map %v", result.Files, result.SourceMap)
+	}
+
+	if _, err := Compile(context.Background(), loadGraph(t, branchGraph), Options{PackageName: "not valid"}); err == nil {
+		t.Error("invalid package name accepted")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Compile(ctx, loadGraph(t, branchGraph), Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled compilation returned %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package transpiler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// Severity ranks a diagnostic. Only errors stop a graph from being transpiled.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// MarshalText writes the severity by name, so JSON output reads "error" rather than 0.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Stable codes identifying each kind of diagnostic. Tools may match on these, so they must not change.
+const (
+	// CodeInvalidFile is for a graph file that cannot be loaded at all.
+	CodeInvalidFile = "invalid-file"
+
+	// Execution flow structure
+	CodeUnknownNode         = "unknown-node"
+	CodeMissingPort         = "missing-port"
+	CodeUnknownExecPin      = "unknown-exec-pin"
+	CodeExecFanOut          = "exec-fan-out"
+	CodeUnconnectedExecPin  = "unconnected-exec-pin"
+	CodeExecCycle           = "exec-cycle"
+	CodeDanglingPath        = "dangling-path"
+	CodeClosureSharedNode   = "closure-shared-node"
+	CodeReturnInClosure     = "return-in-closure"
+	CodeInvalidGlobal       = "invalid-global"
+	CodeInvalidStructDef    = "invalid-struct-def"
+	CodeUnreturnableError   = "unreturnable-error"
+	CodeGroupNotInFunction  = "group-not-in-function"
+	CodePureNodeOutsideFunc = "pure-node-outside-function"
+	CodePureNodeReadsVar    = "pure-node-reads-variable"
+	CodeMainInLibrary       = "main-in-library"
+	CodeInvalidFuncName     = "invalid-func-name"
+	CodeDuplicateFuncName   = "duplicate-func-name"
+
+	// Data flow
+	CodeUnknownPort        = "unknown-port"
+	CodeUnconnectedInput   = "unconnected-input"
+	CodeUnresolvedInput    = "unresolved-input"
+	CodeDataCycle          = "data-cycle"
+	CodeUnhandledError     = "unhandled-error"
+	CodeUnusedOutput       = "unused-output"
+	CodeUnknownVariable    = "unknown-variable"
+	CodeUndeclaredVariable = "undeclared-variable"
+	CodeUnreadVariable     = "unread-variable"
+
+	// Types and signatures
+	CodeUnknownType       = "unknown-type"
+	CodeTypeMismatch      = "type-mismatch"
+	CodeInvalidConversion = "invalid-conversion"
+	CodeOperandMismatch   = "operand-mismatch"
+	CodeUnknownFunction   = "unknown-function"
+	CodeSignatureMismatch = "signature-mismatch"
+	CodeMissingImport     = "missing-import"
+	CodeUnusedImport      = "unused-import"
+	CodePackageNotFound   = "package-not-found"
+
+	// CodeInvalidNode covers a node whose own configuration or ports cannot be transpiled.
+	CodeInvalidNode = "invalid-node"
+	// CodeInvalidGo covers a node whose code is not valid Go, such as a CONSTANT whose value
+	// does not parse.
+	CodeInvalidGo = "invalid-go"
+	// CodePlaceholder is for a PLACEHOLDER node, which stands in for Go code 'axon import' could
+	// not convert.
+	CodePlaceholder = "placeholder"
+)
+
+// Diagnostic is a single problem found in a graph. It names the node, and the port where there is
+// one, so an editor can point at exactly where the problem is.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	NodeID   string   `json:"node_id,omitempty"`
+	Port     string   `json:"port,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (d *Diagnostic) Error() string {
+	return d.Message
+}
+
+// Diagnostics is every problem found in a graph. Transpile returns one as its error, so callers can
+// recover the full list with errors.As.
+type Diagnostics []*Diagnostic
+
+func (ds Diagnostics) Error() string {
+	var lines []string
+	for _, d := range ds {
+		lines = append(lines, d.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasErrors reports whether any of the diagnostics is an error rather than a warning.
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// unique drops repeated diagnostics, keeping the first of each, as a problem with a shared node
+// or port can be found once for every path that reaches it.
+func (ds Diagnostics) unique() Diagnostics {
+	seen := make(map[Diagnostic]bool)
+	out := make(Diagnostics, 0, len(ds))
+	for _, d := range ds {
+		if !seen[*d] {
+			seen[*d] = true
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// errorAt creates an error diagnostic for a node, and for one of its ports if port is not "".
+// node may be nil for problems that do not belong to a single node.
+func errorAt(code string, node *axon.Node, port string, format string, args ...any) *Diagnostic {
+	d := &Diagnostic{Severity: SeverityError, Code: code, Port: port, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		d.NodeID = node.Id
+	}
+	return d
+}
+
+// warningAt creates a warning diagnostic for a node, and for one of its ports if port is not "".
+// node may be nil for problems that do not belong to a single node.
+func warningAt(code string, node *axon.Node, port string, format string, args ...any) *Diagnostic {
+	d := errorAt(code, node, port, format, args...)
+	d.Severity = SeverityWarning
+	return d
+}
+
+// asDiagnostic returns the diagnostic an error carries. Any other error becomes a diagnostic with the
+// given code, attributed to node.
+func asDiagnostic(err error, code string, node *axon.Node) *Diagnostic {
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return d
+	}
+	return errorAt(code, node, "", "%s", err.Error())
+}
+
+// addError records an error, keeping every diagnostic it carries. Errors that are not diagnostics are
+// attributed to node with the given code.
+func (ds *Diagnostics) addError(err error, code string, node *axon.Node) {
+	var all Diagnostics
+	if errors.As(err, &all) {
+		*ds = append(*ds, all...)
+		return
+	}
+	*ds = append(*ds, asDiagnostic(err, code, node))
+}
@@ -0,0 +1,57 @@
+package transpiler
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// brokenGraph has problems in each of its flows: main leaves the BRANCH through a pin it does not
+// have and not through one it does, and the function never returns.
+const brokenGraph = `{
+  "id": "broken", "name": "broken", "imports": ["fmt"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "flag", "type": "CONSTANT", "label": "flag", "outputs": [{"name": "out", "type_name": "bool"}], "config": {"value": "true"}},
+    {"id": "check", "type": "BRANCH", "label": "check", "inputs": [{"name": "condition", "type_name": "bool"}]},
+    {"id": "end", "type": "END", "label": "End"},
+    {"id": "greet", "type": "FUNC_DEF", "label": "greet"},
+    {"id": "hello", "type": "CONSTANT", "label": "hello", "outputs": [{"name": "out", "type_name": "string"}], "config": {"value": "\"hello\""}},
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "string"}]}
+  ],
+  "data_edges": [
+    {"from_node_id": "flag", "from_port": "out", "to_node_id": "check", "to_port": "condition"},
+    {"from_node_id": "hello", "from_port": "out", "to_node_id": "print", "to_port": "a"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "check"},
+    {"from_node_id": "check", "from_port": "true", "to_node_id": "end"},
+    {"from_node_id": "check", "from_port": "maybe", "to_node_id": "end"},
+    {"from_node_id": "greet", "to_node_id": "print"}
+  ]
+}`
+
+func TestEveryFlowProblemIsReported(t *testing.T) {
+	result := compile(t, loadGraph(t, brokenGraph), Options{})
+	if len(result.Files) != 0 {
+		t.Errorf("files were generated for a graph with errors")
+	}
+	for _, want := range []struct{ code, node, port string }{
+		{CodeUnknownExecPin, "check", "maybe"},
+		{CodeUnconnectedExecPin, "check", "false"},
+		{CodeDanglingPath, "print", ""},
+	} {
+		found := slices.ContainsFunc(result.Diagnostics, func(d *Diagnostic) bool {
+			return d.Code == want.code && d.NodeID == want.node && (want.port == "" || d.Port == want.port)
+		})
+		if !found {
+			t.Errorf("no %s diagnostic for node %s %s; got %v", want.code, want.node, want.port, result.Diagnostics)
+		}
+	}
+
+	_, err := Transpile(loadGraph(t, brokenGraph), Options{})
+	var diags Diagnostics
+	if !errors.As(err, &diags) || len(diags) != len(result.Diagnostics) {
+		t.Errorf("Transpile returned %v, want the %d diagnostics Compile found", err, len(result.Diagnostics))
+	}
+}
@@ -0,0 +1,105 @@
+package transpiler
+
+import (
+	"fmt"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// Precedence of Go expressions, higher binding tighter. Binary operators range from 1 (||) to 5 (*),
+// unary expressions bind tighter than any of them, and primary expressions (names, calls, literals)
+// tighter still.
+const (
+	precUnary   = 6
+	precPrimary = 7
+)
+
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3, "<": 3, "<=": 3, ">": 3, ">=": 3,
+	"+": 4, "-": 4, "|": 4, "^": 4,
+	"*": 5, "/": 5, "%": 5, "<<": 5, ">>": 5, "&": 5, "&^": 5,
+}
+
+// findFoldableNodes is the folding pass. It picks the pure nodes whose single output is used exactly
+// once, so their expression can be written straight into the consumer rather than into a variable.
+// A node on the execution flow only qualifies when its consumer runs right after it, so moving the
+// evaluation there changes nothing.
+func findFoldableNodes(graph *axon.Graph, nodeMap map[string]*axon.Node) map[string]bool {
+	foldable := make(map[string]bool)
+	for _, node := range graph.Nodes {
+		isPure := node.Type == axon.NodeType_OPERATOR || (node.Type == axon.NodeType_FUNCTION && node.Config["pure"] == "true")
+		if !isPure || len(node.Outputs) != 1 {
+			continue
+		}
+		var uses []*axon.DataEdge
+		for _, edge := range graph.DataEdges {
+			if edge.FromNodeId == node.Id {
+				uses = append(uses, edge)
+			}
+		}
+		if len(uses) != 1 {
+			continue
+		}
+		consumer, ok := nodeMap[uses[0].ToNodeId]
+		if !ok {
+			continue
+		}
+		switch consumer.Type {
+		case axon.NodeType_IGNORE, axon.NodeType_VAR_GET:
+			continue
+		case axon.NodeType_LOOP, axon.NodeType_ERROR_CHECK:
+			// A loop header is evaluated on every iteration, and an error check reads its input twice.
+			continue
+		}
+		if !isDetachedPure(graph, node) && !runsNext(graph, node, consumer) {
+			continue
+		}
+		foldable[node.Id] = true
+	}
+	return foldable
+}
+
+// runsNext reports whether the only execution edge leaving node leads to next.
+func runsNext(graph *axon.Graph, node, next *axon.Node) bool {
+	var targets []string
+	for _, edge := range graph.ExecEdges {
+		if edge.FromNodeId == node.Id {
+			targets = append(targets, edge.ToNodeId)
+		}
+	}
+	return len(targets) == 1 && targets[0] == next.Id
+}
+
+// foldOrDeclare either registers expr as the value of the node's single output, when the node is
+// folded into its consumer, or emits a variable holding it.
+func foldOrDeclare(state *transpilationState, node *axon.Node, expr string, prec int) string {
+	key := fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)
+	if state.foldable[node.Id] {
+		state.outputVarMap[key] = expr
+		state.precedence[key] = prec
+		return ""
+	}
+	varName := state.nameOfOutput(node, node.Outputs[0])
+	state.outputVarMap[key] = varName
+	return fmt.Sprintf("\t%s := %s\n", varName, expr)
+}
+
+// findSourceOperand resolves an input like findSourceVar, for use as an operand that must bind at
+// least as tightly as minPrec. A folded expression that binds more loosely is parenthesised.
+func findSourceOperand(state *transpilationState, toNodeID, toPortName string, minPrec int) (string, error) {
+	expr, err := findSourceVar(state, toNodeID, toPortName)
+	if err != nil {
+		return "", err
+	}
+	for _, edge := range state.graph.DataEdges {
+		if edge.ToNodeId == toNodeID && edge.ToPort == toPortName {
+			if prec, ok := state.precedence[fmt.Sprintf("%s.%s", edge.FromNodeId, edge.FromPort)]; ok && prec < minPrec {
+				return fmt.Sprintf("(%s)", expr), nil
+			}
+			break
+		}
+	}
+	return expr, nil
+}
@@ -0,0 +1,54 @@
+package transpiler
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Advik-B/Axon/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the code generated now")
+
+// TestGolden transpiles each example with a golden file in testdata, named after it, and compares
+// the code generated with the file.
+func TestGolden(t *testing.T) {
+	goldens, err := filepath.Glob(filepath.Join("testdata", "*.golden"))
+	if err != nil || len(goldens) == 0 {
+		t.Fatalf("no golden files found: %v", err)
+	}
+	for _, golden := range goldens {
+		name := strings.TrimSuffix(filepath.Base(golden), ".golden")
+		t.Run(name, func(t *testing.T) {
+			graph, err := parser.LoadGraphFromFile(filepath.Join("..", "examples", name+".ax"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := Compile(context.Background(), graph, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Diagnostics.HasErrors() {
+				t.Fatalf("example does not transpile: %v", result.Diagnostics)
+			}
+			got := result.Files[0].Content
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("generated code differs from %s; run go test -run TestGolden -update if the change is intended:\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// ImportSpec is one entry of a graph's imports: a package path, optionally preceded by the name it
+// is imported under, as in a Go import declaration. "strings", "yaml gopkg.in/yaml.v3" and
+// "_ embed" are all valid entries.
+type ImportSpec struct {
+	Alias string
+	Path  string
+}
+
+// ParseImport reads an entry of a graph's imports. The path may be quoted, as in Go.
+func ParseImport(entry string) ImportSpec {
+	fields := strings.Fields(entry)
+	if len(fields) == 2 {
+		return ImportSpec{Alias: fields[0], Path: strings.Trim(fields[1], `"`)}
+	}
+	return ImportSpec{Path: strings.Trim(strings.TrimSpace(entry), `"`)}
+}
+
+// Name returns the name the package is referred to by in the generated code.
+func (s ImportSpec) Name() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return ImportName(s.Path)
+}
+
+// String returns the spec as it appears in an import declaration.
+func (s ImportSpec) String() string {
+	if s.Alias != "" {
+		return fmt.Sprintf("%s %q", s.Alias, s.Path)
+	}
+	return fmt.Sprintf("%q", s.Path)
+}
+
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// ImportName returns the name a package is referred to by when imported from the given path,
+// following the usual conventions: the last path element, skipping a major version suffix as in
+// "example.com/mod/v2", and dropping a gopkg.in version as in "gopkg.in/yaml.v3".
+func ImportName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersion.MatchString(name) {
+		name = path.Base(path.Dir(importPath))
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// packageRef is a use of a package by a node, as in the impl_reference "strings.ToUpper".
+type packageRef struct {
+	node   *axon.Node
+	name   string
+	symbol string
+	// required is set where the name can only be a package, as in an impl_reference or a type
+	// name, rather than in an expression that may refer to variables too.
+	required bool
+}
+
+// resolveImports works out the packages the generated code may import: those the graph declares,
+// and the standard library packages its nodes use without declaring them. The generated code
+// relies on some packages of its own, like sync for a WAIT node, and these are added too.
+func resolveImports(graph *axon.Graph) ([]ImportSpec, Diagnostics) {
+	var imports []ImportSpec
+	names := make(map[string]bool)
+	add := func(spec ImportSpec) {
+		if !slices.Contains(imports, spec) {
+			imports = append(imports, spec)
+			names[spec.Name()] = true
+		}
+	}
+	for _, entry := range graph.Imports {
+		add(ParseImport(entry))
+	}
+
+	var diags Diagnostics
+	for _, node := range graph.Nodes {
+		switch node.Type {
+		case axon.NodeType_WAIT:
+			if groupKind(node) == "errgroup" {
+				add(ImportSpec{Path: "golang.org/x/sync/errgroup"})
+			} else {
+				add(ImportSpec{Path: "sync"})
+			}
+		case axon.NodeType_ERROR_CHECK:
+			if node.Config["wrap"] != "" {
+				add(ImportSpec{Path: "fmt"})
+			}
+		}
+	}
+	for _, ref := range packageRefs(graph) {
+		if names[ref.name] {
+			continue
+		}
+		if importPath := findStdlibPackage(ref.name, ref.symbol); importPath != "" {
+			add(ImportSpec{Path: importPath})
+			continue
+		}
+		if ref.required {
+			diags = append(diags, errorAt(CodeMissingImport, ref.node, "", "%s node '%s' uses %s.%s, but package %s is not imported and is not in the standard library; add it to the graph's imports", ref.node.Type, ref.node.Label, ref.name, ref.symbol, ref.name))
+			names[ref.name] = true // Reported once.
+		}
+	}
+	return imports, diags
+}
+
+// packageRefs finds the qualified names nodes use: in impl_references, port types, and the
+// values and types in their config.
+func packageRefs(graph *axon.Graph) []packageRef {
+	var refs []packageRef
+	collect := func(node *axon.Node, src string, required bool) {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			return
+		}
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok {
+					refs = append(refs, packageRef{node: node, name: x.Name, symbol: sel.Sel.Name, required: required})
+				}
+			}
+			return true
+		})
+	}
+
+	for _, node := range graph.Nodes {
+		switch node.Type {
+		case axon.NodeType_FUNCTION, axon.NodeType_GO, axon.NodeType_DEFER:
+			if node.ImplReference != "" && axon.FindPort(node.Inputs, "receiver") == nil {
+				collect(node, node.ImplReference, true)
+			}
+		case axon.NodeType_CONSTANT, axon.NodeType_VAR_DECLARE:
+			collect(node, node.Config["value"], false)
+			collect(node, node.Config["type"], true)
+		case axon.NodeType_OPERATOR:
+			// A conversion like time.Duration or a struct literal like &http.Client.
+			collect(node, strings.TrimPrefix(node.Config["op"], "&"), false)
+		case axon.NodeType_SWITCH:
+			for key, value := range node.Config {
+				if strings.HasPrefix(key, axon.SwitchCasePrefix) {
+					collect(node, value, false)
+				}
+			}
+		}
+		for _, port := range node.Inputs {
+			if port.Name != "receiver" || node.Type != axon.NodeType_FUNCTION {
+				collect(node, port.TypeName, true)
+			}
+		}
+		for _, port := range node.Outputs {
+			collect(node, port.TypeName, true)
+		}
+	}
+	// Map iteration makes the order of config values random; sort for stable diagnostics.
+	slices.SortStableFunc(refs, func(a, b packageRef) int { return strings.Compare(a.node.Id, b.node.Id) })
+	return refs
+}
+
+var stdlib struct {
+	once     sync.Once
+	importer types.Importer
+	byName   map[string][]string // Package name -> import paths
+}
+
+// findStdlibPackage returns the import path of the standard library package with the given
+// name that exports symbol, or "" if there is none. When several do, as for rand.Int, the
+// shortest path wins, so math/rand is chosen over crypto/rand.
+func findStdlibPackage(name, symbol string) string {
+	stdlib.once.Do(indexStdlib)
+	var found string
+	for _, importPath := range stdlib.byName[name] {
+		pkg, err := stdlib.importer.Import(importPath)
+		if err != nil || pkg.Scope().Lookup(symbol) == nil || !token.IsExported(symbol) {
+			continue
+		}
+		if found == "" || len(importPath) < len(found) || (len(importPath) == len(found) && importPath < found) {
+			found = importPath
+		}
+	}
+	return found
+}
+
+// indexStdlib lists the importable packages in GOROOT by name.
+func indexStdlib() {
+	stdlib.importer = importer.Default()
+	stdlib.byName = make(map[string][]string)
+	root := filepath.Join(build.Default.GOROOT, "src")
+	filepath.WalkDir(root, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, dir)
+		rel = filepath.ToSlash(rel)
+		switch d.Name() {
+		case "internal", "vendor", "testdata":
+			return filepath.SkipDir
+		}
+		if rel == "cmd" {
+			return filepath.SkipDir
+		}
+		if rel != "." {
+			name := ImportName(rel)
+			stdlib.byName[name] = append(stdlib.byName[name], rel)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+package transpiler
+
+import (
+	"go/format"
+	"testing"
+)
+
+func TestParseImport(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  ImportSpec
+		name  string
+	}{
+		{"strings", ImportSpec{Path: "strings"}, "strings"},
+		{`"math/rand"`, ImportSpec{Path: "math/rand"}, "rand"},
+		{"yaml gopkg.in/yaml.v3", ImportSpec{Alias: "yaml", Path: "gopkg.in/yaml.v3"}, "yaml"},
+		{`str "strings"`, ImportSpec{Alias: "str", Path: "strings"}, "str"},
+		{"example.com/mod/v2", ImportSpec{Path: "example.com/mod/v2"}, "mod"},
+	}
+	for _, tt := range tests {
+		spec := ParseImport(tt.entry)
+		if spec != tt.want || spec.Name() != tt.name {
+			t.Errorf("ParseImport(%q) = %+v named %s, want %+v named %s", tt.entry, spec, spec.Name(), tt.want, tt.name)
+		}
+	}
+}
+
+// importsGraph declares strings under an alias, and os without using it, and calls fmt.Println
+// without declaring fmt.
+const importsGraph = `{
+  "id": "imports", "name": "imports", "imports": ["str strings", "os"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "word", "type": "CONSTANT", "label": "word", "outputs": [{"name": "out", "type_name": "string"}], "config": {"value": "\"hi\""}},
+    {"id": "upper", "type": "FUNCTION", "label": "upper", "impl_reference": "str.ToUpper", "inputs": [{"name": "s", "type_name": "string"}], "outputs": [{"name": "out", "type_name": "string"}]},
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "string"}]},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "data_edges": [
+    {"from_node_id": "word", "from_port": "out", "to_node_id": "upper", "to_port": "s"},
+    {"from_node_id": "upper", "from_port": "out", "to_node_id": "print", "to_port": "a"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "upper"},
+    {"from_node_id": "upper", "to_node_id": "print"},
+    {"from_node_id": "print", "to_node_id": "end"}
+  ]
+}`
+
+func TestImports(t *testing.T) {
+	result := compile(t, loadGraph(t, importsGraph), Options{})
+	if result.Diagnostics.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %v", result.Diagnostics)
+	}
+	if d := find(result.Diagnostics, CodeUnusedImport); d == nil {
+		t.Errorf("unused import of os not reported: %v", result.Diagnostics)
+	}
+	code := generate(t, importsGraph, Options{})
+	expectCode(t, code, "import (\n\t\"fmt\"\n\tstr \"strings\"\n)\n", "str.ToUpper(word)")
+	if formatted, err := format.Source([]byte(code)); err != nil || string(formatted) != code {
+		t.Errorf("generated code is not gofmt-clean: %v\n%s", err, code)
+	}
+
+	missing := loadGraph(t, importsGraph)
+	missing.Imports = nil
+	if d := find(compile(t, missing, Options{}).Diagnostics, CodeMissingImport); d == nil || d.NodeID != "upper" {
+		t.Errorf("str.ToUpper without an import of str reported as %v", d)
+	}
+}
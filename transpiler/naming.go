@@ -0,0 +1,309 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// The naming pass gives every value a graph declares a Go identifier before any code is generated.
+// Names come from node labels (or port names, for values like parameters and loop variables), made
+// into valid identifiers. They are unique within each function, and file-level names are unique
+// across the file. Clashes are settled by node ID rather than by the order nodes appear in, so a
+// value keeps its name when unrelated nodes are added or removed and regenerated code diffs cleanly.
+
+// nameClaim is a value that needs a name.
+type nameClaim struct {
+	key  string // Like the keys of outputVarMap: "node.port", or the node ID for variables and groups.
+	node *axon.Node
+	base string
+}
+
+// assignNames names every value in the graph. Globals and pure nodes off the execution flow, which
+// can be computed in any function, share the file-level namespace; the values of each flow are
+// named within their function.
+func assignNames(graph *axon.Graph, entryPoints map[axon.NodeType][]*Flow, globals []*axon.Node, imports []ImportSpec, funcNames map[string]string) map[string]string {
+	reserved := make(map[string]bool)
+	for _, name := range types.Universe.Names() {
+		reserved[name] = true
+	}
+	reserved["main"], reserved["init"], reserved[traceFunc] = true, true, true
+	for _, spec := range imports {
+		reserved[spec.Name()] = true
+	}
+	for _, name := range funcNames {
+		reserved[name] = true
+	}
+	for _, node := range graph.Nodes {
+		if node.Type == axon.NodeType_STRUCT_DEF {
+			reserved[node.Label] = true
+		}
+	}
+
+	names := make(map[string]string)
+	fileLevel := slices.Clone(globals)
+	for _, node := range graph.Nodes {
+		if isDetachedPure(graph, node) {
+			fileLevel = append(fileLevel, node)
+		}
+	}
+	settleNames(nameClaims(fileLevel), reserved, names)
+
+	taken := make(map[string]bool)
+	for name := range reserved {
+		taken[name] = true
+	}
+	for _, name := range names {
+		taken[name] = true
+	}
+	for _, flows := range entryPoints {
+		for _, f := range flows {
+			settleNames(nameClaims(f.Nodes), taken, names)
+			// Labels have a namespace of their own, so they only need to differ from each other.
+			settleNames(loopLabelClaims(f.Nodes), make(map[string]bool), names)
+		}
+	}
+	return names
+}
+
+// nameClaims lists the values the given nodes declare, each with the name it would ideally get.
+func nameClaims(nodes []*axon.Node) []nameClaim {
+	var claims []nameClaim
+	claim := func(node *axon.Node, key, base string) {
+		claims = append(claims, nameClaim{key: key, node: node, base: base})
+	}
+	portKey := func(node *axon.Node, port *axon.Port) string {
+		return fmt.Sprintf("%s.%s", node.Id, port.Name)
+	}
+
+	for _, node := range nodes {
+		switch node.Type {
+		case axon.NodeType_CONSTANT, axon.NodeType_OPERATOR, axon.NodeType_CHAN_MAKE,
+			axon.NodeType_FUNCTION, axon.NodeType_CHAN_RECEIVE:
+			// A value named after its node, or after its node and port when there are several.
+			for _, port := range node.Outputs {
+				base := node.Label
+				if len(node.Outputs) > 1 {
+					base = node.Label + " " + port.Name
+				}
+				claim(node, portKey(node, port), base)
+			}
+		case axon.NodeType_VAR_DECLARE:
+			claim(node, node.Id, configName(node))
+		case axon.NodeType_WAIT:
+			claim(node, node.Id, configName(node))
+			for _, port := range node.Outputs {
+				claim(node, portKey(node, port), port.Name)
+			}
+		case axon.NodeType_FUNC_DEF:
+			for _, port := range node.Inputs {
+				if port.Name == "receiver" {
+					// A short, conventional receiver name like 'u' for 'User'
+					typeName := strings.TrimLeft(port.TypeName, "*")
+					claim(node, portKey(node, port), strings.ToLower(typeName[:min(1, len(typeName))]))
+				}
+			}
+			for _, port := range node.Outputs {
+				claim(node, portKey(node, port), port.Name)
+			}
+		case axon.NodeType_GO:
+			// A call joining a group runs in a function literal taking the call's arguments.
+			if node.ImplReference != "" {
+				for _, port := range node.Inputs {
+					claim(node, portKey(node, port), port.Name)
+				}
+			}
+			for _, port := range node.Outputs {
+				claim(node, portKey(node, port), port.Name)
+			}
+		case axon.NodeType_LOOP, axon.NodeType_DEFER, axon.NodeType_RECOVER, axon.NodeType_SELECT, axon.NodeType_ERROR_CHECK,
+			axon.NodeType_SWITCH:
+			// Parameters, loop variables, received values, wrapped errors and the value a type switch
+			// binds are named after their ports.
+			for _, port := range node.Outputs {
+				claim(node, portKey(node, port), port.Name)
+			}
+		}
+	}
+	return claims
+}
+
+// loopLabelClaims lists the labels the LOOP nodes given may need, for breaking or continuing them
+// from an inner loop. They are named after the node's label, or "loop" when it has no usable one.
+func loopLabelClaims(nodes []*axon.Node) []nameClaim {
+	var claims []nameClaim
+	for _, node := range nodes {
+		if node.Type != axon.NodeType_LOOP {
+			continue
+		}
+		base := node.Label
+		if identifier(base) == "" {
+			base = "loop"
+		}
+		claims = append(claims, nameClaim{key: loopLabelKey(node), node: node, base: base})
+	}
+	return claims
+}
+
+// loopLabelKey is the key a LOOP node's label is named under, apart from the values it declares.
+func loopLabelKey(node *axon.Node) string {
+	return node.Id + ":label"
+}
+
+// configName returns the name a VAR_DECLARE or WAIT node asks for: its config "name", or its label.
+func configName(node *axon.Node) string {
+	if name := node.Config["name"]; name != "" {
+		return name
+	}
+	return node.Label
+}
+
+// settleNames names a set of claims that share a namespace, avoiding the names already taken.
+// When several claims want the same name, the one with the lowest key gets it and the others add
+// their node's ID, so the outcome does not depend on the order of the graph's nodes.
+func settleNames(claims []nameClaim, taken map[string]bool, names map[string]string) {
+	slices.SortFunc(claims, func(a, b nameClaim) int { return strings.Compare(a.key, b.key) })
+	used := make(map[string]bool)
+	for _, c := range claims {
+		name := identifier(c.base)
+		if name == "" {
+			name = identifier(c.node.Id)
+		}
+		if name == "" {
+			name = "v"
+		}
+		if taken[name] || used[name] {
+			name += exported(identifier(c.node.Id))
+		}
+		for i := 2; taken[name] || used[name]; i++ {
+			name = fmt.Sprintf("%s%d", strings.TrimRight(name, "0123456789"), i)
+		}
+		used[name] = true
+		names[c.key] = name
+	}
+}
+
+// identifier turns a label into a Go identifier. Labels that already are one are kept as they are;
+// others are split into words and joined in lowerCamelCase, so "Print Result" becomes printResult.
+// A keyword gets a trailing underscore. It returns "" if the label has no usable characters.
+func identifier(label string) string {
+	name := label
+	if !token.IsIdentifier(name) && !token.IsKeyword(name) {
+		words := strings.FieldsFunc(label, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		})
+		var sb strings.Builder
+		for i, word := range words {
+			if i == 0 {
+				sb.WriteString(lowerFirst(word))
+			} else {
+				sb.WriteString(exported(word))
+			}
+		}
+		name = sb.String()
+		if name != "" && unicode.IsDigit([]rune(name)[0]) {
+			name = "v" + name
+		}
+	}
+	if strings.Trim(name, "_") == "" {
+		return ""
+	}
+	if token.IsKeyword(name) {
+		name += "_"
+	}
+	return name
+}
+
+func exported(word string) string {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return ""
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func lowerFirst(word string) string {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return ""
+	}
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// funcName returns the name a FUNC_DEF with the given label is generated under, or "" if the label
+// cannot be made into one.
+func funcName(label string, naming FuncNaming) string {
+	switch naming {
+	case FuncNamesExported:
+		return exported(identifier(exported(label)))
+	case FuncNamesUnexported:
+		return identifier(lowerFirst(identifier(label)))
+	}
+	return identifier(label)
+}
+
+// checkFuncNames reports FUNC_DEF labels that cannot be used as they are, and functions, or methods
+// of one type, whose labels give the same Go name.
+func checkFuncNames(graph *axon.Graph, naming FuncNaming) Diagnostics {
+	var diags Diagnostics
+	first := make(map[string]*axon.Node)
+	for _, node := range graph.Nodes {
+		if node.Type != axon.NodeType_FUNC_DEF {
+			continue
+		}
+		name := funcName(node.Label, naming)
+		switch {
+		case name == "":
+			diags = append(diags, errorAt(CodeInvalidFuncName, node, "", "function '%s' cannot be given a Go name, as its label has no letters or digits", node.Label))
+			continue
+		case naming == FuncNamesAsLabeled && name != node.Label:
+			diags = append(diags, warningAt(CodeInvalidFuncName, node, "", "function label '%s' is not a valid Go identifier, so it is generated as %s", node.Label, name))
+		}
+		key := name
+		if receiver := axon.FindPort(node.Inputs, "receiver"); receiver != nil {
+			key = strings.TrimLeft(receiver.TypeName, "*") + "." + name
+		}
+		if other, ok := first[key]; ok {
+			diags = append(diags, errorAt(CodeDuplicateFuncName, node, "", "functions '%s' and '%s' are both generated as %s; rename one of them", other.Label, node.Label, name))
+			continue
+		}
+		first[key] = node
+	}
+	return diags
+}
+
+// callee returns the Go name an impl_reference calls: the generated name when it is the label of
+// a FUNC_DEF, looked up among methods for calls through a receiver, and the reference unchanged
+// otherwise.
+func (s *transpilationState) callee(ref string, method bool) string {
+	names := s.funcNames
+	if method {
+		names = s.methodNames
+	}
+	if name, ok := names[ref]; ok {
+		return name
+	}
+	return ref
+}
+
+// nameOf returns the Go identifier assigned to a value, keyed like outputVarMap.
+func (s *transpilationState) nameOf(key string) string {
+	if name, ok := s.names[key]; ok {
+		return name
+	}
+	// Every declared value is named up front, so this only happens for nodes in invalid places,
+	// which are reported elsewhere.
+	return identifier(key)
+}
+
+// nameOfOutput returns the Go identifier assigned to one of a node's outputs.
+func (s *transpilationState) nameOfOutput(node *axon.Node, port *axon.Port) string {
+	return s.nameOf(fmt.Sprintf("%s.%s", node.Id, port.Name))
+}
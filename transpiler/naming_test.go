@@ -0,0 +1,161 @@
+package transpiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+func TestIdentifier(t *testing.T) {
+	tests := []struct {
+		label, want string
+	}{
+		{"sum", "sum"},
+		{"PrintResult", "PrintResult"},
+		{"Print Result", "printResult"},
+		{"say hi!", "sayHi"},
+		{"config.txt", "configTxt"},
+		{"2nd try", "v2ndTry"},
+		{"type", "type_"},
+		{"naïve café", "naïveCafé"},
+		{"???", ""},
+		{"_", ""},
+	}
+	for _, tt := range tests {
+		if got := identifier(tt.label); got != tt.want {
+			t.Errorf("identifier(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestFuncName(t *testing.T) {
+	tests := []struct {
+		label  string
+		naming FuncNaming
+		want   string
+	}{
+		{"greet", FuncNamesAsLabeled, "greet"},
+		{"say hi", FuncNamesAsLabeled, "sayHi"},
+		{"say hi", FuncNamesExported, "SayHi"},
+		{"Greet", FuncNamesUnexported, "greet"},
+		{"func", FuncNamesAsLabeled, "func_"},
+		{"!!", FuncNamesAsLabeled, ""},
+		{"!!", FuncNamesExported, ""},
+	}
+	for _, tt := range tests {
+		if got := funcName(tt.label, tt.naming); got != tt.want {
+			t.Errorf("funcName(%q, %v) = %q, want %q", tt.label, tt.naming, got, tt.want)
+		}
+	}
+}
+
+// funcDef returns a FUNC_DEF node, a method of receiver unless it is empty.
+func funcDef(id, label, receiver string) *axon.Node {
+	node := &axon.Node{Id: id, Type: axon.NodeType_FUNC_DEF, Label: label}
+	if receiver != "" {
+		node.Inputs = []*axon.Port{{Name: "receiver", TypeName: receiver}}
+	}
+	return node
+}
+
+func TestCheckFuncNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		nodes  []*axon.Node
+		naming FuncNaming
+		// want lists the code and severity of each diagnostic, in order.
+		want []string
+	}{
+		{
+			name:  "valid names",
+			nodes: []*axon.Node{funcDef("a", "greet", ""), funcDef("b", "Greet", "")},
+		},
+		{
+			name:  "label that is not an identifier",
+			nodes: []*axon.Node{funcDef("a", "say hi", "")},
+			want:  []string{"warning " + CodeInvalidFuncName},
+		},
+		{
+			name:  "label with no letters",
+			nodes: []*axon.Node{funcDef("a", "!!", "")},
+			want:  []string{"error " + CodeInvalidFuncName},
+		},
+		{
+			name:  "labels giving one name",
+			nodes: []*axon.Node{funcDef("a", "sayHi", ""), funcDef("b", "say hi", "")},
+			want:  []string{"warning " + CodeInvalidFuncName, "error " + CodeDuplicateFuncName},
+		},
+		{
+			name:   "labels giving one name once exported",
+			nodes:  []*axon.Node{funcDef("a", "greet", ""), funcDef("b", "Greet", "")},
+			naming: FuncNamesExported,
+			want:   []string{"error " + CodeDuplicateFuncName},
+		},
+		{
+			name:  "methods of different types",
+			nodes: []*axon.Node{funcDef("a", "Greet", "*User"), funcDef("b", "Greet", "Admin"), funcDef("c", "Greet", "")},
+		},
+		{
+			name:  "methods of one type",
+			nodes: []*axon.Node{funcDef("a", "Greet", "*User"), funcDef("b", "Greet", "User")},
+			want:  []string{"error " + CodeDuplicateFuncName},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range checkFuncNames(&axon.Graph{Nodes: tt.nodes}, tt.naming) {
+				got = append(got, d.Severity.String()+" "+d.Code)
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("got diagnostics %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// typeSwitchGraph prints a value through two type switches whose bound values are both named
+// after their port, type, which is a keyword.
+const typeSwitchGraph = `{
+  "id": "ts", "name": "tswitch", "imports": ["fmt"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "x", "type": "VAR_DECLARE", "label": "x", "config": {"type": "any", "value": "42"}},
+    {"id": "xg", "type": "VAR_GET", "label": "x", "outputs": [{"name": "out", "type_name": "any"}], "config": {"variable": "x"}},
+    {"id": "s1", "type": "SWITCH", "label": "kind1", "inputs": [{"name": "in", "type_name": "any"}], "outputs": [{"name": "type", "type_name": "any"}], "config": {"kind": "type", "case:num": "int"}},
+    {"id": "p1", "type": "FUNCTION", "label": "p1", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "any"}]},
+    {"id": "s2", "type": "SWITCH", "label": "kind2", "inputs": [{"name": "in", "type_name": "any"}], "outputs": [{"name": "type", "type_name": "any"}], "config": {"kind": "type", "case:num": "int"}},
+    {"id": "p2", "type": "FUNCTION", "label": "p2", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "any"}]},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "data_edges": [
+    {"from_node_id": "xg", "from_port": "out", "to_node_id": "s1", "to_port": "in"},
+    {"from_node_id": "xg", "from_port": "out", "to_node_id": "s2", "to_port": "in"},
+    {"from_node_id": "s1", "from_port": "type", "to_node_id": "p1", "to_port": "a"},
+    {"from_node_id": "s2", "from_port": "type", "to_node_id": "p2", "to_port": "a"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "x"},
+    {"from_node_id": "x", "to_node_id": "s1"},
+    {"from_node_id": "s1", "from_port": "num", "to_node_id": "p1"},
+    {"from_node_id": "s1", "from_port": "default", "to_node_id": "s2"},
+    {"from_node_id": "p1", "to_node_id": "s2"},
+    {"from_node_id": "s2", "from_port": "num", "to_node_id": "p2"},
+    {"from_node_id": "s2", "from_port": "default", "to_node_id": "end"},
+    {"from_node_id": "p2", "to_node_id": "end"}
+  ]
+}`
+
+func TestTypeSwitchBindingNames(t *testing.T) {
+	result := compile(t, loadGraph(t, typeSwitchGraph), Options{})
+	if result.Diagnostics.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %v", result.Diagnostics)
+	}
+	code := string(result.Files[0].Content)
+	for _, header := range []string{"switch type_ := x.(type)", "switch type_S2 := x.(type)"} {
+		if !strings.Contains(code, header) {
+			t.Errorf("generated code has no %q:\n%s", header, code)
+		}
+	}
+}
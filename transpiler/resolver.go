@@ -2,30 +2,52 @@ package transpiler
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+
 	"github.com/Advik-B/Axon/pkg/axon"
 )
 
-// flow represents a single, self-contained execution graph (like main or a function).
-type flow struct {
-	entryNode *axon.Node
-	nodes     []*axon.Node
+// Flow is one execution flow of a graph: the main flow from its START node, or the body of a
+// function from its FUNC_DEF node.
+type Flow struct {
+	Entry *axon.Node
+	// Nodes are the nodes reached from the entry along execution edges.
+	Nodes []*axon.Node
+}
+
+// FindFlows returns a graph's execution flows, keyed by the type of their entry node, and its
+// global definitions, exactly as the transpiler sees them. Every flow is validated, and all the
+// problems found are returned together. It lets tools that run a graph without generating code,
+// like an interpreter, rely on the same structure.
+func FindFlows(graph *axon.Graph) (map[axon.NodeType][]*Flow, []*axon.Node, Diagnostics) {
+	return findExecutionScopes(graph)
 }
 
 // findExecutionScopes identifies all separate execution flows and global definitions.
-func findExecutionScopes(graph *axon.Graph) (map[axon.NodeType][]*flow, []*axon.Node, error) {
+// Every flow is validated, and all the problems found are returned together.
+func findExecutionScopes(graph *axon.Graph) (map[axon.NodeType][]*Flow, []*axon.Node, Diagnostics) {
 	nodeMap := make(map[string]*axon.Node)
 	adjList := make(map[string][]string)
+	entryPoints := make(map[axon.NodeType][]*Flow)
+	visited := make(map[string]bool)
+	var diags Diagnostics
+
 	for _, node := range graph.Nodes {
 		nodeMap[node.Id] = node
 		adjList[node.Id] = []string{}
+		// Code generation reads these ports without looking, so a node without them stops here.
+		if err := node.CheckPorts(); err != nil {
+			diags = append(diags, errorAt(CodeMissingPort, node, "", "%v", err))
+		}
+		if node.Type == axon.NodeType_PLACEHOLDER {
+			diags = append(diags, errorAt(CodePlaceholder, node, "", "node '%s' is a placeholder for Go code that could not be converted to nodes; replace it with nodes that do the same: %s", node.Label, node.Config["source"]))
+		}
 	}
 	for _, edge := range graph.ExecEdges {
 		adjList[edge.FromNodeId] = append(adjList[edge.FromNodeId], edge.ToNodeId)
 	}
 
-	entryPoints := make(map[axon.NodeType][]*flow)
-	visited := make(map[string]bool)
-
 	for _, node := range graph.Nodes {
 		if node.Type == axon.NodeType_START || node.Type == axon.NodeType_FUNC_DEF {
 			if visited[node.Id] {
@@ -33,19 +55,15 @@ func findExecutionScopes(graph *axon.Graph) (map[axon.NodeType][]*flow, []*axon.
 			}
 			var pathNodes []*axon.Node
 			var pathVisited = make(map[string]bool)
-			err := dfs(node.Id, adjList, nodeMap, pathVisited, &pathNodes)
-			if err != nil {
-				return nil, nil, fmt.Errorf("validation failed for flow starting at '%s': %w", node.Label, err)
-			}
-			if err := validateFlowTermination(node, pathNodes, nodeMap, adjList); err != nil {
-				return nil, nil, err
-			}
+			dfs(node.Id, adjList, nodeMap, pathVisited, &pathNodes)
+			diags = append(diags, validateFlowTermination(graph, node, nodeMap)...)
+			diags = append(diags, validateExecPins(graph, pathNodes, nodeMap)...)
 			for _, n := range pathNodes {
 				visited[n.Id] = true
 			}
-			entryPoints[node.Type] = append(entryPoints[node.Type], &flow{
-				entryNode: node,
-				nodes:     pathNodes,
+			entryPoints[node.Type] = append(entryPoints[node.Type], &Flow{
+				Entry: node,
+				Nodes: pathNodes,
 			})
 		}
 	}
@@ -55,72 +73,322 @@ func findExecutionScopes(graph *axon.Graph) (map[axon.NodeType][]*flow, []*axon.
 	for _, node := range graph.Nodes {
 		if !visited[node.Id] {
 			// **FIX**: An IGNORE node is a valid data sink and is allowed to be "unreachable" in the execution flow.
-			if node.Type == axon.NodeType_IGNORE {
+			// A VAR_GET is likewise read wherever its value is needed, and a pure node computed there.
+			// A placeholder, already reported, may stand in for a declaration.
+			if node.Type == axon.NodeType_IGNORE || node.Type == axon.NodeType_VAR_GET || node.Type == axon.NodeType_PLACEHOLDER || isDetachedPure(graph, node) {
 				// Mark it as visited so it doesn't get flagged as an invalid global.
 				visited[node.Id] = true
 				continue
 			}
-			if node.Type != axon.NodeType_CONSTANT && node.Type != axon.NodeType_STRUCT_DEF {
-				return nil, nil, fmt.Errorf("unreachable node '%s' is not a valid global type (CONSTANT or STRUCT_DEF)", node.Label)
+			if node.Type != axon.NodeType_CONSTANT && node.Type != axon.NodeType_STRUCT_DEF && node.Type != axon.NodeType_VAR_DECLARE {
+				diags = append(diags, errorAt(CodeInvalidGlobal, node, "", "unreachable node '%s' is not a valid global type (CONSTANT, STRUCT_DEF or VAR_DECLARE), nor a pure node free of execution edges", node.Label))
+				continue
 			}
 			globals = append(globals, node)
 		}
 	}
 
-	return entryPoints, globals, nil
+	return entryPoints, globals, diags
 }
 
 // dfs traverses a flow from an entry point and returns the nodes in topological order.
-func dfs(nodeID string, adjList map[string][]string, nodeMap map[string]*axon.Node, visited map[string]bool, pathNodes *[]*axon.Node) error {
+// Edges to unknown nodes are skipped; validateFlowTermination reports them.
+func dfs(nodeID string, adjList map[string][]string, nodeMap map[string]*axon.Node, visited map[string]bool, pathNodes *[]*axon.Node) {
 	if visited[nodeID] {
-		return nil
+		return
 	}
 	visited[nodeID] = true
 
 	for _, neighborID := range adjList[nodeID] {
-		if err := dfs(neighborID, adjList, nodeMap, visited, pathNodes); err != nil {
-			return err
+		if _, ok := nodeMap[neighborID]; ok {
+			dfs(neighborID, adjList, nodeMap, visited, pathNodes)
 		}
 	}
 	*pathNodes = append(*pathNodes, nodeMap[nodeID])
-	return nil
 }
 
-// validateFlowTermination ensures that a given flow terminates correctly.
-func validateFlowTermination(entryNode *axon.Node, pathNodes []*axon.Node, nodeMap map[string]*axon.Node, adjList map[string][]string) error {
+// validateFlowTermination ensures that every path through a flow ends with the correct terminator.
+// Inside a LOOP body a path may also simply end, or lead back into its LOOP, to start the next
+// iteration; any other cycle is an error. The "body" of a GO or DEFER node and the "handler" of a
+// RECOVER node are flows of their own: they run inside a function literal, so they may end anywhere
+// (or at END), but they can neither return from the enclosing function, jump to an enclosing loop,
+// nor share nodes with the code around them.
+func validateFlowTermination(graph *axon.Graph, entryNode *axon.Node, nodeMap map[string]*axon.Node) Diagnostics {
 	terminatorType := axon.NodeType_END
 	if entryNode.Type == axon.NodeType_FUNC_DEF {
 		terminatorType = axon.NodeType_RETURN
 	}
 
-	hasTerminator := false
+	const (
+		inProgress = iota + 1
+		done
+	)
+	status := make(map[string]int)
+	// Maps a node to the node whose function literal it runs in, or "" for the flow itself.
+	closureOf := make(map[string]string)
+	var diags Diagnostics
+
+	var walk func(nodeID string, loops []string, closure string)
+	walk = func(nodeID string, loops []string, closure string) {
+		node := nodeMap[nodeID]
+		status[nodeID] = inProgress
+		closureOf[nodeID] = closure
+		hasNext := false
+		for _, edge := range graph.ExecEdges {
+			if edge.FromNodeId != nodeID {
+				continue
+			}
+			hasNext = true
+			target, ok := nodeMap[edge.ToNodeId]
+			if !ok {
+				diags = append(diags, errorAt(CodeUnknownNode, node, edge.SourcePin(), "node '%s' has an execution edge to unknown node '%s'", node.Label, edge.ToNodeId))
+				continue
+			}
+			if slices.Contains(loops, edge.ToNodeId) {
+				continue // A jump back into an enclosing loop.
+			}
+			innerLoops, innerClosure := loops, closure
+			switch {
+			case node.Type == axon.NodeType_LOOP && edge.SourcePin() == "body":
+				innerLoops = append(slices.Clip(loops), node.Id)
+			case isClosurePin(node, edge.SourcePin()):
+				innerLoops, innerClosure = nil, node.Id
+			}
+			if status[edge.ToNodeId] != 0 && closureOf[edge.ToNodeId] != innerClosure {
+				diags = append(diags, errorAt(CodeClosureSharedNode, target, "", "node '%s' is reached both from inside and outside the body of a GO, DEFER or RECOVER node; such a body must be separate from the code around it", target.Label))
+				continue
+			}
+			switch status[edge.ToNodeId] {
+			case inProgress:
+				diags = append(diags, errorAt(CodeExecCycle, target, "", "flow starting at '%s' has a cycle through node '%s'; only a LOOP body may lead back to its LOOP", entryNode.Label, target.Label))
+				continue
+			case done:
+				continue
+			}
+			walk(edge.ToNodeId, innerLoops, innerClosure)
+		}
+		status[nodeID] = done
+
+		if hasNext {
+			return
+		}
+		if closure != "" {
+			if node.Type == axon.NodeType_RETURN {
+				diags = append(diags, errorAt(CodeReturnInClosure, node, "", "the body of %s node '%s' ends at RETURN node '%s'; it runs in a function literal, so it cannot return from its function", nodeMap[closure].Type, nodeMap[closure].Label, node.Label))
+			}
+			return
+		}
+		isTerminator := node.Type == axon.NodeType_END || node.Type == axon.NodeType_RETURN
+		if node.Type == terminatorType || (len(loops) > 0 && !isTerminator && node != entryNode) {
+			return
+		}
+		diags = append(diags, errorAt(CodeDanglingPath, node, "", "flow starting at '%s' has a dangling path at node '%s'. It must end with a %s node", entryNode.Label, node.Label, terminatorType))
+	}
+	walk(entryNode.Id, nil, "")
+	return diags
+}
+
+// isClosurePin reports whether an execution output leads into a flow that is generated inside a
+// function literal rather than inline.
+func isClosurePin(node *axon.Node, pin string) bool {
+	switch node.Type {
+	case axon.NodeType_GO, axon.NodeType_DEFER:
+		return pin == "body"
+	case axon.NodeType_RECOVER:
+		return pin == "handler"
+	}
+	return false
+}
+
+// validateExecPins ensures that exec edges only use pins their nodes declare, that no output
+// fans out to more than one node, and that nodes with several outputs (like BRANCH) wire up every one,
+// so the flow can be emitted as structured Go. An ERROR_CHECK may leave "failed" unconnected,
+// in which case the error is returned (the generator checks that the enclosing function returns one),
+// and a RECOVER may leave "handler" unconnected to swallow the panic.
+func validateExecPins(graph *axon.Graph, pathNodes []*axon.Node, nodeMap map[string]*axon.Node) Diagnostics {
+	var diags Diagnostics
 	for _, node := range pathNodes {
-		if len(adjList[node.Id]) == 0 {
-			if node.Type != terminatorType {
-				return fmt.Errorf("flow starting at '%s' has a dangling path at node '%s'. It must end with a %s node", entryNode.Label, node.Label, terminatorType)
+		targets := make(map[string]int)
+		for _, edge := range graph.ExecEdges {
+			if edge.FromNodeId != node.Id {
+				continue
+			}
+			if !node.HasExecOutputPin(edge.SourcePin()) {
+				diags = append(diags, errorAt(CodeUnknownExecPin, node, edge.SourcePin(), "node '%s' has no execution output named '%s'", node.Label, edge.SourcePin()))
+				continue
+			}
+			if target, ok := nodeMap[edge.ToNodeId]; ok && !target.HasExecInputPin(edge.TargetPin()) {
+				diags = append(diags, errorAt(CodeUnknownExecPin, target, edge.TargetPin(), "node '%s' has no execution input named '%s'", target.Label, edge.TargetPin()))
+			}
+			targets[edge.SourcePin()]++
+		}
+		for _, port := range slices.Sorted(maps.Keys(targets)) {
+			if count := targets[port]; count > 1 {
+				diags = append(diags, errorAt(CodeExecFanOut, node, port, "node '%s' has %d execution edges leaving output '%s'; an output may only flow to one node", node.Label, count, port))
+			}
+		}
+		if pins := node.ExecOutputPins(); len(pins) > 1 {
+			for _, port := range pins {
+				if targets[port] == 0 {
+					if node.Type == axon.NodeType_ERROR_CHECK && port == "failed" {
+						continue // The error is returned from the function.
+					}
+					if node.Type == axon.NodeType_RECOVER && port == "handler" {
+						continue // The panic is swallowed.
+					}
+					diags = append(diags, errorAt(CodeUnconnectedExecPin, node, port, "%s node '%s' has no execution edge for its '%s' output", node.Type, node.Label, port))
+				}
 			}
-			hasTerminator = true
 		}
 	}
+	return diags
+}
 
-	if !hasTerminator && len(pathNodes) > 1 {
-		return fmt.Errorf("flow starting at '%s' has a cycle or does not have a valid %s terminator node", entryNode.Label, terminatorType)
+// nextExecEdge returns the edge leaving the given execution output, or nil if there is none.
+func nextExecEdge(state *transpilationState, nodeID, port string) *axon.ExecEdge {
+	for _, edge := range state.graph.ExecEdges {
+		if edge.FromNodeId == nodeID && edge.SourcePin() == port {
+			return edge
+		}
 	}
 	return nil
 }
 
+// nextInChain returns the edge that leads on from a straight-line node in its flow.
+// Such nodes have at most one execution output, whatever it is named.
+func nextInChain(state *transpilationState, node *axon.Node) (*axon.ExecEdge, error) {
+	pins := node.ExecOutputPins()
+	switch len(pins) {
+	case 0:
+		return nil, nil
+	case 1:
+		return nextExecEdge(state, node.Id, pins[0]), nil
+	default:
+		return nil, fmt.Errorf("%s node '%s' cannot have more than one execution output (found %d)", node.Type, node.Label, len(pins))
+	}
+}
+
+// findJoinNode finds the first node that every branch target eventually flows into.
+// This is where the branches merge back together, and it returns "" if they never do.
+// Jumps back into an enclosing LOOP leave the branch rather than joining it, so they are not followed.
+func findJoinNode(state *transpilationState, targets []string) string {
+	if len(targets) == 0 {
+		return ""
+	}
+	reachable := make([]map[string]bool, len(targets))
+	var order []string
+	for i, target := range targets {
+		reachable[i] = make(map[string]bool)
+		if target == "" || state.enclosingLoop(target) != nil {
+			continue
+		}
+		queue := []string{target}
+		reachable[i][target] = true
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if i == 0 {
+				order = append(order, id)
+			}
+			for _, edge := range state.graph.ExecEdges {
+				if edge.FromNodeId == id && !reachable[i][edge.ToNodeId] && state.enclosingLoop(edge.ToNodeId) == nil {
+					reachable[i][edge.ToNodeId] = true
+					queue = append(queue, edge.ToNodeId)
+				}
+			}
+		}
+	}
+
+	for _, id := range order {
+		common := true
+		for _, set := range reachable[1:] {
+			if !set[id] {
+				common = false
+				break
+			}
+		}
+		if common {
+			return id
+		}
+	}
+	return ""
+}
+
 // findSourceVar finds the Go variable name connected to a specific input port of a node.
 func findSourceVar(state *transpilationState, toNodeID, toPortName string) (string, error) {
 	for _, edge := range state.graph.DataEdges {
 		if edge.ToNodeId == toNodeID && edge.ToPort == toPortName {
+			// A VAR_GET reads whichever variable it names, at the point its value is used.
+			if source, ok := state.nodeMap[edge.FromNodeId]; ok && source.Type == axon.NodeType_VAR_GET {
+				return resolveVariable(state, source)
+			}
 			sourceKey := fmt.Sprintf("%s.%s", edge.FromNodeId, edge.FromPort)
 			if varName, ok := state.outputVarMap[sourceKey]; ok {
 				return varName, nil
 			}
-			return "", fmt.Errorf("unresolved source variable for %s.%s (source key %s). This may indicate a flaw in the execution order or a missing global", toNodeID, toPortName, sourceKey)
+			// A pure node off the execution flow is computed the first time its value is needed in a scope.
+			if source, ok := state.nodeMap[edge.FromNodeId]; ok && isDetachedPure(state.graph, source) {
+				if err := generatePureNode(state, source); err != nil {
+					return "", err
+				}
+				if varName, ok := state.outputVarMap[sourceKey]; ok {
+					return varName, nil
+				}
+			}
+			return "", errorAt(CodeUnresolvedInput, state.nodeMap[toNodeID], toPortName, "input '%s' of node %s reads output '%s' of node %s, which has not been computed at this point; it may run later in the flow or in another scope", toPortName, toNodeID, edge.FromPort, edge.FromNodeId)
 		}
 	}
-	return "", fmt.Errorf("no data edge found connecting to %s.%s", toNodeID, toPortName)
+	return "", errorAt(CodeUnconnectedInput, state.nodeMap[toNodeID], toPortName, "input '%s' of node %s is not connected; no data edge leads to it", toPortName, toNodeID)
+}
+
+// resolveVariable finds the Go name of the variable a VAR_SET or VAR_GET node refers to,
+// provided its declaration is in scope.
+func resolveVariable(state *transpilationState, node *axon.Node) (string, error) {
+	declID := node.Config["variable"]
+	if decl, ok := state.nodeMap[declID]; !ok || decl.Type != axon.NodeType_VAR_DECLARE {
+		return "", errorAt(CodeUnknownVariable, node, "", "node '%s' refers to variable '%s', which is not a VAR_DECLARE node", node.Label, declID)
+	}
+	v, ok := state.variables[declID]
+	if !ok {
+		return "", errorAt(CodeUndeclaredVariable, node, "", "variable '%s' used by node '%s' is not declared in this scope", state.nodeMap[declID].Label, node.Label)
+	}
+	return v.name, nil
+}
+
+// isDetachedPure reports whether a node is a pure computation left off the execution flow, to be
+// scheduled from its data dependencies instead. OPERATOR nodes are always pure, FUNCTION nodes when
+// config "pure" is "true". (CONSTANT nodes off the flow are globals.)
+func isDetachedPure(graph *axon.Graph, node *axon.Node) bool {
+	isPure := node.Type == axon.NodeType_OPERATOR || (node.Type == axon.NodeType_FUNCTION && node.Config["pure"] == "true")
+	if !isPure {
+		return false
+	}
+	for _, edge := range graph.ExecEdges {
+		if edge.FromNodeId == node.Id || edge.ToNodeId == node.Id {
+			return false
+		}
+	}
+	return true
+}
+
+// isVariableRead checks if any VAR_GET node reads the variable declared by the given node.
+func isVariableRead(graph *axon.Graph, declID string) bool {
+	for _, node := range graph.Nodes {
+		if node.Type == axon.NodeType_VAR_GET && node.Config["variable"] == declID {
+			return true
+		}
+	}
+	return false
+}
+
+// isInputConnected checks if a specific input port has a data edge feeding it.
+func isInputConnected(graph *axon.Graph, nodeID, portName string) bool {
+	for _, edge := range graph.DataEdges {
+		if edge.ToNodeId == nodeID && edge.ToPort == portName {
+			return true
+		}
+	}
+	return false
 }
 
 // isOutputUsed checks if a specific output port is connected to any other node.
@@ -143,4 +411,4 @@ func isPortConnectedToIgnore(state *transpilationState, nodeID, portName string)
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
@@ -0,0 +1,152 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// checkSignature compares a FUNCTION node's ports with the real signature of the Go function or
+// method its impl_reference names. Functions that are defined by the graph itself, that come from
+// packages which cannot be loaded, or that are builtins are not checked.
+func (tc *typeChecker) checkSignature(node *axon.Node) error {
+	if node.ImplReference == "" {
+		return nil
+	}
+	sig, err := tc.lookupSignature(node)
+	if err != nil || sig == nil {
+		return err
+	}
+
+	var inputs []*axon.Port
+	for _, port := range node.Inputs {
+		if port.Name != "receiver" {
+			inputs = append(inputs, port)
+		}
+	}
+	params, results := sig.Params(), sig.Results()
+	mismatch := func(format string, args ...any) error {
+		return errorAt(CodeSignatureMismatch, node, "", "function node '%s' does not match %s: %s; it should declare %s", node.Label, node.ImplReference, fmt.Sprintf(format, args...), tc.describeSignature(sig))
+	}
+
+	if sig.Variadic() {
+		if len(inputs) < params.Len()-1 {
+			return mismatch("it takes at least %d inputs, but the node has %d", params.Len()-1, len(inputs))
+		}
+	} else if len(inputs) != params.Len() {
+		return mismatch("it takes %d inputs, but the node has %d", params.Len(), len(inputs))
+	}
+	// A call whose results are all left unconnected is a plain statement, so no outputs is fine too.
+	if len(node.Outputs) != 0 && len(node.Outputs) != results.Len() {
+		return mismatch("it returns %d results, but the node has %d outputs", results.Len(), len(node.Outputs))
+	}
+	if sig.TypeParams().Len() > 0 {
+		return nil // Parameter types depend on how the generic function is instantiated.
+	}
+
+	for i, port := range inputs {
+		param := tc.paramType(sig, i)
+		portType, err := tc.portType(node, port)
+		if err != nil {
+			return err
+		}
+		if portType != nil && !types.AssignableTo(portType, param) {
+			return mismatch("input '%s' is %s, which cannot be passed as %s", port.Name, tc.typeString(portType), tc.typeString(param))
+		}
+	}
+	for i, port := range node.Outputs {
+		result := results.At(i).Type()
+		portType, err := tc.portType(node, port)
+		if err != nil {
+			return err
+		}
+		if portType != nil && !types.AssignableTo(result, portType) {
+			return mismatch("result %d is %s, which cannot be stored in output '%s' (%s)", i+1, tc.typeString(result), port.Name, tc.typeString(portType))
+		}
+	}
+	return nil
+}
+
+// lookupSignature finds the signature of the function or method a FUNCTION node calls. It returns
+// nil when the callee cannot be resolved in the loaded packages.
+func (tc *typeChecker) lookupSignature(node *axon.Node) (*types.Signature, error) {
+	if receiver := axon.FindPort(node.Inputs, "receiver"); receiver != nil {
+		recvType, err := tc.portType(node, receiver)
+		if err != nil || recvType == nil {
+			return nil, err
+		}
+		obj, _, _ := types.LookupFieldOrMethod(recvType, true, tc.pkg, node.ImplReference)
+		fn, ok := obj.(*types.Func)
+		if !ok && tc.graphMethods[node.ImplReference] {
+			return nil, nil // A method defined by the graph, which the checker's package does not declare.
+		}
+		if !ok {
+			return nil, errorAt(CodeUnknownFunction, node, "", "function node '%s' calls method %s, but %s has no such method", node.Label, node.ImplReference, tc.typeString(recvType))
+		}
+		return fn.Type().(*types.Signature), nil
+	}
+
+	pkgName, name, ok := strings.Cut(node.ImplReference, ".")
+	if !ok {
+		return nil, nil // A function defined by the graph, or a builtin.
+	}
+	pkg := tc.imported[pkgName]
+	if pkg == nil {
+		return nil, nil
+	}
+	member := pkg.Scope().Lookup(name)
+	if member == nil || !member.Exported() {
+		return nil, errorAt(CodeUnknownFunction, node, "", "function node '%s' calls %s, but package %s has no exported function %s", node.Label, node.ImplReference, pkg.Path(), name)
+	}
+	fn, ok := member.(*types.Func)
+	if !ok {
+		return nil, errorAt(CodeUnknownFunction, node, "", "function node '%s' calls %s, which is not a function", node.Label, node.ImplReference)
+	}
+	return fn.Type().(*types.Signature), nil
+}
+
+// paramType returns the type an argument in position i must be assignable to, expanding a
+// variadic parameter into its element type.
+func (tc *typeChecker) paramType(sig *types.Signature, i int) types.Type {
+	params := sig.Params()
+	if sig.Variadic() && i >= params.Len()-1 {
+		return params.At(params.Len() - 1).Type().(*types.Slice).Elem()
+	}
+	return params.At(i).Type()
+}
+
+// describeSignature lists the ports a node needs to call a function with the given signature,
+// e.g. "inputs (name string) and outputs (data []byte, err error)".
+func (tc *typeChecker) describeSignature(sig *types.Signature) string {
+	describe := func(vars *types.Tuple, variadic bool, unnamed string) string {
+		var ports []string
+		for i := 0; i < vars.Len(); i++ {
+			name := vars.At(i).Name()
+			if name == "" || name == "_" {
+				name = fmt.Sprintf("%s%d", unnamed, i)
+				if types.Identical(vars.At(i).Type(), types.Universe.Lookup("error").Type()) {
+					name = "err"
+				}
+			}
+			typ := tc.typeString(vars.At(i).Type())
+			if variadic && i == vars.Len()-1 {
+				typ = "..." + tc.typeString(vars.At(i).Type().(*types.Slice).Elem())
+			}
+			ports = append(ports, fmt.Sprintf("%s %s", name, typ))
+		}
+		return "(" + strings.Join(ports, ", ") + ")"
+	}
+	return fmt.Sprintf("inputs %s and outputs %s", describe(sig.Params(), sig.Variadic(), "arg"), describe(sig.Results(), false, "out"))
+}
+
+// typeString writes a type the way a port's type_name would, qualified by package name.
+func (tc *typeChecker) typeString(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg == tc.pkg {
+			return ""
+		}
+		return pkg.Name()
+	})
+}
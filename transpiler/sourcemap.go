@@ -0,0 +1,181 @@
+package transpiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"strings"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// markerPrefix starts the comment line written in front of the code generated for each node while
+// a file is generated. The markers are taken out again to build the source map. Comments from the
+// graph are written with a space after the slashes, so they never look like a marker.
+const markerPrefix = "//axon:node "
+
+// nodeMarker returns the marker line for a node's code, at the indentation of that code.
+func nodeMarker(node *axon.Node, indent string) string {
+	return indent + markerPrefix + node.Id + "\n"
+}
+
+// portMarker returns the marker line for code a node writes for one of its ports, like the
+// `} else {` of a BRANCH, which starts its "false" block.
+func portMarker(node *axon.Node, port string, indent string) string {
+	return indent + markerPrefix + node.Id + " " + port + "\n"
+}
+
+// SourceMap relates the lines of a generated file to the nodes they were generated from.
+type SourceMap struct {
+	// File is the name of the generated file.
+	File string `json:"file"`
+	// Source is the graph file the code was generated from, if it is known.
+	Source string `json:"source,omitempty"`
+	// Mappings list runs of lines in order. Lines that belong to no node, such as the package
+	// clause and blank lines, are left out.
+	Mappings []Mapping `json:"mappings"`
+}
+
+// Mapping is a run of lines generated for one node, or for one of its ports.
+type Mapping struct {
+	NodeID string `json:"node_id"`
+	Port   string `json:"port,omitempty"`
+	// StartLine and EndLine are the first and last line of the run, counted from 1.
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+}
+
+// NodeAt returns the ID of the node a line was generated for, or "" if it belongs to none.
+func (m *SourceMap) NodeAt(line int) string {
+	if mapping := m.MappingAt(line); mapping != nil {
+		return mapping.NodeID
+	}
+	return ""
+}
+
+// MappingAt returns the run of lines a line belongs to, or nil if it belongs to no node.
+func (m *SourceMap) MappingAt(line int) *Mapping {
+	for i, mapping := range m.Mappings {
+		if mapping.StartLine <= line && line <= mapping.EndLine {
+			return &m.Mappings[i]
+		}
+	}
+	return nil
+}
+
+// LinesOf returns the runs of lines generated for a node. A node has several when its code is
+// interrupted by that of nested nodes, as for a BRANCH around its two blocks.
+func (m *SourceMap) LinesOf(nodeID string) []Mapping {
+	var mappings []Mapping
+	for _, mapping := range m.Mappings {
+		if mapping.NodeID == nodeID {
+			mappings = append(mappings, mapping)
+		}
+	}
+	return mappings
+}
+
+// WriteSourceMap writes a source map as the JSON of a .axmap file.
+func WriteSourceMap(w io.Writer, m *SourceMap) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// ReadSourceMap reads a source map from the JSON of a .axmap file.
+func ReadSourceMap(r io.Reader) (*SourceMap, error) {
+	m := &SourceMap{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+	return m, nil
+}
+
+// extractSourceMap takes the node markers out of generated code and returns the code without them,
+// along with the source map they describe. A marker claims the lines after it, up to the next
+// marker, and resumes after any nested blocks of deeper indentation; a line indented less than a
+// marker closes it. Each line belongs to the innermost node still open. A marker counts as indented
+// like the code it precedes, since gofmt indents a comment before a closing brace like the block
+// it closes.
+//
+// If opts names the graph's source file, markers for nodes with a known line in it are replaced by
+// //line directives instead of being dropped, so the compiler reports positions in the graph.
+func extractSourceMap(code, file string, opts Options) (string, *SourceMap) {
+	type open struct {
+		nodeID, port string
+		indent       int
+	}
+	var stack []open
+	var out strings.Builder
+	sourceMap := &SourceMap{File: file, Source: opts.SourceFile, Mappings: []Mapping{}}
+
+	lines := strings.SplitAfter(code, "\n")
+	lineNo := 0
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		content := strings.TrimLeft(line, "\t")
+		indent := lineIndent(line)
+		if marker, ok := strings.CutPrefix(strings.TrimSpace(content), strings.TrimSpace(markerPrefix)); ok {
+			indent = codeIndent(lines[i+1:])
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			nodeID, port, _ := strings.Cut(strings.TrimSpace(marker), " ")
+			stack = append(stack, open{nodeID: nodeID, port: port, indent: indent})
+			// A directive for a port would only repeat its node's.
+			if line, ok := opts.SourceLines[nodeID]; ok && opts.SourceFile != "" && port == "" {
+				// Line directives only take effect at the start of a line.
+				out.WriteString(fmt.Sprintf("//line %s:%d\n", opts.SourceFile, line))
+				lineNo++
+			}
+			continue
+		}
+
+		out.WriteString(line)
+		lineNo++
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent > indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			continue
+		}
+		top := stack[len(stack)-1]
+		if n := len(sourceMap.Mappings); n > 0 {
+			last := &sourceMap.Mappings[n-1]
+			if last.NodeID == top.nodeID && last.Port == top.port && last.EndLine == lineNo-1 {
+				last.EndLine = lineNo
+				continue
+			}
+		}
+		sourceMap.Mappings = append(sourceMap.Mappings, Mapping{NodeID: top.nodeID, Port: top.port, StartLine: lineNo, EndLine: lineNo})
+	}
+	return out.String(), sourceMap
+}
+
+// codeIndent returns the indentation of the first line of code among lines, skipping markers and
+// blank lines.
+func codeIndent(lines []string) int {
+	for _, line := range lines {
+		if content := strings.TrimSpace(line); content != "" && !strings.HasPrefix(content, strings.TrimSpace(markerPrefix)) {
+			return lineIndent(line)
+		}
+	}
+	return 0
+}
+
+// lineIndent returns the indentation of a line of code. gofmt writes a loop's label one tab to
+// the left of the statement it labels, so a label counts as indented like its loop.
+func lineIndent(line string) int {
+	content := strings.TrimLeft(line, "\t")
+	indent := len(line) - len(content)
+	if label, ok := strings.CutSuffix(strings.TrimSpace(content), ":"); ok && token.IsIdentifier(label) {
+		indent++
+	}
+	return indent
+}
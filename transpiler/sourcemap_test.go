@@ -0,0 +1,74 @@
+package transpiler
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lineOf returns the number, counted from 1, of the first line of code containing s, or 0.
+func lineOf(code, s string) int {
+	for i, line := range strings.Split(code, "\n") {
+		if strings.Contains(line, s) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func TestSourceMap(t *testing.T) {
+	result := compile(t, loadGraph(t, branchGraph), Options{FileName: "main.go"})
+	if result.Diagnostics.HasErrors() {
+		t.Fatalf("unexpected diagnostics: %v", result.Diagnostics)
+	}
+	code, sourceMap := string(result.Files[0].Content), result.SourceMap
+	if strings.Contains(code, markerPrefix) {
+		t.Errorf("node markers left in the generated code:\n%s", code)
+	}
+	if sourceMap.File != "main.go" {
+		t.Errorf("source map is for %q, want main.go", sourceMap.File)
+	}
+
+	for snippet, nodeID := range map[string]string{
+		"if args > one":     "check",
+		"fmt.Println(args)": "print",
+		"package main":      "",
+	} {
+		line := lineOf(code, snippet)
+		if line == 0 {
+			t.Fatalf("generated code has no %q:\n%s", snippet, code)
+		}
+		if got := sourceMap.NodeAt(line); got != nodeID {
+			t.Errorf("line %d, %q, is mapped to node %q, want %q", line, snippet, got, nodeID)
+		}
+	}
+	if runs := sourceMap.LinesOf("check"); len(runs) != 2 {
+		t.Errorf("BRANCH has runs %v, want one for the if and one for its closing brace", runs)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSourceMap(&buf, sourceMap); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSourceMap(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, sourceMap) {
+		t.Errorf("source map read back as %+v, want %+v", read, sourceMap)
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	opts := Options{SourceFile: "/graphs/branch.ax", SourceLines: map[string]int{"print": 12, "check": 10}}
+	code := generate(t, branchGraph, opts)
+	expectCode(t, code,
+		"//line /graphs/branch.ax:10\n\tif args > one {\n",
+		"//line /graphs/branch.ax:12\n\t\tfmt.Println(args)\n",
+		// The closing brace belongs to the BRANCH again.
+		"//line /graphs/branch.ax:10\n\t}\n")
+	if strings.Count(code, "//line ") != 3 {
+		t.Errorf("want directives only for the nodes with a known line:\n%s", code)
+	}
+}
@@ -1,6 +1,10 @@
 package transpiler
 
 import (
+	"fmt"
+	"go/token"
+	"strings"
+
 	"github.com/Advik-B/Axon/pkg/axon"
 )
 
@@ -9,24 +13,145 @@ type transpilationState struct {
 	graph      *axon.Graph
 	nodeMap    map[string]*axon.Node
 	commentMap map[string]*axon.Comment
+	// The name of the generated package.
+	packageName string
+	// The packages the generated code may import: the graph's own imports, and those inferred
+	// from its nodes. Only the ones the code uses are written out.
+	imports []ImportSpec
+	// Map the labels of FUNC_DEF nodes to the names they are generated under, for plain
+	// functions and for methods.
+	funcNames   map[string]string
+	methodNames map[string]string
 	// Maps "nodeID.portName" -> "goVariableName"
 	outputVarMap map[string]string
+	// The Go identifier chosen for each value the graph declares, keyed like outputVarMap, or by
+	// node ID for VAR_DECLARE and WAIT nodes.
+	names map[string]string
+	// LOOP nodes enclosing the block being generated, innermost last.
+	loops []*axon.Node
+	// LOOP and SWITCH nodes enclosing the block being generated, innermost last.
+	// An unlabelled `break` leaves the innermost of these.
+	breakables []*axon.Node
+	// LOOP nodes that need a Go label because a nested loop jumps to them.
+	labelledLoops map[string]bool
+	// Maps a VAR_DECLARE node ID to the variable it declares, for those in scope.
+	variables map[string]variable
+	// Block nesting depth of the code being generated: 0 for globals, 1 for a function body.
+	depth int
+	// Result types of the function being generated; nil while generating main.
+	returnTypes []string
+	// WAIT nodes whose group is used by the function being generated, in order of first use,
+	// and those of them that are actually waited on.
+	groups []*axon.Node
+	waited map[string]bool
+	// Pure nodes computed on demand for the statement being generated; nil outside function bodies.
+	prelude *prelude
+	// Pure nodes whose expression is inlined into their only consumer.
+	foldable map[string]bool
+	// Maps "nodeID.portName" of a folded output to the precedence of its expression.
+	precedence map[string]int
+	// Whether the generated code records the nodes it runs; see Options.Trace.
+	trace bool
+}
+
+// prelude collects the statements that compute pure nodes off the execution flow while another
+// statement is generated, so they can be placed just in front of it.
+type prelude struct {
+	code strings.Builder
+	// outputVarMap entries declared by the code, which stay visible after the statement.
+	vars map[string]string
+	// Pure nodes whose code is being generated, to catch data cycles.
+	active map[string]bool
 }
 
-func newState(graph *axon.Graph) (*transpilationState, error) {
+// variable is a mutable Go variable introduced by a VAR_DECLARE node.
+type variable struct {
+	name  string
+	depth int // The block depth it was declared at.
+}
+
+// scope captures the names visible at one point in the generated code, so they can be
+// restored once a nested block is closed.
+type scope struct {
+	outputVarMap map[string]string
+	variables    map[string]variable
+	depth        int
+}
+
+func newState(graph *axon.Graph, opts Options) (*transpilationState, error) {
 	state := &transpilationState{
-		graph:        graph,
-		nodeMap:      make(map[string]*axon.Node),
-		commentMap:   make(map[string]*axon.Comment),
-		outputVarMap: make(map[string]string),
+		graph:         graph,
+		nodeMap:       make(map[string]*axon.Node),
+		commentMap:    make(map[string]*axon.Comment),
+		packageName:   "main",
+		funcNames:     make(map[string]string),
+		methodNames:   make(map[string]string),
+		outputVarMap:  make(map[string]string),
+		labelledLoops: make(map[string]bool),
+		variables:     make(map[string]variable),
+		foldable:      make(map[string]bool),
+		precedence:    make(map[string]int),
+	}
+
+	if opts.PackageName != "" {
+		if !token.IsIdentifier(opts.PackageName) || opts.PackageName == "_" {
+			return nil, fmt.Errorf("package name '%s' is not a valid Go identifier", opts.PackageName)
+		}
+		state.packageName = opts.PackageName
 	}
 
 	for _, node := range graph.Nodes {
 		state.nodeMap[node.Id] = node
+		if node.Type == axon.NodeType_FUNC_DEF {
+			if axon.FindPort(node.Inputs, "receiver") != nil {
+				state.methodNames[node.Label] = funcName(node.Label, opts.FuncNaming)
+			} else {
+				state.funcNames[node.Label] = funcName(node.Label, opts.FuncNaming)
+			}
+		}
 	}
 	for _, comment := range graph.Comments {
 		state.commentMap[comment.Id] = comment
 	}
+	state.trace = opts.Trace != ""
+	if !opts.NoFold && !state.trace {
+		state.foldable = findFoldableNodes(graph, state.nodeMap)
+	}
 
 	return state, nil
-}
\ No newline at end of file
+}
+
+// enclosingLoop returns the LOOP node with the given ID if the current block is inside its body.
+func (s *transpilationState) enclosingLoop(nodeID string) *axon.Node {
+	for _, loop := range s.loops {
+		if loop.Id == nodeID {
+			return loop
+		}
+	}
+	return nil
+}
+
+// enterScope opens a nested block. Names declared until the matching exitScope call
+// are discarded afterwards, as they are out of scope in Go.
+func (s *transpilationState) enterScope() scope {
+	saved := scope{
+		outputVarMap: make(map[string]string, len(s.outputVarMap)),
+		variables:    make(map[string]variable, len(s.variables)),
+		depth:        s.depth,
+	}
+	for k, v := range s.outputVarMap {
+		saved.outputVarMap[k] = v
+	}
+	for k, v := range s.variables {
+		saved.variables[k] = v
+	}
+	s.depth++
+	return saved
+}
+
+// exitScope closes a block opened by enterScope.
+func (s *transpilationState) exitScope(saved scope) {
+	s.outputVarMap = saved.outputVarMap
+	s.variables = saved.variables
+	s.depth = saved.depth
+}
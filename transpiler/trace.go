@@ -0,0 +1,132 @@
+package transpiler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// traceFunc is the function the code traced builds call after each node runs. It is declared in
+// traceFileName, next to the generated code.
+const (
+	traceFunc     = "axonTrace"
+	traceFileName = "axon_trace.go"
+)
+
+// TraceEvent is one line of the trace a build with Options.Trace writes as it runs: a node that
+// ran, and the values its outputs produced, as JSON. Values that cannot be encoded as JSON are
+// recorded as their fmt.Sprint text, and errors as their message.
+type TraceEvent struct {
+	// Seq numbers the events from 1 in the order they were recorded.
+	Seq     int                        `json:"seq"`
+	Time    time.Time                  `json:"time"`
+	Node    string                     `json:"node"`
+	Outputs map[string]json.RawMessage `json:"outputs,omitempty"`
+}
+
+// ReadTrace reads the events of a trace, one JSON object per line.
+func ReadTrace(r io.Reader) ([]TraceEvent, error) {
+	var events []TraceEvent
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20) // Values, like the contents of a file, can make for long lines.
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var event TraceEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// traceCall returns the statement that records a node's run in a traced build, passing the values
+// of those of its outputs that are held in variables. Outputs discarded into IGNORE nodes have no
+// variable to pass. Without tracing it returns "".
+func traceCall(state *transpilationState, node *axon.Node) string {
+	if !state.trace {
+		return ""
+	}
+	args := []string{fmt.Sprintf("%q", node.Id)}
+	for _, port := range node.Outputs {
+		if isPortConnectedToIgnore(state, node.Id, port.Name) {
+			continue
+		}
+		if value, ok := state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, port.Name)]; ok {
+			args = append(args, fmt.Sprintf("%q", port.Name), value)
+		}
+	}
+	return fmt.Sprintf("\t%s(%s)\n", traceFunc, strings.Join(args, ", "))
+}
+
+// traceRuntime returns the file that declares traceFunc for a traced build. Events are written to
+// the file the AXON_TRACE environment variable names, or else to path.
+func traceRuntime(packageName, path string) []byte {
+	code := fmt.Sprintf(`// Code generated by axon build --trace. DO NOT EDIT.
+
+package %s
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// %[2]s records that a node ran, with its outputs given as pairs of port name and value, as one
+// line of JSON in the trace file 'axon preview --trace' replays.
+var %[2]s = func() func(node string, outputs ...any) {
+	path := os.Getenv("AXON_TRACE")
+	if path == "" {
+		path = %[3]q
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "axon: cannot record trace: %%v\n", err)
+	}
+	var mu sync.Mutex
+	seq := 0
+	return func(node string, outputs ...any) {
+		if file == nil {
+			return
+		}
+		values := make(map[string]json.RawMessage, len(outputs)/2)
+		for i := 0; i+1 < len(outputs); i += 2 {
+			value := outputs[i+1]
+			if err, ok := value.(error); ok && err != nil {
+				value = err.Error()
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				data, _ = json.Marshal(fmt.Sprint(value))
+			}
+			values[outputs[i].(string)] = data
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		seq++
+		line, _ := json.Marshal(struct {
+			Seq     int                        `+"`json:\"seq\"`"+`
+			Time    time.Time                  `+"`json:\"time\"`"+`
+			Node    string                     `+"`json:\"node\"`"+`
+			Outputs map[string]json.RawMessage `+"`json:\"outputs,omitempty\"`"+`
+		}{seq, time.Now(), node, values})
+		file.Write(append(line, '\n'))
+	}
+}()
+`, packageName, traceFunc, path)
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return []byte(code)
+	}
+	return formatted
+}
@@ -1,75 +1,249 @@
 package transpiler
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"slices"
 	"strings"
 
 	"github.com/Advik-B/Axon/pkg/axon"
 )
 
-// Transpile converts an Axon graph into a complete Go source file.
-func Transpile(graph *axon.Graph) (string, error) {
-	state, err := newState(graph)
+// Options controls how a graph is transpiled. The zero value gives the default output.
+type Options struct {
+	// NoFold keeps one variable per node, instead of inlining pure values that are used
+	// only once into the expression that uses them.
+	NoFold bool
+	// PackageName is the name of the generated package. It defaults to "main".
+	PackageName string
+	// NoMain leaves out the main function, and the START flow it would run, so the graph's
+	// functions can be generated as a library. A package other than main must set it.
+	NoMain bool
+	// FuncNaming controls how FUNC_DEF labels become Go function names.
+	FuncNaming FuncNaming
+	// FileName is the name of the generated file. It defaults to main.go, or to the package
+	// name with a .go extension for a package other than main.
+	FileName string
+	// SourceFile is the path of the graph file, recorded in the source map. With SourceLines,
+	// which gives the line of the graph file that defines each node, the code for every node is
+	// preceded by a //line directive pointing there, so compiler errors and stack traces name the
+	// node's place in the graph rather than the generated file.
+	SourceFile  string
+	SourceLines map[string]int
+	// Trace builds the program so that it records every node it runs, with the values of its
+	// outputs, to the file named by the AXON_TRACE environment variable, or else to Trace. The
+	// recording code is written to a second file, and values are no longer folded into
+	// expressions, so each has a variable to record. See ReadTrace.
+	Trace string
+}
+
+// FuncNaming is a way of deriving Go function names from FUNC_DEF labels.
+type FuncNaming int
+
+const (
+	// FuncNamesAsLabeled uses each label as the function name, unchanged if it is a valid Go
+	// identifier.
+	FuncNamesAsLabeled FuncNaming = iota
+	// FuncNamesExported capitalises each name, so the functions can be called from other packages.
+	FuncNamesExported
+	// FuncNamesUnexported lowers the first letter of each name, keeping the functions private.
+	FuncNamesUnexported
+)
+
+// Transpile converts an Axon graph into a complete Go source file. If the graph cannot be
+// transpiled, the error is a Diagnostics listing every problem that was found. It is a shorthand
+// for Compile, for callers that only need the code.
+func Transpile(graph *axon.Graph, opts Options) (string, error) {
+	result, err := Compile(context.Background(), graph, opts)
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize transpiler state: %w", err)
+		return "", err
 	}
+	if result.Diagnostics.HasErrors() {
+		return "", result.Diagnostics
+	}
+	return string(result.Files[0].Content), nil
+}
+
+// generateFile writes the Go source file for a graph whose flows have been validated.
+// Code is generated for as much of the graph as possible, so the problems in every function
+// are found in one pass.
+func generateFile(state *transpilationState, opts Options, entryPoints map[axon.NodeType][]*Flow, globals []*axon.Node) (string, Diagnostics) {
+	var diags Diagnostics
+	var body strings.Builder
 
-	// 1. Identify all execution graphs (main function + global functions) and globals.
-	entryPoints, globals, err := findExecutionScopes(graph)
+	// 1. Transpile all global definitions (structs, constants, functions)
+	globalCode, err := generateGlobals(state, globals, entryPoints)
 	if err != nil {
-		return "", fmt.Errorf("graph validation failed: %w", err)
+		diags.addError(err, CodeInvalidNode, nil)
 	}
+	body.WriteString(globalCode)
 
-	var finalCode strings.Builder
+	// 2. Find the main execution flow (the one starting with a START node)
+	// A graph with no START node is a library that only defines functions.
+	mainFlows := entryPoints[axon.NodeType_START]
+	switch {
+	case len(mainFlows) == 0 || opts.NoMain:
+	case state.packageName != "main":
+		diags = append(diags, errorAt(CodeMainInLibrary, mainFlows[0].Entry, "", "the graph has a START node, but package %s cannot have a main function; leave out main to generate it as a library", state.packageName))
+	default:
+		// 3. Transpile the main function body
+		body.WriteString(nodeMarker(mainFlows[0].Entry, ""))
+		body.WriteString("func main() {\n")
+		saved := state.enterScope()
+		bodyCode, err := generateFunctionBody(state, mainFlows[0].Entry)
+		state.exitScope(saved)
+		if err != nil {
+			diags.addError(err, CodeInvalidNode, mainFlows[0].Entry)
+		}
+		body.WriteString(bodyCode)
+		body.WriteString("}\n")
+	}
+	if diags.HasErrors() {
+		return "", diags
+	}
 
-	// 2. Write the package and import block
-	finalCode.WriteString("package main\n\n")
-	if len(graph.Imports) > 0 {
-		finalCode.WriteString("import (\n")
-		for _, imp := range graph.Imports {
-			finalCode.WriteString(fmt.Sprintf("\t\"%s\"\n", imp))
+	// 4. Write the package clause and the imports the code uses, now that it is known which
+	// those are, and format the whole file.
+	packageClause := fmt.Sprintf("package %s\n\n", state.packageName)
+	used, invalid := usedPackages(packageClause, body.String(), state.imports)
+	if invalid != nil {
+		return "", append(diags, invalid)
+	}
+	var imports []ImportSpec
+	for _, spec := range state.imports {
+		switch {
+		case spec.Alias == "_" || spec.Alias == "." || used[spec.Name()]:
+			imports = append(imports, spec)
+		case slices.Contains(state.graph.Imports, spec.String()) || slices.Contains(state.graph.Imports, spec.Path):
+			diags = append(diags, warningAt(CodeUnusedImport, nil, "", "package \"%s\" is imported but not used by the generated code, so it was left out", spec.Path))
 		}
-		finalCode.WriteString(")\n\n")
 	}
+	code, err := format.Source([]byte(packageClause + importBlock(imports) + body.String()))
+	if err != nil {
+		return "", append(diags, errorAt(CodeInvalidGo, nil, "", "the generated code could not be formatted: %v", err))
+	}
+	return string(code), diags
+}
 
-	// 3. Transpile all global definitions (structs, constants, functions)
-	globalCode, err := generateGlobals(state, globals, entryPoints)
+// usedPackages parses generated code and returns the names of the imports it refers to. The
+// code is type-checked with every candidate import in scope, as an empty package, so a qualifier
+// counts only when it resolves to the import and not to something the code declares. If the code
+// does not parse, the error is a diagnostic for the node whose code is at fault.
+func usedPackages(packageClause, body string, candidates []ImportSpec) (map[string]bool, *Diagnostic) {
+	var named []ImportSpec
+	paths := make(map[string]string)
+	for _, spec := range candidates {
+		if spec.Alias != "_" && spec.Alias != "." {
+			named = append(named, spec)
+			paths[spec.Path] = spec.Name()
+		}
+	}
+	code := packageClause + importBlock(named) + body
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, 0)
 	if err != nil {
-		return "", err
+		var list scanner.ErrorList
+		if errors.As(err, &list) && len(list) > 0 {
+			first := list[0]
+			return nil, &Diagnostic{Severity: SeverityError, Code: CodeInvalidGo, NodeID: nodeBefore(code, first.Pos), Message: fmt.Sprintf("the generated code is not valid Go: %s", first.Msg)}
+		}
+		return nil, errorAt(CodeInvalidGo, nil, "", "the generated code is not valid Go: %v", err)
 	}
-	finalCode.WriteString(globalCode)
 
-	// 4. Find the main execution flow (the one starting with a START node)
-	mainFlows, ok := entryPoints[axon.NodeType_START]
-	if !ok || len(mainFlows) == 0 {
-		// This case is for libraries that only define functions but have no main.
-		fmt.Println("INFO: No START node found. Generating a library file without a main function.")
-		return finalCode.String(), nil
+	info := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	conf := types.Config{
+		Importer: stubImporter(paths),
+		// Every use of a stub package is an error, as it declares nothing; only what the
+		// qualifiers resolve to matters here.
+		Error: func(error) {},
 	}
+	conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
 
-	// 5. Transpile the main function body
-	finalCode.WriteString("func main() {\n")
-	bodyCode, err := generateFunctionBody(state, mainFlows[0].nodes)
-	if err != nil {
-		return "", err
+	used := make(map[string]bool)
+	for ident, obj := range info.Uses {
+		if _, ok := obj.(*types.PkgName); ok {
+			used[ident.Name] = true
+		}
 	}
-	finalCode.WriteString(bodyCode)
-	finalCode.WriteString("}\n")
+	return used, nil
+}
+
+// stubImporter imports every path as an empty package with the given name.
+type stubImporter map[string]string
 
-	return finalCode.String(), nil
+func (s stubImporter) Import(path string) (*types.Package, error) {
+	pkg := types.NewPackage(path, cmp.Or(s[path], ImportName(path)))
+	pkg.MarkComplete()
+	return pkg, nil
 }
 
-// generateGlobals transpiles all top-level definitions.
-func generateGlobals(state *transpilationState, globals []*axon.Node, funcs map[axon.NodeType][]*flow) (string, error) {
+// nodeBefore returns the ID of the node whose code a syntax error was found in, or "" if there is
+// none. An error at the first token of a line, like the "var" after an unfinished expression, is
+// blamed on the code before it.
+func nodeBefore(code string, pos token.Position) string {
+	lines := strings.Split(code, "\n")
+	i := min(pos.Line, len(lines)) - 1
+	if i >= 0 && pos.Column <= len(lines[i])-len(strings.TrimLeft(lines[i], "\t"))+1 {
+		i--
+		for i >= 0 && (strings.TrimSpace(lines[i]) == "" || strings.HasPrefix(strings.TrimSpace(lines[i]), strings.TrimSpace(markerPrefix))) {
+			i--
+		}
+	}
+	for ; i >= 0; i-- {
+		if marker, ok := strings.CutPrefix(strings.TrimSpace(lines[i]), strings.TrimSpace(markerPrefix)); ok {
+			nodeID, _, _ := strings.Cut(strings.TrimSpace(marker), " ")
+			return nodeID
+		}
+	}
+	return ""
+}
+
+// importBlock writes the import declaration for the given packages, with the standard library
+// first and every other package after it, as goimports would group them.
+func importBlock(imports []ImportSpec) string {
+	if len(imports) == 0 {
+		return ""
+	}
+	imports = slices.Clone(imports)
+	slices.SortFunc(imports, func(a, b ImportSpec) int { return strings.Compare(a.Path, b.Path) })
+	var std, other []string
+	for _, spec := range imports {
+		if first, _, _ := strings.Cut(spec.Path, "/"); strings.Contains(first, ".") {
+			other = append(other, "\t"+spec.String()+"\n")
+		} else {
+			std = append(std, "\t"+spec.String()+"\n")
+		}
+	}
+	groups := strings.Join(std, "")
+	if len(std) > 0 && len(other) > 0 {
+		groups += "\n"
+	}
+	return "import (\n" + groups + strings.Join(other, "") + ")\n\n"
+}
+
+// generateGlobals transpiles all top-level definitions. A definition that fails is reported
+// and skipped, so the problems in every function are found in one pass.
+func generateGlobals(state *transpilationState, globals []*axon.Node, funcs map[axon.NodeType][]*Flow) (string, error) {
 	var sb strings.Builder
+	var diags Diagnostics
 
 	// Generate Structs first
 	for _, node := range globals {
 		if node.Type == axon.NodeType_STRUCT_DEF {
 			code, err := generateStructDef(state, node)
 			if err != nil {
-				return "", err
+				diags.addError(err, CodeInvalidNode, node)
+				continue
 			}
+			sb.WriteString(nodeMarker(node, ""))
 			sb.WriteString(code)
 		}
 	}
@@ -78,11 +252,26 @@ func generateGlobals(state *transpilationState, globals []*axon.Node, funcs map[
 	for _, node := range globals {
 		if node.Type == axon.NodeType_CONSTANT {
 			// Register global constants so they are available everywhere.
-			state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = node.Label
+			state.outputVarMap[fmt.Sprintf("%s.%s", node.Id, node.Outputs[0].Name)] = state.nameOfOutput(node, node.Outputs[0])
 			code, err := generateConstant(state, node, true) // true for global
 			if err != nil {
-				return "", err
+				diags.addError(err, CodeInvalidNode, node)
+				continue
+			}
+			sb.WriteString(nodeMarker(node, ""))
+			sb.WriteString(code)
+		}
+	}
+
+	// Generate global variables, which may be initialised from the constants above
+	for _, node := range globals {
+		if node.Type == axon.NodeType_VAR_DECLARE {
+			code, err := generateVarDeclare(state, node, true) // true for global
+			if err != nil {
+				diags.addError(err, CodeInvalidNode, node)
+				continue
 			}
+			sb.WriteString(nodeMarker(node, ""))
 			sb.WriteString(code)
 		}
 	}
@@ -90,34 +279,170 @@ func generateGlobals(state *transpilationState, globals []*axon.Node, funcs map[
 	// Generate global functions/methods
 	if funcDefs, ok := funcs[axon.NodeType_FUNC_DEF]; ok {
 		for _, flow := range funcDefs {
-			code, err := generateFuncDef(state, flow.entryNode, flow.nodes)
+			code, err := generateFuncDef(state, flow.Entry, flow.Nodes)
 			if err != nil {
-				return "", err
+				diags.addError(err, CodeInvalidNode, flow.Entry)
+				continue
 			}
+			sb.WriteString(nodeMarker(flow.Entry, ""))
 			sb.WriteString(code)
 		}
 	}
+	if len(diags) > 0 {
+		return "", diags
+	}
 	return sb.String(), nil
 }
 
-// generateFunctionBody transpiles the nodes within a function's scope.
-func generateFunctionBody(state *transpilationState, nodes []*axon.Node) (string, error) {
+// generateFunctionBody transpiles the nodes within a function's scope by following
+// the execution edges from its entry node.
+func generateFunctionBody(state *transpilationState, entryNode *axon.Node) (string, error) {
+	edge, err := nextInChain(state, entryNode)
+	if err != nil {
+		return "", err
+	}
+	groups, waited := state.groups, state.waited
+	state.groups, state.waited = nil, make(map[string]bool)
+	defer func() { state.groups, state.waited = groups, waited }()
+
+	body, err := generateBlock(state, edge, blockContext{tail: true})
+	if err != nil {
+		return "", err
+	}
+
+	// Goroutine groups are declared up front, so every GO node in the function can join them.
 	var sb strings.Builder
-	// Reverse the nodes for correct execution order from the reversed DFS
-	reversedNodes := make([]*axon.Node, len(nodes))
-	for i := range nodes {
-		reversedNodes[i] = nodes[len(nodes)-1-i]
+	for _, wait := range state.groups {
+		if !state.waited[wait.Id] {
+			return "", errorAt(CodeGroupNotInFunction, wait, "", "goroutines in '%s' join the group of WAIT node '%s', but it does not run in the same function", entryNode.Label, wait.Label)
+		}
+		decl, err := generateGroupDecl(state, wait)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(decl)
 	}
+	sb.WriteString(traceCall(state, entryNode))
+	sb.WriteString(body)
+	return sb.String(), nil
+}
+
+// blockContext describes where a block of statements sits within its function.
+type blockContext struct {
+	// stopID is the node where an enclosing branch rejoins; the block ends just before it.
+	stopID string
+	// tail reports whether the end of the block is also the end of the function,
+	// in which case an END node needs no explicit return.
+	tail bool
+	// loopTail reports whether the end of the block is also the end of the innermost
+	// LOOP body, in which case jumping back to that loop needs no explicit continue.
+	loopTail bool
+}
 
-	for _, node := range reversedNodes {
+// generateBlock emits the chain of statements entered through edge. It ends before ctx.stopID,
+// after a terminator, or with a jump back into an enclosing LOOP.
+func generateBlock(state *transpilationState, edge *axon.ExecEdge, ctx blockContext) (string, error) {
+	var sb strings.Builder
+	for edge != nil && edge.ToNodeId != ctx.stopID {
+		if loop := state.enclosingLoop(edge.ToNodeId); loop != nil {
+			sb.WriteString(generateLoopJump(state, loop, edge, ctx))
+			break
+		}
+
+		node := state.nodeMap[edge.ToNodeId]
 		// Transpile comments attached to the node
-		sb.WriteString(generateCommentBlock(state, node))
+		comment := generateCommentBlock(state, node)
+
+		// Pure nodes this statement reads are computed just before it.
+		outer := state.prelude
+		state.prelude = &prelude{vars: make(map[string]string), active: make(map[string]bool)}
 
-		code, err := generateNodeCode(state, node)
+		var code string
+		var err error
+		// A traced build records control nodes as they are reached, and others once they have run.
+		reached, traced := traceCall(state, node), false
+		switch node.Type {
+		case axon.NodeType_BRANCH:
+			code, edge, err = generateBranch(state, node, ctx)
+		case axon.NodeType_LOOP:
+			// Recorded on every iteration instead, with its index.
+			code, edge, err = generateLoop(state, node)
+			traced = true
+		case axon.NodeType_SWITCH:
+			code, edge, err = generateSwitch(state, node, ctx)
+		case axon.NodeType_ERROR_CHECK:
+			code, edge, err = generateErrorCheck(state, node)
+		case axon.NodeType_GO:
+			code, edge, err = generateGo(state, node)
+		case axon.NodeType_DEFER:
+			code, edge, err = generateDefer(state, node)
+		case axon.NodeType_RECOVER:
+			code, edge, err = generateRecover(state, node)
+		case axon.NodeType_SELECT:
+			code, edge, err = generateSelect(state, node, ctx)
+		case axon.NodeType_END:
+			if !ctx.tail {
+				code = generateBareReturn(state)
+			}
+			edge = nil
+		default:
+			if code, err = generateNodeCode(state, node); err == nil {
+				edge, err = nextInChain(state, node)
+			}
+			if node.Type != axon.NodeType_RETURN {
+				code += traceCall(state, node)
+				traced = true
+			}
+			// A path that simply ends inside a loop body moves on to the next iteration,
+			// which has to be explicit unless it is the end of the body anyway.
+			if err == nil && edge == nil && node.Type != axon.NodeType_RETURN && len(state.loops) > 0 && !ctx.loopTail {
+				code += "\tcontinue\n"
+			}
+		}
+		if !traced {
+			code = reached + code
+		}
+		pre := state.prelude
+		state.prelude = outer
 		if err != nil {
-			return "", fmt.Errorf("error generating code for node %s (%s): %w", node.Label, node.Id, err)
+			return "", asDiagnostic(err, CodeInvalidNode, node)
+		}
+		// The statement may have closed a scope of its own, but the pure values stay declared.
+		for key, varName := range pre.vars {
+			state.outputVarMap[key] = varName
 		}
+		sb.WriteString(pre.code.String())
+		if comment != "" || code != "" {
+			sb.WriteString(nodeMarker(node, "\t"))
+		}
+		sb.WriteString(comment)
 		sb.WriteString(code)
 	}
 	return sb.String(), nil
-}
\ No newline at end of file
+}
+
+// generateNestedBlock emits a block one level deeper than its parent. Variables declared
+// inside it are forgotten afterwards, as they are out of scope in Go.
+func generateNestedBlock(state *transpilationState, edge *axon.ExecEdge, ctx blockContext) (string, error) {
+	saved := state.enterScope()
+	code, err := generateBlock(state, edge, ctx)
+	state.exitScope(saved)
+	if err != nil {
+		return "", err
+	}
+	return indentBlock(code), nil
+}
+
+// indentBlock shifts every line of a generated block one tab to the right.
+func indentBlock(code string) string {
+	if code == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSuffix(code, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "\t" + line
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
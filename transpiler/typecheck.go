@@ -0,0 +1,276 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"sync"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+)
+
+// typeChecker resolves the free-text type names of ports into Go types, in a package that has the
+// graph's imports and STRUCT_DEF types in scope.
+type typeChecker struct {
+	fset     *token.FileSet
+	pkg      *types.Package
+	scopePos token.Pos
+	// The packages that could be imported, by the name the graph refers to them by; types from
+	// any other package are unknown.
+	imported map[string]*types.Package
+	// The names of the methods the graph's FUNC_DEF nodes define.
+	graphMethods map[string]bool
+	cache        map[string]resolvedType
+}
+
+type resolvedType struct {
+	typ types.Type // nil when the type cannot be known, e.g. its package is unavailable
+	err error
+}
+
+// checkTypes verifies that every data edge joins ports of compatible types, that unary OPERATOR
+// casts are valid conversions, that binary OPERATOR operands match, and that FUNCTION nodes match
+// the signatures of the functions they call. Ports whose type cannot be resolved, such as those
+// from packages that are not available, are skipped, with a warning for each such package.
+func checkTypes(graph *axon.Graph, nodeMap map[string]*axon.Node, imports []ImportSpec) Diagnostics {
+	tc, diags, err := newTypeChecker(graph, imports)
+	if err != nil {
+		return Diagnostics{asDiagnostic(err, CodeInvalidStructDef, nil)}
+	}
+
+	for _, edge := range graph.DataEdges {
+		from, to := nodeMap[edge.FromNodeId], nodeMap[edge.ToNodeId]
+		if from == nil || to == nil {
+			known, port := from, edge.FromPort
+			if from == nil {
+				known, port = to, edge.ToPort
+			}
+			diags = append(diags, errorAt(CodeUnknownNode, known, port, "data edge %s.%s -> %s.%s references a node that does not exist", edge.FromNodeId, edge.FromPort, edge.ToNodeId, edge.ToPort))
+			continue
+		}
+		fromPort, toPort := axon.FindPort(from.Outputs, edge.FromPort), axon.FindPort(to.Inputs, edge.ToPort)
+		if fromPort == nil {
+			diags = append(diags, errorAt(CodeUnknownPort, from, edge.FromPort, "node '%s' has no output named '%s'", from.Label, edge.FromPort))
+			continue
+		}
+		if toPort == nil {
+			diags = append(diags, errorAt(CodeUnknownPort, to, edge.ToPort, "node '%s' has no input named '%s'", to.Label, edge.ToPort))
+			continue
+		}
+
+		src, err := tc.portType(from, fromPort)
+		if err != nil {
+			diags.addError(err, CodeUnknownType, from)
+			continue
+		}
+		if from.Type == axon.NodeType_SWITCH {
+			src = nil // The value a type switch binds has the type of the clause it is used in.
+		}
+		dst, err := tc.portType(to, toPort)
+		if err != nil {
+			diags.addError(err, CodeUnknownType, to)
+			continue
+		}
+		if src != nil && dst != nil && !types.AssignableTo(src, dst) {
+			diags = append(diags, errorAt(CodeTypeMismatch, to, toPort.Name, "type mismatch: output '%s' of node '%s' is %s, which cannot be used as input '%s' of node '%s' (%s)", fromPort.Name, from.Label, src, toPort.Name, to.Label, dst))
+		}
+	}
+
+	for _, node := range graph.Nodes {
+		var err error
+		switch node.Type {
+		case axon.NodeType_OPERATOR:
+			err = tc.checkOperator(node)
+		case axon.NodeType_FUNCTION:
+			err = tc.checkSignature(node)
+		}
+		if err != nil {
+			diags.addError(err, CodeInvalidNode, node)
+		}
+	}
+	return diags
+}
+
+// sourceImporter loads packages by type-checking their source, so modules from the build's go.mod
+// resolve as well as the standard library.
+type sourceImporter struct {
+	sync.Mutex
+	importer types.Importer
+}
+
+// packages is shared between graphs, as loading a package from source is slow.
+var packages sourceImporter
+
+func (s *sourceImporter) Import(path string) (*types.Package, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.importer == nil {
+		s.importer = importer.ForCompiler(token.NewFileSet(), "source", nil)
+	}
+	return s.importer.Import(path)
+}
+
+// newTypeChecker loads the graph's imports and declares its STRUCT_DEF types. Imports that cannot
+// be loaded are reported as warnings, as the code may still build where they are available.
+func newTypeChecker(graph *axon.Graph, imports []ImportSpec) (*typeChecker, Diagnostics, error) {
+	tc := &typeChecker{
+		fset:         token.NewFileSet(),
+		imported:     make(map[string]*types.Package),
+		graphMethods: make(map[string]bool),
+		cache:        make(map[string]resolvedType),
+	}
+	imp := &packages
+
+	var diags Diagnostics
+	var src strings.Builder
+	src.WriteString("package main\n\n")
+	for _, spec := range imports {
+		if pkg, err := imp.Import(spec.Path); err == nil {
+			tc.imported[spec.Name()] = pkg
+		} else {
+			diags = append(diags, warningAt(CodePackageNotFound, nil, "", "package \"%s\" could not be loaded, so the types and functions used from it are not checked", spec.Path))
+		}
+		src.WriteString(fmt.Sprintf("import %s\n", spec))
+	}
+	for _, node := range graph.Nodes {
+		if node.Type == axon.NodeType_FUNC_DEF && axon.FindPort(node.Inputs, "receiver") != nil {
+			tc.graphMethods[node.Label] = true
+		}
+		if node.Type != axon.NodeType_STRUCT_DEF {
+			continue
+		}
+		src.WriteString(fmt.Sprintf("\ntype %s struct {\n", node.Label))
+		for _, field := range node.Inputs {
+			src.WriteString(fmt.Sprintf("\t%s %s\n", field.Name, field.TypeName))
+		}
+		src.WriteString("}\n")
+	}
+
+	file, err := parser.ParseFile(tc.fset, "types.go", src.String(), 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid import or struct definition: %w", err)
+	}
+	// Errors such as unused imports do not matter here; anything that failed to resolve is unknown.
+	conf := types.Config{Importer: imp, Error: func(error) {}}
+	tc.pkg, _ = conf.Check("main", tc.fset, []*ast.File{file}, nil)
+	tc.scopePos = file.End() - 1
+	return tc, diags, nil
+}
+
+// resolve returns the Go type a type name denotes, or nil if it cannot be known.
+func (tc *typeChecker) resolve(typeName string) (types.Type, error) {
+	if r, ok := tc.cache[typeName]; ok {
+		return r.typ, r.err
+	}
+	r := tc.eval(typeName)
+	tc.cache[typeName] = r
+	return r.typ, r.err
+}
+
+func (tc *typeChecker) eval(typeName string) resolvedType {
+	expr, err := parser.ParseExpr(typeName)
+	if err != nil {
+		return resolvedType{err: fmt.Errorf("'%s' is not a valid Go type", typeName)}
+	}
+	for _, pkg := range qualifiers(expr) {
+		if tc.imported[pkg] == nil {
+			return resolvedType{} // From a package that is not imported or not available.
+		}
+	}
+	tv, err := types.Eval(tc.fset, tc.pkg, tc.scopePos, typeName)
+	if err != nil {
+		return resolvedType{err: fmt.Errorf("unknown type '%s'", typeName)}
+	}
+	if !tv.IsType() {
+		return resolvedType{err: fmt.Errorf("'%s' is not a type", typeName)}
+	}
+	if tv.Type == nil || tv.Type == types.Typ[types.Invalid] {
+		return resolvedType{}
+	}
+	return resolvedType{typ: tv.Type}
+}
+
+// portType resolves the type of a node's port, naming the port in any error.
+func (tc *typeChecker) portType(node *axon.Node, port *axon.Port) (types.Type, error) {
+	if port.TypeName == "" {
+		return nil, nil
+	}
+	typ, err := tc.resolve(port.TypeName)
+	if err != nil {
+		return nil, errorAt(CodeUnknownType, node, port.Name, "port '%s' of node '%s': %s", port.Name, node.Label, err)
+	}
+	return typ, nil
+}
+
+// checkOperator checks what a single OPERATOR node does with its operands: a unary operator whose
+// op names a type must be a valid conversion, and a binary operator needs operands of one type.
+func (tc *typeChecker) checkOperator(node *axon.Node) error {
+	op := node.Config["op"]
+	if len(node.Outputs) != 1 {
+		return nil
+	}
+	out, err := tc.portType(node, node.Outputs[0])
+	if err != nil {
+		return err
+	}
+
+	switch len(node.Inputs) {
+	case 1:
+		in, err := tc.portType(node, node.Inputs[0])
+		if err != nil || in == nil {
+			return err
+		}
+		target, err := tc.resolve(op)
+		if err != nil || target == nil {
+			return nil // Not a conversion, e.g. a builtin like len.
+		}
+		if !types.ConvertibleTo(in, target) {
+			return errorAt(CodeInvalidConversion, node, "", "operator node '%s' cannot convert %s to %s", node.Label, in, target)
+		}
+		if out != nil && !types.AssignableTo(target, out) {
+			return errorAt(CodeTypeMismatch, node, node.Outputs[0].Name, "operator node '%s' converts to %s, but its output '%s' is %s", node.Label, target, node.Outputs[0].Name, out)
+		}
+	case 2:
+		prec, ok := binaryPrecedence[op]
+		if !ok || strings.HasPrefix(op, "&") || ('A' <= op[0] && op[0] <= 'Z') {
+			return nil // Struct instantiation, as generateOperator reads it.
+		}
+		a, err := tc.portType(node, node.Inputs[0])
+		if err != nil {
+			return err
+		}
+		b, err := tc.portType(node, node.Inputs[1])
+		if err != nil || a == nil || b == nil {
+			return err
+		}
+		if op != "<<" && op != ">>" && !types.Identical(a, b) {
+			return errorAt(CodeOperandMismatch, node, "", "operator node '%s' applies '%s' to mismatched types %s and %s", node.Label, op, a, b)
+		}
+		result := a
+		if prec == binaryPrecedence["=="] || op == "&&" || op == "||" {
+			result = types.Typ[types.UntypedBool]
+		}
+		if out != nil && !types.AssignableTo(result, out) {
+			return errorAt(CodeTypeMismatch, node, node.Outputs[0].Name, "operator node '%s' produces %s, but its output '%s' is %s", node.Label, result, node.Outputs[0].Name, out)
+		}
+	}
+	return nil
+}
+
+// qualifiers returns the package names a type expression refers to, like "os" in "*os.File".
+func qualifiers(expr ast.Expr) []string {
+	var names []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				names = append(names, ident.Name)
+			}
+		}
+		return true
+	})
+	return names
+}
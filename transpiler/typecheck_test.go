@@ -0,0 +1,180 @@
+package transpiler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Advik-B/Axon/pkg/axon"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// loadGraph reads a graph written as it would be in a .ax file.
+func loadGraph(t *testing.T, src string) *axon.Graph {
+	t.Helper()
+	var graph axon.Graph
+	if err := protojson.Unmarshal([]byte(src), &graph); err != nil {
+		t.Fatalf("invalid graph: %v", err)
+	}
+	return &graph
+}
+
+// compile compiles a graph, failing the test if that fails other than with diagnostics.
+func compile(t *testing.T, graph *axon.Graph, opts Options) *Result {
+	t.Helper()
+	result, err := Compile(context.Background(), graph, opts)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return result
+}
+
+// find returns the first diagnostic with the given code, or nil.
+func find(diags Diagnostics, code string) *Diagnostic {
+	for _, d := range diags {
+		if d.Code == code {
+			return d
+		}
+	}
+	return nil
+}
+
+// greetGraph defines a method Greet on User and calls it from main, importing extra packages.
+const greetGraph = `{
+  "id": "greet", "name": "greet", "imports": ["fmt" %s],
+  "nodes": [
+    {"id": "user", "type": "STRUCT_DEF", "label": "User", "inputs": [{"name": "Name", "type_name": "string"}]},
+    {"id": "greet", "type": "FUNC_DEF", "label": "Greet", "inputs": [{"name": "receiver", "type_name": "*User"}]},
+    {"id": "hello", "type": "CONSTANT", "label": "hello", "outputs": [{"name": "out", "type_name": "string"}], "config": {"value": "\"hello\""}},
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "string"}]},
+    {"id": "ret", "type": "RETURN", "label": "Return"},
+    {"id": "start", "type": "START", "label": "Start"},
+    {"id": "u", "type": "VAR_DECLARE", "label": "u", "config": {"type": "*User", "value": "&User{}"}},
+    {"id": "ug", "type": "VAR_GET", "label": "u", "outputs": [{"name": "out", "type_name": "*User"}], "config": {"variable": "u"}},
+    {"id": "call", "type": "FUNCTION", "label": "greetUser", "impl_reference": "Greet", "inputs": [{"name": "receiver", "type_name": "*User"}]},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "data_edges": [
+    {"from_node_id": "hello", "from_port": "out", "to_node_id": "print", "to_port": "a"},
+    {"from_node_id": "ug", "from_port": "out", "to_node_id": "call", "to_port": "receiver"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "greet", "to_node_id": "print"},
+    {"from_node_id": "print", "to_node_id": "ret"},
+    {"from_node_id": "start", "to_node_id": "u"},
+    {"from_node_id": "u", "to_node_id": "call"},
+    {"from_node_id": "call", "to_node_id": "end"}
+  ]
+}`
+
+// printGraph passes the output of the node given, whose ID is value, to a call of impl taking the
+// type given, and prints the result.
+const printGraph = `{
+  "id": "print", "name": "print", "imports": ["fmt", "strings"],
+  "nodes": [
+    {"id": "start", "type": "START", "label": "Start"},
+    %s,
+    {"id": "call", "type": "FUNCTION", "label": "call", "impl_reference": "%s", "inputs": [{"name": "s", "type_name": "%s"}], "outputs": [{"name": "out", "type_name": "string"}]},
+    {"id": "print", "type": "FUNCTION", "label": "print", "impl_reference": "fmt.Println", "inputs": [{"name": "a", "type_name": "string"}]},
+    {"id": "end", "type": "END", "label": "End"}
+  ],
+  "data_edges": [
+    {"from_node_id": "value", "from_port": "out", "to_node_id": "call", "to_port": "s"},
+    {"from_node_id": "call", "from_port": "out", "to_node_id": "print", "to_port": "a"}
+  ],
+  "exec_edges": [
+    {"from_node_id": "start", "to_node_id": "call"},
+    {"from_node_id": "call", "to_node_id": "print"},
+    {"from_node_id": "print", "to_node_id": "end"}
+  ]
+}`
+
+func TestTypeCheckDiagnostics(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph string
+		code  string
+		// severity is the severity the diagnostic with code must have. absent means the graph must
+		// compile without it, or any error.
+		severity Severity
+		absent   bool
+	}{
+		{
+			name:  "matching signature",
+			graph: fmt.Sprintf(printGraph, `{"id": "value", "type": "CONSTANT", "label": "word", "outputs": [{"name": "out", "type_name": "string"}], "config": {"value": "\"hi\""}}`, "strings.ToUpper", "string"),
+			code:  CodeSignatureMismatch, absent: true,
+		},
+		{
+			name:     "argument of the wrong type",
+			graph:    fmt.Sprintf(printGraph, `{"id": "value", "type": "CONSTANT", "label": "count", "outputs": [{"name": "out", "type_name": "int"}], "config": {"value": "3"}}`, "strings.ToUpper", "int"),
+			code:     CodeSignatureMismatch,
+			severity: SeverityError,
+		},
+		{
+			name:     "function the package does not have",
+			graph:    fmt.Sprintf(printGraph, `{"id": "value", "type": "CONSTANT", "label": "word", "outputs": [{"name": "out", "type_name": "string"}], "config": {"value": "\"hi\""}}`, "strings.Shout", "string"),
+			code:     CodeUnknownFunction,
+			severity: SeverityError,
+		},
+		{
+			name:  "method the graph defines",
+			graph: fmt.Sprintf(greetGraph, ""),
+			code:  CodeUnknownFunction, absent: true,
+		},
+		{
+			name:     "package that cannot be found",
+			graph:    fmt.Sprintf(greetGraph, `, "example.com/nowhere/at/all"`),
+			code:     CodePackageNotFound,
+			severity: SeverityWarning,
+		},
+		{
+			name:     "edge between ports of different types",
+			graph:    fmt.Sprintf(printGraph, `{"id": "value", "type": "CONSTANT", "label": "count", "outputs": [{"name": "out", "type_name": "int"}], "config": {"value": "3"}}`, "strings.ToUpper", "string"),
+			code:     CodeTypeMismatch,
+			severity: SeverityError,
+		},
+		{
+			name:  "value assignable to an interface port",
+			graph: fmt.Sprintf(printGraph, `{"id": "value", "type": "CONSTANT", "label": "count", "outputs": [{"name": "out", "type_name": "int"}], "config": {"value": "3"}}`, "fmt.Sprint", "any"),
+			code:  CodeTypeMismatch, absent: true,
+		},
+		{
+			name:     "port of a type that does not exist",
+			graph:    fmt.Sprintf(printGraph, `{"id": "value", "type": "CONSTANT", "label": "word", "outputs": [{"name": "out", "type_name": "Nowhere"}], "config": {"value": "\"hi\""}}`, "strings.ToUpper", "string"),
+			code:     CodeUnknownType,
+			severity: SeverityError,
+		},
+		{
+			name:     "operands of different types",
+			graph:    fmt.Sprintf(printGraph, `{"id": "value", "type": "OPERATOR", "label": "word", "inputs": [{"name": "a", "type_name": "string"}, {"name": "b", "type_name": "int"}], "outputs": [{"name": "out", "type_name": "string"}], "config": {"op": "+"}}`, "strings.ToUpper", "string"),
+			code:     CodeOperandMismatch,
+			severity: SeverityError,
+		},
+		{
+			name:     "constant without an output",
+			graph:    fmt.Sprintf(printGraph, `{"id": "value", "type": "CONSTANT", "label": "word", "config": {"value": "\"hi\""}}`, "strings.ToUpper", "string"),
+			code:     CodeMissingPort,
+			severity: SeverityError,
+		},
+		{
+			name:     "operator without an output",
+			graph:    fmt.Sprintf(printGraph, `{"id": "value", "type": "OPERATOR", "label": "word", "inputs": [{"name": "in", "type_name": "string"}], "config": {"op": "string"}}`, "strings.ToUpper", "string"),
+			code:     CodeMissingPort,
+			severity: SeverityError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := compile(t, loadGraph(t, tt.graph), Options{})
+			d := find(result.Diagnostics, tt.code)
+			switch {
+			case tt.absent && (d != nil || result.Diagnostics.HasErrors()):
+				t.Errorf("unexpected diagnostics: %v", result.Diagnostics)
+			case !tt.absent && d == nil:
+				t.Errorf("no %s diagnostic; got %v", tt.code, result.Diagnostics)
+			case !tt.absent && d.Severity != tt.severity:
+				t.Errorf("%s diagnostic is a %s, want a %s: %s", tt.code, d.Severity, tt.severity, d.Message)
+			}
+		})
+	}
+}